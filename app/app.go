@@ -0,0 +1,353 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/auth"
+	"main/discovery"
+	"main/discovery/consul"
+	"main/dynconfig"
+	"main/loadshed"
+	"main/metrics"
+	"main/mtls"
+	"main/ratelimit"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Hook is a component of a service taking part in its lifecycle. All its functions are optional.
+type Hook struct {
+	// Name identifies the component in the logs.
+	Name string
+	// OnInit prepares the component before any component starts.
+	OnInit func(ctx context.Context) error
+	// OnStart starts the component. Components start in the order their hooks were added.
+	OnStart func(ctx context.Context) error
+	// OnReady is called once every component started and the service serves requests.
+	OnReady func(ctx context.Context) error
+	// OnStop stops the component. Started components stop in the reverse order they started.
+	OnStop func(ctx context.Context) error
+}
+
+// App runs a service: it sets up its logger, metrics, tracing, discovery and gRPC server, and drives the lifecycle
+// of the service's components from their initialization to their graceful shutdown.
+type App struct {
+	name       string
+	instanceID string
+	opts       options
+
+	logLevel   *slog.LevelVar
+	dynamic    *dynconfig.Store
+	metrics    *prometheus.Registry
+	discovery  discovery.Registry
+	authorizer *auth.Authorizer
+	limiter    *ratelimit.Limiter
+	tls        *mtls.Reloader
+	server     *grpc.Server
+	serveErr   chan error
+	health     *healthState
+	hooks      []Hook
+}
+
+// New sets up the subsystems of the named service enabled by the options.
+func New(name string, opts ...Option) (*App, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	a := &App{
+		name:       name,
+		instanceID: discovery.GenerateInstanceID(name),
+		opts:       o,
+		logLevel:   &slog.LevelVar{},
+		dynamic:    dynconfig.New(),
+		metrics:    prometheus.NewRegistry(),
+		serveErr:   make(chan error, 1),
+		health:     newHealthState(name),
+	}
+	a.setupLogger()
+	a.dynamic.LogLevel("LOG_LEVEL", a.logLevel, slog.LevelInfo)
+	a.metrics.MustRegister(a.dynamic.Collectors()...)
+	a.metrics.MustRegister(a.health.failing)
+	if o.dynamicSource != nil {
+		a.Append(a.dynamicConfigHook())
+	}
+
+	if o.cpuProfilePath != "" {
+		a.Append(a.profilingHook())
+	}
+
+	serverMetrics := metrics.NewServerMetrics()
+	a.metrics.MustRegister(serverMetrics.Collectors()...)
+	started := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: name,
+		Name:      "service_started",
+	})
+	a.metrics.MustRegister(started)
+	started.Inc()
+	if o.metricsPort != 0 {
+		a.Append(a.metricsHook(serverMetrics))
+	}
+
+	if o.jaegerURL != "" {
+		hook, err := a.tracingHook()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Jaeger provider: %w", err)
+		}
+		a.Append(hook)
+	}
+
+	if o.consulURL != "" {
+		registry, err := consul.NewRegistry(o.consulURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to consul registry: %w", err)
+		}
+		a.discovery = registry
+	}
+
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(serverMetrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(serverMetrics.StreamServerInterceptor()),
+	}
+	if o.tls != nil {
+		reloader, err := mtls.New(*o.tls)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificates: %w", err)
+		}
+		a.tls = reloader
+		a.metrics.MustRegister(reloader.Collectors()...)
+		a.Append(a.tlsHook())
+		serverOptions = append(serverOptions, reloader.ServerOption())
+	}
+	if o.concurrency != nil {
+		limiter := loadshed.New("server", *o.concurrency)
+		a.metrics.MustRegister(limiter.Collectors()...)
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+	}
+	if o.policy != nil && o.auth == nil {
+		if o.environment != "dev" {
+			return nil, errors.New("authorization requires authentication to be enabled")
+		}
+		slog.Warn("Authentication is disabled, the authorization policy is not enforced")
+	}
+	if o.auth != nil {
+		authenticator, err := auth.New(*o.auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize authentication: %w", err)
+		}
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+		)
+	}
+	if o.rateLimit != nil {
+		a.limiter = a.rateLimiter(*o.rateLimit)
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(a.limiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(a.limiter.StreamServerInterceptor()),
+		)
+	}
+	if o.auth != nil && o.policy != nil {
+		var err error
+		a.authorizer, err = auth.NewAuthorizer(o.policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize authorization: %w", err)
+		}
+		a.metrics.MustRegister(a.authorizer.Collectors()...)
+		serverOptions = append(serverOptions,
+			grpc.ChainUnaryInterceptor(a.authorizer.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(a.authorizer.StreamServerInterceptor()),
+		)
+	}
+	a.server = grpc.NewServer(append(serverOptions, o.grpcOptions...)...)
+	healthpb.RegisterHealthServer(a.server, a.health.server)
+	reflection.Register(a.server)
+
+	return a, nil
+}
+
+// setupLogger sets the default logger of the service, logging text in the dev environment and JSON otherwise.
+// Its level is the dynamic LOG_LEVEL setting.
+func (a *App) setupLogger() {
+	opts := &slog.HandlerOptions{Level: a.logLevel}
+	var handler slog.Handler
+	if a.opts.environment == "dev" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler).With("service_name", a.name))
+}
+
+// Name returns the name of the service.
+func (a *App) Name() string {
+	return a.name
+}
+
+// Metrics returns the Prometheus registry whose metrics the service exposes.
+func (a *App) Metrics() *prometheus.Registry {
+	return a.metrics
+}
+
+// Dynamic returns the store of the dynamic settings of the service, to register the settings it reloads at runtime.
+func (a *App) Dynamic() *dynconfig.Store {
+	return a.dynamic
+}
+
+// RateLimiter returns the rate limiter of the service, for the HTTP handlers to share its quotas, or nil without
+// rate limiting.
+func (a *App) RateLimiter() *ratelimit.Limiter {
+	return a.limiter
+}
+
+// Authorizer returns the authorizer of the service, for the HTTP handlers to enforce its policy, or nil without
+// authentication or authorization.
+func (a *App) Authorizer() *auth.Authorizer {
+	return a.authorizer
+}
+
+// TLS returns the certificates of the service, for its clients to dial the other services with mutual TLS,
+// or nil without TLS.
+func (a *App) TLS() *mtls.Reloader {
+	return a.tls
+}
+
+// Discovery returns the service registry, or nil without discovery.
+func (a *App) Discovery() discovery.Registry {
+	return a.discovery
+}
+
+// GRPCServer returns the gRPC server to register the handlers of the service on.
+func (a *App) GRPCServer() *grpc.Server {
+	return a.server
+}
+
+// Append adds a component to the lifecycle of the service.
+func (a *App) Append(h Hook) {
+	a.hooks = append(a.hooks, h)
+}
+
+// Go runs fn in the background from the start of the service until it stops. The context of fn is cancelled
+// on shutdown, and the shutdown waits for fn to return.
+func (a *App) Go(name string, fn func(ctx context.Context) error) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	a.Append(Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				if err := fn(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					slog.Error("background task failed:", slog.String("task", name), slog.String("error", err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Run runs the service until ctx is cancelled, it receives an interrupt or termination signal, or its gRPC
+// server fails. Once all hooks initialized and started, the service serves gRPC requests, runs its health checks,
+// registers itself and calls the ready hooks. On shutdown it deregisters itself, reports NOT_SERVING, gracefully
+// stops the gRPC server and then stops the other components in the reverse order they started, all within the
+// shutdown timeout.
+// Run returns the error that stopped the service, if any, along with the errors of the stopping components.
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hooks := append(append([]Hook{}, a.hooks...), a.grpcHook(), a.healthHook())
+	if a.discovery != nil {
+		hooks = append(hooks, a.discoveryHook())
+	}
+
+	slog.Info("Starting the service", slog.String("name", a.name), slog.String("address", a.opts.grpcAddr))
+	for _, h := range hooks {
+		if err := a.call(ctx, h.OnInit, a.opts.startTimeout); err != nil {
+			return fmt.Errorf("failed to initialize %s: %w", h.Name, err)
+		}
+	}
+
+	started := 0
+	for _, h := range hooks {
+		if err := a.call(ctx, h.OnStart, a.opts.startTimeout); err != nil {
+			return errors.Join(fmt.Errorf("failed to start %s: %w", h.Name, err), a.shutdown(hooks[:started]))
+		}
+		started++
+	}
+
+	for _, h := range hooks {
+		if err := a.call(ctx, h.OnReady, a.opts.startTimeout); err != nil {
+			return errors.Join(fmt.Errorf("failed to get %s ready: %w", h.Name, err), a.shutdown(hooks))
+		}
+	}
+	slog.Info("The service is ready")
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("Attempting graceful shutdown", slog.String("cause", context.Cause(ctx).Error()))
+	case err = <-a.serveErr:
+		slog.Error("gRPC server failed:", slog.String("error", err.Error()))
+	}
+	return errors.Join(err, a.shutdown(hooks))
+}
+
+// call runs a lifecycle function of a hook within the timeout.
+func (a *App) call(ctx context.Context, fn func(context.Context) error, timeout time.Duration) error {
+	if fn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// shutdown stops the started hooks in reverse order. The hooks share the shutdown timeout: once it expires,
+// the remaining hooks are still stopped, with an expired context.
+func (a *App) shutdown(started []Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		h := started[i]
+		if h.OnStop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := h.OnStop(ctx); err != nil {
+			slog.Error("failed to stop:", slog.String("component", h.Name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.Name, err))
+			continue
+		}
+		slog.Info("Stopped", slog.String("component", h.Name), slog.Duration("duration", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
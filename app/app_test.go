@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"main/auth"
+	"main/ratelimit"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recorder records the lifecycle calls of hooks.
+type recorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *recorder) hook(name string) Hook {
+	record := func(phase string) func(context.Context) error {
+		return func(context.Context) error {
+			r.Lock()
+			defer r.Unlock()
+			r.calls = append(r.calls, phase+" "+name)
+			return nil
+		}
+	}
+	return Hook{Name: name, OnInit: record("init"), OnStart: record("start"), OnReady: record("ready"), OnStop: record("stop")}
+}
+
+func TestRunLifecycle(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"))
+	require.NoError(t, err)
+
+	var r recorder
+	a.Append(r.hook("database"))
+	a.Append(r.hook("publisher"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	taskStopped := make(chan struct{})
+	a.Go("task", func(taskCtx context.Context) error {
+		cancel()
+		<-taskCtx.Done()
+		close(taskStopped)
+		return taskCtx.Err()
+	})
+
+	require.NoError(t, a.Run(ctx))
+	<-taskStopped
+	require.Equal(t, []string{
+		"init database", "init publisher",
+		"start database", "start publisher",
+		"ready database", "ready publisher",
+		"stop publisher", "stop database",
+	}, r.calls)
+}
+
+func TestRunStopsStartedHooksOnStartFailure(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"))
+	require.NoError(t, err)
+
+	var r recorder
+	a.Append(r.hook("database"))
+	failing := r.hook("pulsar")
+	failing.OnStart = func(context.Context) error { return errors.New("connection refused") }
+	a.Append(failing)
+	a.Append(r.hook("relay"))
+
+	err = a.Run(context.Background())
+	require.ErrorContains(t, err, "failed to start pulsar")
+	require.Equal(t, []string{"init database", "init pulsar", "init relay", "start database", "stop database"}, r.calls)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"), WithShutdownTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	var r recorder
+	a.Append(r.hook("database"))
+	a.Go("stuck", func(context.Context) error {
+		select {}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = a.Run(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, r.calls, "stop database")
+}
+
+func TestAuthorizationRequiresAuth(t *testing.T) {
+	policy := auth.Policy{auth.AdminMethods: {auth.RoleOperator}}
+	_, err := New("test", WithGRPC("127.0.0.1:0"), WithAuthorization(policy))
+	require.Error(t, err)
+
+	a, err := New("test", WithEnvironment("dev"), WithGRPC("127.0.0.1:0"), WithAuthorization(policy))
+	require.NoError(t, err)
+	require.Nil(t, a.Authorizer())
+}
+
+func TestRateLimitSparesHealthChecks(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"), WithRateLimit(ratelimit.Quota{Limit: 1, Burst: 1}))
+	require.NoError(t, err)
+	interceptor := a.RateLimiter().UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	for _, method := range []string{"/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Check", "/MovieService/GetMovieDetails"} {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		require.NoError(t, err, method)
+	}
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/MovieService/GetMovieDetails"}, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports whether a dependency of the service is usable, returning the reason it is not.
+type Check func(ctx context.Context) error
+
+// healthState tracks the results of the health checks of the service and publishes them through the standard
+// gRPC health service. The service is serving once it is ready and all its readiness checks pass, until it shuts
+// down. Failing degraded checks are only reported.
+type healthState struct {
+	server   *health.Server
+	checks   map[string]Check
+	degraded map[string]bool
+	failing  *prometheus.GaugeVec
+
+	mu       sync.Mutex
+	ready    bool
+	stopping bool
+	failures map[string]error
+}
+
+func newHealthState(name string) *healthState {
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return &healthState{
+		server:   server,
+		checks:   map[string]Check{},
+		degraded: map[string]bool{},
+		failing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: name,
+			Name:      "health_check_failing",
+			Help:      "Whether a health check of the service is failing, by check and whether it only degrades the service.",
+		}, []string{"check", "degraded"}),
+		failures: map[string]error{},
+	}
+}
+
+// Check adds a named check of a dependency that must pass for the service to be ready. The checks run on every
+// heartbeat once the service started; while one fails, the service reports NOT_SERVING on the gRPC health service
+// and /readyz, and stops reporting its healthy state to the registry.
+func (a *App) Check(name string, check Check) {
+	a.addCheck(name, check, false)
+}
+
+// CheckDegraded adds a named check of a dependency the service can serve without, such as a downstream service or
+// a background worker. It runs with the readiness checks but does not affect readiness: a failure is logged,
+// listed by /readyz and exported by the health_check_failing metric, so it does not take the service out of
+// rotation for a problem it cannot fix.
+func (a *App) CheckDegraded(name string, check Check) {
+	a.addCheck(name, check, true)
+}
+
+func (a *App) addCheck(name string, check Check, degraded bool) {
+	if _, ok := a.health.checks[name]; ok {
+		panic(fmt.Sprintf("app: health check %s added twice", name))
+	}
+	a.health.checks[name] = check
+	a.health.degraded[name] = degraded
+}
+
+// healthStatus is a snapshot of the health of the service.
+type healthStatus struct {
+	ready    bool
+	stopping bool
+	// failures holds the failing readiness checks and degraded the failing degraded checks.
+	failures map[string]error
+	degraded map[string]error
+}
+
+// healthy reports whether the service is ready, not shutting down and all its readiness checks passed.
+func (s healthStatus) healthy() bool {
+	return s.ready && !s.stopping && len(s.failures) == 0
+}
+
+// status returns the current health of the service.
+func (a *App) status() healthStatus {
+	a.health.mu.Lock()
+	defer a.health.mu.Unlock()
+	failures := map[string]error{}
+	degraded := map[string]error{}
+	for name, err := range a.health.failures {
+		if a.health.degraded[name] {
+			degraded[name] = err
+		} else {
+			failures[name] = err
+		}
+	}
+	return healthStatus{ready: a.health.ready, stopping: a.health.stopping, failures: failures, degraded: degraded}
+}
+
+// runChecks runs all the health checks, each within the heartbeat interval, and updates the serving status of the
+// service. A check changing state is logged.
+func (a *App) runChecks(ctx context.Context) {
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(a.health.checks))
+	for name, check := range a.health.checks {
+		go func(name string, check Check) {
+			ctx, cancel := context.WithTimeout(ctx, a.opts.heartbeatInterval)
+			defer cancel()
+			results <- result{name: name, err: check(ctx)}
+		}(name, check)
+	}
+
+	failures := map[string]error{}
+	ready := true
+	for range a.health.checks {
+		r := <-results
+		failing := 0.0
+		if r.err != nil {
+			failures[r.name] = r.err
+			ready = ready && a.health.degraded[r.name]
+			failing = 1
+		}
+		a.health.failing.WithLabelValues(r.name, strconv.FormatBool(a.health.degraded[r.name])).Set(failing)
+	}
+
+	a.health.mu.Lock()
+	defer a.health.mu.Unlock()
+	for name, err := range failures {
+		if _, failed := a.health.failures[name]; !failed {
+			slog.Warn("Health check failed", slog.String("check", name), slog.Bool("degraded", a.health.degraded[name]), slog.String("error", err.Error()))
+		}
+	}
+	for name := range a.health.failures {
+		if _, failed := failures[name]; !failed {
+			slog.Info("Health check recovered", slog.String("check", name))
+		}
+	}
+	a.health.failures = failures
+	a.health.ready = true
+	if a.health.stopping {
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if !ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	a.health.server.SetServingStatus("", status)
+	for service := range a.server.GetServiceInfo() {
+		a.health.server.SetServingStatus(service, status)
+	}
+}
+
+// healthHook runs the health checks once the service is ready and on every heartbeat. It is the first to stop,
+// so the service reports NOT_SERVING for the whole graceful shutdown.
+func (a *App) healthHook() Hook {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	started := false
+
+	return Hook{
+		Name: "health checks",
+		OnReady: func(ctx context.Context) error {
+			a.runChecks(ctx)
+			started = true
+
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(a.opts.heartbeatInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+						a.runChecks(context.Background())
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			a.health.mu.Lock()
+			a.health.stopping = true
+			a.health.mu.Unlock()
+			a.health.server.Shutdown()
+
+			if started {
+				close(stop)
+				<-done
+			}
+			return nil
+		},
+	}
+}
+
+// handleHealthz reports that the service is alive.
+func (a *App) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the service is ready, along with its failing degraded checks, or why it is not with
+// 503 Service Unavailable.
+func (a *App) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	status := a.status()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	var degraded []string
+	for name, err := range status.degraded {
+		degraded = append(degraded, fmt.Sprintf("degraded: %s: %s", name, err))
+	}
+	sort.Strings(degraded)
+	if status.healthy() {
+		fmt.Fprintln(w, strings.Join(append([]string{"ok"}, degraded...), "\n"))
+		return
+	}
+
+	var failures []string
+	for name, err := range status.failures {
+		failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+	}
+	sort.Strings(failures)
+	failures = append(failures, degraded...)
+	switch {
+	case status.stopping:
+		failures = append([]string{"shutting down"}, failures...)
+	case !status.ready:
+		failures = append([]string{"starting"}, failures...)
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintln(w, strings.Join(failures, "\n"))
+}
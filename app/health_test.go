@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func servingStatus(t *testing.T, a *App, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := a.health.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func readyz(a *App) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	a.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w
+}
+
+func TestHealthChecks(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"), WithHeartbeatInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	a.Check("database", func(context.Context) error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.Equal(t, http.StatusServiceUnavailable, readyz(a).Code)
+	require.Contains(t, readyz(a).Body.String(), "starting")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return a.status().ready }, time.Second, 10*time.Millisecond)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, a, ""))
+	require.Equal(t, http.StatusServiceUnavailable, readyz(a).Code)
+	require.Contains(t, readyz(a).Body.String(), "database: connection refused")
+
+	failing.Store(false)
+	require.Eventually(t, func() bool {
+		return servingStatus(t, a, "") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, a, healthpb.Health_ServiceDesc.ServiceName))
+	require.Equal(t, http.StatusOK, readyz(a).Code)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, a, ""))
+	require.Equal(t, http.StatusServiceUnavailable, readyz(a).Code)
+	require.Contains(t, readyz(a).Body.String(), "shutting down")
+}
+
+func TestDegradedChecks(t *testing.T) {
+	a, err := New("test", WithGRPC("127.0.0.1:0"), WithHeartbeatInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	a.CheckDegraded("rating registry", func(context.Context) error {
+		if failing.Load() {
+			return errors.New("no instances")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	// A failing degraded check is reported but keeps the service serving.
+	require.Eventually(t, func() bool { return a.status().ready }, time.Second, 10*time.Millisecond)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, a, ""))
+	require.True(t, a.status().healthy())
+	require.Equal(t, http.StatusOK, readyz(a).Code)
+	require.Equal(t, "ok\ndegraded: rating registry: no instances\n", readyz(a).Body.String())
+
+	failing.Store(false)
+	require.Eventually(t, func() bool { return readyz(a).Body.String() == "ok\n" }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"main/auth"
+	"main/dynconfig"
+	"main/loadshed"
+	"main/mtls"
+	"main/ratelimit"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Default lifecycle settings of a service.
+const (
+	defaultStartTimeout      = 15 * time.Second
+	defaultShutdownTimeout   = 15 * time.Second
+	defaultHeartbeatInterval = 1 * time.Second
+)
+
+// options are the subsystems of a service and their settings. A subsystem without settings is disabled.
+type options struct {
+	environment       string
+	cpuProfilePath    string
+	memProfilePath    string
+	metricsPort       int
+	jaegerURL         string
+	consulURL         string
+	grpcAddr          string
+	grpcOptions       []grpc.ServerOption
+	startTimeout      time.Duration
+	shutdownTimeout   time.Duration
+	heartbeatInterval time.Duration
+	dynamicSource     dynconfig.Source
+	concurrency       *loadshed.Config
+	auth              *auth.Config
+	policy            auth.Policy
+	rateLimit         *ratelimit.Quota
+	tls               *mtls.Config
+}
+
+func defaultOptions() options {
+	return options{
+		startTimeout:      defaultStartTimeout,
+		shutdownTimeout:   defaultShutdownTimeout,
+		heartbeatInterval: defaultHeartbeatInterval,
+	}
+}
+
+// Option configures a subsystem of a service.
+type Option func(*options)
+
+// WithEnvironment sets the environment the service runs in. The dev environment logs text, any other logs JSON.
+func WithEnvironment(environment string) Option {
+	return func(o *options) {
+		o.environment = environment
+	}
+}
+
+// WithProfiling records a CPU profile of the service to cpuPath and writes a heap profile to memPath when it stops.
+func WithProfiling(cpuPath, memPath string) Option {
+	return func(o *options) {
+		o.cpuProfilePath = cpuPath
+		o.memProfilePath = memPath
+	}
+}
+
+// WithMetrics serves the Prometheus metrics of the service on /metrics at the given port.
+func WithMetrics(port int) Option {
+	return func(o *options) {
+		o.metricsPort = port
+	}
+}
+
+// WithTracing exports the traces of the service to the Jaeger collector at url.
+func WithTracing(url string) Option {
+	return func(o *options) {
+		o.jaegerURL = url
+	}
+}
+
+// WithDiscovery registers the gRPC server of the service in the Consul registry at url once it is ready,
+// and reports its healthy state on every heartbeat.
+func WithDiscovery(url string) Option {
+	return func(o *options) {
+		o.consulURL = url
+	}
+}
+
+// WithDynamicConfig reloads the dynamic settings of the service from source while it runs.
+// A nil source keeps the settings at their defaults.
+func WithDynamicConfig(source dynconfig.Source) Option {
+	return func(o *options) {
+		o.dynamicSource = source
+	}
+}
+
+// WithGRPC serves the gRPC server of the service at addr, in the host:port form.
+// The server options are added after the tracing and metrics interceptors.
+func WithGRPC(addr string, opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.grpcAddr = addr
+		o.grpcOptions = append(o.grpcOptions, opts...)
+	}
+}
+
+// WithConcurrencyLimit sheds the gRPC requests over an adaptive concurrency limit, lowest priority first.
+// The limiter runs after the tracing and metrics interceptors, and before the ones of the server options.
+func WithConcurrencyLimit(cfg loadshed.Config) Option {
+	return func(o *options) {
+		o.concurrency = &cfg
+	}
+}
+
+// WithAuth rejects the gRPC requests without credentials accepted by cfg, except health checks, and adds the
+// principal of the others to their context. A nil cfg serves requests without authentication.
+// The authentication runs after the concurrency limiter, and before the interceptors of the server options.
+func WithAuth(cfg *auth.Config) Option {
+	return func(o *options) {
+		o.auth = cfg
+	}
+}
+
+// WithRateLimit rejects the gRPC requests over the quota of their caller for their method with ResourceExhausted.
+// Callers are identified by their principal, or else their IP address. The dynamic settings RATE_LIMIT and RATE_BURST
+// override the quota, and RATE_LIMIT_METHODS the quotas of some methods; the health checks are never limited unless
+// it sets their quota. The rate limiter runs after the authentication, and before the authorization.
+func WithRateLimit(quota ratelimit.Quota) Option {
+	return func(o *options) {
+		o.rateLimit = &quota
+	}
+}
+
+// WithAuthorization rejects the gRPC requests whose principal lacks the roles the policy requires with
+// PermissionDenied. The policy is only enforced with authentication, after it: the service fails to start with a
+// policy but no authentication, except in the dev environment where the policy is not enforced.
+func WithAuthorization(policy auth.Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithTLS serves the gRPC server with mutual TLS, reloading the certificates of cfg when their files change.
+// A nil cfg serves connections without TLS.
+func WithTLS(cfg *mtls.Config) Option {
+	return func(o *options) {
+		o.tls = cfg
+	}
+}
+
+// WithStartTimeout bounds the time each hook has to initialize, start or get ready.
+func WithStartTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.startTimeout = d
+	}
+}
+
+// WithShutdownTimeout bounds the time the service has to stop all its hooks.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = d
+	}
+}
+
+// WithHeartbeatInterval sets how often the service runs its health checks and reports its healthy state to the
+// registry. It also bounds the time each check has to complete.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = d
+	}
+}
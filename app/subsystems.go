@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/metrics"
+	"main/ratelimit"
+	"main/tracing"
+	"net"
+	"net/http"
+	"os"
+	"runtime/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// profilingHook records a CPU profile while the service runs and writes a heap profile when it stops.
+func (a *App) profilingHook() Hook {
+	var cpuFile *os.File
+	return Hook{
+		Name: "profiling",
+		OnInit: func(context.Context) error {
+			var err error
+			if cpuFile, err = os.Create(a.opts.cpuProfilePath); err != nil {
+				return err
+			}
+			return pprof.StartCPUProfile(cpuFile)
+		},
+		OnStop: func(context.Context) error {
+			pprof.StopCPUProfile()
+			err := cpuFile.Close()
+			if a.opts.memProfilePath == "" {
+				return err
+			}
+			memFile, createErr := os.Create(a.opts.memProfilePath)
+			if createErr != nil {
+				return errors.Join(err, createErr)
+			}
+			return errors.Join(err, pprof.WriteHeapProfile(memFile), memFile.Close())
+		},
+	}
+}
+
+// metricsHook serves the metrics registry on /metrics, along with the /healthz liveness and /readyz readiness
+// endpoints.
+func (a *App) metricsHook(serverMetrics *metrics.ServerMetrics) Hook {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.handleHealthz)
+	mux.HandleFunc("/readyz", a.handleReadyz)
+	mux.Handle("/metrics", serverMetrics.Middleware("/metrics", promhttp.HandlerFor(a.metrics, promhttp.HandlerOpts{
+		Registry:          a.metrics,
+		EnableOpenMetrics: true,
+	})))
+	server := &http.Server{Addr: fmt.Sprintf(":%d", a.opts.metricsPort), Handler: mux}
+
+	return Hook{
+		Name: "metrics server",
+		OnStart: func(context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					slog.Error("failed to serve metrics:", slog.String("error", err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: server.Shutdown,
+	}
+}
+
+// tracingHook sets up the Jaeger tracing provider and flushes its pending spans when the service stops.
+func (a *App) tracingHook() (Hook, error) {
+	tp, err := tracing.NewJaegerProvider(a.opts.jaegerURL, a.name)
+	if err != nil {
+		return Hook{}, err
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return Hook{
+		Name:   "tracing",
+		OnStop: tp.Shutdown,
+	}, nil
+}
+
+// dynamicConfigHook reloads the dynamic settings from their source while the service runs. The service starts
+// once the settings were first loaded, or with their defaults if the source does not answer within the start timeout.
+func (a *App) dynamicConfigHook() Hook {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	return Hook{
+		Name: "dynamic config",
+		OnStart: func(ctx context.Context) error {
+			var watchCtx context.Context
+			watchCtx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				if err := a.dynamic.Run(watchCtx, a.opts.dynamicSource); err != nil && !errors.Is(err, context.Canceled) {
+					slog.Error("failed to watch dynamic configuration:", slog.String("error", err.Error()))
+				}
+			}()
+			select {
+			case <-a.dynamic.Loaded():
+				slog.Info("Loaded dynamic configuration", slog.Uint64("version", a.dynamic.Version()))
+			case <-ctx.Done():
+				slog.Warn("Dynamic configuration not loaded, starting with the defaults")
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// tlsHook reloads the certificates of the service when their files change while the service runs.
+func (a *App) tlsHook() Hook {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	return Hook{
+		Name: "certificates",
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				if err := a.tls.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					slog.Error("failed to reload certificates:", slog.String("error", err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// grpcHook serves the gRPC server and stops it gracefully, or forcibly once the shutdown timeout expires.
+func (a *App) grpcHook() Hook {
+	return Hook{
+		Name: "gRPC server",
+		OnStart: func(context.Context) error {
+			listener, err := net.Listen("tcp", a.opts.grpcAddr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := a.server.Serve(listener); err != nil {
+					a.serveErr <- err
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				a.server.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				a.server.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// discoveryHook registers the service once it is ready and reports its healthy state on every heartbeat
+// while its health checks pass. The service is deregistered first on shutdown, so it stops receiving requests
+// before its server stops.
+func (a *App) discoveryHook() Hook {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	registered := false
+
+	return Hook{
+		Name: "discovery",
+		OnReady: func(ctx context.Context) error {
+			if err := a.discovery.Register(ctx, a.instanceID, a.name, a.opts.grpcAddr); err != nil {
+				return err
+			}
+			registered = true
+
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(a.opts.heartbeatInterval)
+				defer ticker.Stop()
+				for {
+					a.heartbeat()
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if !registered {
+				return nil
+			}
+			close(stop)
+			<-done
+			return a.discovery.Deregister(ctx, a.instanceID, a.name)
+		},
+	}
+}
+
+// heartbeat reports the healthy state of the service if all its health checks passed.
+func (a *App) heartbeat() {
+	if !a.status().healthy() {
+		return
+	}
+	if err := a.discovery.ReportHealthyState(a.instanceID, a.name); err != nil {
+		slog.Info("Failed to report healthy state:", slog.String("error", err.Error()))
+	}
+}
+
+// healthMethods are the methods of the health checks of the registry and orchestrators, which are not rate limited.
+var healthMethods = []string{"/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch"}
+
+// rateLimiter creates the rate limiter of the service with the default quota, reloading its quotas from the
+// RATE_LIMIT, RATE_BURST and RATE_LIMIT_METHODS dynamic settings.
+func (a *App) rateLimiter(quota ratelimit.Quota) *ratelimit.Limiter {
+	limiter := ratelimit.New(quota)
+	a.metrics.MustRegister(limiter.Collectors()...)
+
+	rateLimit := a.dynamic.Float("RATE_LIMIT", quota.Limit)
+	rateBurst := a.dynamic.Int("RATE_BURST", quota.Burst)
+	rateLimitMethods := a.dynamic.String("RATE_LIMIT_METHODS", "", func(value string) error {
+		_, err := ratelimit.ParseQuotas(value)
+		return err
+	})
+	a.dynamic.Subscribe(func() {
+		quotas, _ := ratelimit.ParseQuotas(rateLimitMethods.Load())
+		for _, method := range healthMethods {
+			if _, ok := quotas[method]; !ok {
+				quotas[method] = ratelimit.Quota{}
+			}
+		}
+		limiter.SetQuotas(ratelimit.Quota{Limit: rateLimit.Load(), Burst: max(rateBurst.Load(), 1)}, quotas)
+	})
+	return limiter
+}
@@ -0,0 +1,252 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"main/auth"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Outcomes of audited mutations.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// ResourceTypeMethod is the resource type of the records of the requests denied by the authorizer, whose resource
+// is the method they called.
+const ResourceTypeMethod = "method"
+
+// Change is the change of a field of a resource, with its values encoded in JSON. A value is empty if the resource
+// did not exist before or after the change, or did not have the field.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Record is a mutation of a resource by a principal.
+type Record struct {
+	// ID is the store-assigned sequence number, which orders the records.
+	ID      int64
+	Service string
+	// Principal and PrincipalKind identify the caller, they are empty for unauthenticated requests.
+	Principal     string
+	PrincipalKind string
+	// Method is the full gRPC method of the mutation, or the name of the operation outside gRPC requests.
+	Method       string
+	ResourceType string
+	ResourceID   string
+	Changes      []Change
+	TraceID      string
+	Outcome      string
+	// Error is the error the mutation failed with.
+	Error     string
+	CreatedAt time.Time
+}
+
+// Filter selects audit records. Empty fields match every record.
+type Filter struct {
+	Service      string
+	Principal    string
+	Method       string
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	// Since and Until bound the creation time of the records, Until being excluded.
+	Since time.Time
+	Until time.Time
+	// BeforeID selects the records older than the record with this id, to page through the records.
+	BeforeID int64
+	// Limit is the maximum number of records returned.
+	Limit int
+}
+
+// Store defines the append-only storage of the audit records.
+type Store interface {
+	// Append stores a record and sets its id.
+	Append(ctx context.Context, record *Record) error
+	// Query returns the records matching the filter, newest first.
+	Query(ctx context.Context, filter Filter) ([]Record, error)
+}
+
+// Diff returns the changes of the fields of a resource between two of its states, compared by their JSON
+// encoding. A nil state stands for a resource that does not exist. The changes are sorted by field.
+func Diff(before, after any) ([]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	changes := []Change{}
+	for name := range names {
+		if !bytes.Equal(beforeFields[name], afterFields[name]) {
+			changes = append(changes, Change{Field: name, Before: beforeFields[name], After: afterFields[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// fields returns the JSON encoding of the fields of a resource state by name.
+func fields(state any) (map[string]json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(state); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var res map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &res); err != nil {
+		return nil, fmt.Errorf("resource state %T is not a JSON object: %w", state, err)
+	}
+	return res, nil
+}
+
+// Logger records the mutations of the resources of a service. A nil logger records nothing.
+type Logger struct {
+	service string
+	store   Store
+
+	records  *prometheus.CounterVec
+	failures prometheus.Counter
+}
+
+// New creates a logger appending the mutations of the resources of the named service to the store.
+func New(service string, store Store) *Logger {
+	return &Logger{
+		service: service,
+		store:   store,
+		records: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "audit",
+			Name:      "records_total",
+			Help:      "Number of audited mutations, by resource type and outcome.",
+		}, []string{"resource_type", "outcome"}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "audit",
+			Name:      "append_failures_total",
+			Help:      "Number of audit records that could not be stored.",
+		}),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the audit metrics.
+func (l *Logger) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{l.records, l.failures}
+}
+
+// NewRecord builds the record of the mutation of a resource from its state before to its state after, for the
+// repository to store in the transaction of the mutation. The principal, the gRPC method and the trace of the
+// mutation are taken from ctx; operation names the mutation outside gRPC requests. It returns nil for a nil logger,
+// which the repositories take as no record to store.
+func (l *Logger) NewRecord(ctx context.Context, operation, resourceType, resourceID string, before, after any) *Record {
+	if l == nil {
+		return nil
+	}
+
+	record := &Record{
+		Service:      l.service,
+		Method:       operation,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Outcome:      OutcomeSuccess,
+		CreatedAt:    time.Now(),
+	}
+	if method, ok := grpc.Method(ctx); ok {
+		record.Method = method
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		record.Principal, record.PrincipalKind = p.Subject, p.Kind
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		record.TraceID = sc.TraceID().String()
+	}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		slog.Error("failed to diff audited resource:", slog.String("resource_type", resourceType), slog.String("error", err.Error()))
+	}
+	record.Changes = changes
+	return record
+}
+
+// Committed counts a record stored in the transaction of its mutation.
+func (l *Logger) Committed(record *Record) {
+	if l == nil || record == nil {
+		return
+	}
+	l.records.WithLabelValues(record.ResourceType, record.Outcome).Inc()
+}
+
+// Failed audits the failure of the mutation of a record, which was rolled back with it. The record is appended on
+// its own, without changes, as the resource did not change.
+func (l *Logger) Failed(ctx context.Context, record *Record, err error) {
+	if l == nil || record == nil {
+		return
+	}
+	record.Outcome, record.Error = OutcomeFailure, err.Error()
+	record.Changes = nil
+	l.append(ctx, record)
+}
+
+// Record audits a mutation of a resource from its state before to its state after, which failed if err is not nil,
+// appending its record on its own. It is used for the mutations that do not store their record themselves, see
+// NewRecord. Records that cannot be stored are logged, the mutation having already happened.
+func (l *Logger) Record(ctx context.Context, operation, resourceType, resourceID string, before, after any, err error) {
+	if l == nil {
+		return
+	}
+
+	record := l.NewRecord(ctx, operation, resourceType, resourceID, before, after)
+	if err != nil {
+		l.Failed(ctx, record, err)
+		return
+	}
+	l.append(ctx, record)
+}
+
+// Denied audits a request denied by the authorizer as a failed mutation of the method denied. It is meant to be
+// set as the OnDenied function of the authorizer of the service.
+func (l *Logger) Denied(ctx context.Context, method string, err error) {
+	l.Record(ctx, method, ResourceTypeMethod, method, nil, nil, err)
+}
+
+// append stores a record and counts it.
+func (l *Logger) append(ctx context.Context, record *Record) {
+	l.records.WithLabelValues(record.ResourceType, record.Outcome).Inc()
+	if err := l.store.Append(context.WithoutCancel(ctx), record); err != nil {
+		l.failures.Inc()
+		slog.Error("failed to append audit record:",
+			slog.String("method", record.Method),
+			slog.String("resource_type", record.ResourceType),
+			slog.String("resource_id", record.ResourceID),
+			slog.String("principal", record.Principal),
+			slog.String("outcome", record.Outcome),
+			slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,134 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"main/audit"
+	"main/audit/memory"
+	"main/auth"
+	"main/rpc"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type movie struct {
+	Title    string `json:"title"`
+	Director string `json:"director"`
+}
+
+// transportStream is the server transport stream of a gRPC request to a method.
+type transportStream struct {
+	grpc.ServerTransportStream
+	method string
+}
+
+func (s *transportStream) Method() string { return s.method }
+
+func TestDiff(t *testing.T) {
+	changes, err := audit.Diff(&movie{Title: "Alien", Director: "Scott"}, &movie{Title: "Aliens", Director: "Scott"})
+	require.NoError(t, err)
+	require.Equal(t, []audit.Change{{Field: "title", Before: json.RawMessage(`"Alien"`), After: json.RawMessage(`"Aliens"`)}}, changes)
+
+	var missing *movie
+	changes, err = audit.Diff(missing, &movie{Title: "Alien"})
+	require.NoError(t, err)
+	require.Equal(t, []audit.Change{
+		{Field: "director", After: json.RawMessage(`""`)},
+		{Field: "title", After: json.RawMessage(`"Alien"`)},
+	}, changes)
+
+	changes, err = audit.Diff(&movie{Title: "Alien"}, &movie{Title: "Alien"})
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = audit.Diff("Alien", nil)
+	require.Error(t, err)
+}
+
+func TestLoggerRecord(t *testing.T) {
+	store := memory.New()
+	l := audit.New("metadata", store)
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{Subject: "alice", Kind: "jwt"})
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &transportStream{method: "/rpc.MetadataService/PutMetadata"})
+	l.Record(ctx, "PutMetadata", "movie", "1", nil, &movie{Title: "Alien"}, nil)
+	l.Record(context.Background(), "PutMetadata", "movie", "2", nil, nil, errors.New("database is down"))
+
+	records, err := store.Query(context.Background(), audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	failed, succeeded := records[0], records[1]
+	require.Equal(t, "metadata", succeeded.Service)
+	require.Equal(t, "alice", succeeded.Principal)
+	require.Equal(t, "jwt", succeeded.PrincipalKind)
+	require.Equal(t, "/rpc.MetadataService/PutMetadata", succeeded.Method)
+	require.Equal(t, audit.OutcomeSuccess, succeeded.Outcome)
+	require.Len(t, succeeded.Changes, 2)
+
+	require.Empty(t, failed.Principal)
+	require.Equal(t, "PutMetadata", failed.Method)
+	require.Equal(t, audit.OutcomeFailure, failed.Outcome)
+	require.Equal(t, "database is down", failed.Error)
+	require.Empty(t, failed.Changes)
+
+	var nilLogger *audit.Logger
+	nilLogger.Record(ctx, "PutMetadata", "movie", "1", nil, nil, nil)
+}
+
+func TestLoggerDenied(t *testing.T) {
+	store := memory.New()
+	l := audit.New("metadata", store)
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{Subject: "carol", Kind: "jwt"})
+	l.Denied(ctx, "PutMetadata", auth.ErrPermissionDenied)
+
+	records, err := store.Query(context.Background(), audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "carol", records[0].Principal)
+	require.Equal(t, audit.ResourceTypeMethod, records[0].ResourceType)
+	require.Equal(t, "PutMetadata", records[0].ResourceID)
+	require.Equal(t, audit.OutcomeFailure, records[0].Outcome)
+	require.Equal(t, auth.ErrPermissionDenied.Error(), records[0].Error)
+}
+
+func TestQueryAuditLog(t *testing.T) {
+	store := memory.New()
+	l := audit.New("rating", store)
+	for _, id := range []string{"1", "2", "3"} {
+		l.Record(context.Background(), "PutRating", "rating", id, nil, &movie{}, nil)
+	}
+	l.Record(context.Background(), "PutRating", "rating", "4", nil, nil, errors.New("invalid rating"))
+	audit.New("metadata", store).Record(context.Background(), "PutMetadata", "movie", "5", nil, &movie{}, nil)
+	h := audit.NewHandler("rating", store)
+
+	resp, err := h.QueryAuditLog(context.Background(), &rpc.QueryAuditLogRequest{Outcome: audit.OutcomeSuccess, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Records, 2)
+	require.Equal(t, "3", resp.Records[0].ResourceId)
+	require.Equal(t, "2", resp.Records[1].ResourceId)
+	require.NotEmpty(t, resp.NextPageToken)
+
+	resp, err = h.QueryAuditLog(context.Background(), &rpc.QueryAuditLogRequest{Outcome: audit.OutcomeSuccess, PageSize: 2, PageToken: resp.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, resp.Records, 1)
+	require.Equal(t, "1", resp.Records[0].ResourceId)
+	require.Empty(t, resp.NextPageToken)
+
+	// The records of the other services sharing the log are not returned.
+	resp, err = h.QueryAuditLog(context.Background(), &rpc.QueryAuditLogRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Records, 4)
+	for _, r := range resp.Records {
+		require.Equal(t, "rating", r.Service)
+	}
+
+	_, err = h.QueryAuditLog(context.Background(), &rpc.QueryAuditLogRequest{PageToken: "next"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"main/rpc"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// Handler defines a gRPC audit log API handler, which operators query the audit records of a service with.
+// The services may share an audit log, so the handler only returns the records of its own service.
+type Handler struct {
+	rpc.UnimplementedAuditAdminServer
+	service string
+	store   Store
+}
+
+// NewHandler creates a new audit log gRPC handler of the audit records of the named service.
+func NewHandler(service string, store Store) *Handler {
+	return &Handler{
+		service: service,
+		store:   store,
+	}
+}
+
+// QueryAuditLog returns the audit records matching the request, newest first.
+func (h *Handler) QueryAuditLog(ctx context.Context, req *rpc.QueryAuditLogRequest) (*rpc.QueryAuditLogResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request")
+	}
+
+	filter := Filter{
+		Service:      h.service,
+		Principal:    req.Principal,
+		Method:       req.Method,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceId,
+		Outcome:      req.Outcome,
+		Limit:        int(req.PageSize),
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	} else if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+	if req.PageToken != "" {
+		id, err := strconv.ParseInt(req.PageToken, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+		}
+		filter.BeforeID = id
+	}
+	if req.Since != nil {
+		if err := req.Since.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid since: %v", err)
+		}
+		filter.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		if err := req.Until.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid until: %v", err)
+		}
+		filter.Until = req.Until.AsTime()
+	}
+
+	records, err := h.store.Query(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	res := &rpc.QueryAuditLogResponse{
+		Records: make([]*rpc.AuditRecord, 0, len(records)),
+	}
+	for i := range records {
+		res.Records = append(res.Records, RecordToProto(&records[i]))
+	}
+	if len(records) == filter.Limit {
+		res.NextPageToken = strconv.FormatInt(records[len(records)-1].ID, 10)
+	}
+	return res, nil
+}
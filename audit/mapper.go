@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"main/rpc"
+	eventsv1 "main/rpc/events/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RecordToProto converts a Record struct into a generated proto counterpart.
+func RecordToProto(r *Record) *rpc.AuditRecord {
+	changes := make([]*rpc.FieldChange, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		changes = append(changes, &rpc.FieldChange{Field: c.Field, Before: string(c.Before), After: string(c.After)})
+	}
+	return &rpc.AuditRecord{
+		Id:            r.ID,
+		Service:       r.Service,
+		Principal:     r.Principal,
+		PrincipalKind: r.PrincipalKind,
+		Method:        r.Method,
+		ResourceType:  r.ResourceType,
+		ResourceId:    r.ResourceID,
+		Changes:       changes,
+		TraceId:       r.TraceID,
+		Outcome:       r.Outcome,
+		Error:         r.Error,
+		CreatedAt:     timestamppb.New(r.CreatedAt),
+	}
+}
+
+// RecordToEvent converts a Record struct into the event published when it is appended.
+func RecordToEvent(r *Record) *eventsv1.AuditRecorded {
+	changes := make([]*eventsv1.AuditChange, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		changes = append(changes, &eventsv1.AuditChange{Field: c.Field, Before: string(c.Before), After: string(c.After)})
+	}
+	return &eventsv1.AuditRecorded{
+		Id:            r.ID,
+		Service:       r.Service,
+		Principal:     r.Principal,
+		PrincipalKind: r.PrincipalKind,
+		Method:        r.Method,
+		ResourceType:  r.ResourceType,
+		ResourceId:    r.ResourceID,
+		Changes:       changes,
+		TraceId:       r.TraceID,
+		Outcome:       r.Outcome,
+		Error:         r.Error,
+		CreatedAt:     timestamppb.New(r.CreatedAt),
+	}
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"main/audit"
+	"sync"
+)
+
+// Store defines a memory audit record store.
+type Store struct {
+	sync.RWMutex
+	records []audit.Record
+}
+
+// New creates a new memory audit record store.
+func New() *Store {
+	return &Store{}
+}
+
+// Append stores a record and sets its id.
+func (s *Store) Append(_ context.Context, record *audit.Record) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record.ID = int64(len(s.records) + 1)
+	s.records = append(s.records, *record)
+	return nil
+}
+
+// Query returns the records matching the filter, newest first.
+func (s *Store) Query(_ context.Context, filter audit.Filter) ([]audit.Record, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	res := []audit.Record{}
+	for i := len(s.records) - 1; i >= 0 && (filter.Limit <= 0 || len(res) < filter.Limit); i-- {
+		if r := s.records[i]; matches(&r, &filter) {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func matches(r *audit.Record, f *audit.Filter) bool {
+	return (f.Service == "" || r.Service == f.Service) &&
+		(f.Principal == "" || r.Principal == f.Principal) &&
+		(f.Method == "" || r.Method == f.Method) &&
+		(f.ResourceType == "" || r.ResourceType == f.ResourceType) &&
+		(f.ResourceID == "" || r.ResourceID == f.ResourceID) &&
+		(f.Outcome == "" || r.Outcome == f.Outcome) &&
+		(f.Since.IsZero() || !r.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || r.CreatedAt.Before(f.Until)) &&
+		(f.BeforeID == 0 || r.ID < f.BeforeID)
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"main/audit"
+	"main/database/db"
+	"main/outbox"
+	outboxpg "main/outbox/postgres"
+
+	"go.opentelemetry.io/otel"
+)
+
+const tracerID = "audit-store-postgres"
+
+// AggregateType is the outbox aggregate type of the audit events. The events of a resource share an aggregate,
+// so they are published in order.
+const AggregateType = "audit"
+
+// Store defines a PostgreSQL-based audit record store, appending to the append-only audit_log table.
+type Store struct {
+	db          db.Store
+	eventSource string
+}
+
+// New creates a new PostgreSQL-based audit record store. If eventSource is not empty, every appended record is
+// also published to the event bus as an AuditRecorded event from that source, through the outbox.
+func New(store db.Store, eventSource string) *Store {
+	return &Store{
+		db:          store,
+		eventSource: eventSource,
+	}
+}
+
+// Append stores a record and sets its id, recording its event in the outbox in the same transaction.
+func (s *Store) Append(ctx context.Context, record *audit.Record) error {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Store/APPEND")
+	defer span.End()
+
+	arg, err := s.TxParams(record)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.CreateAuditRecordTx(ctx, *arg)
+	if err != nil {
+		return err
+	}
+	record.ID = result.Record.ID
+	return nil
+}
+
+// TxParams returns the parameters appending a record and recording its event in the outbox, for the mutation
+// transactions to append the record of their mutation with it. It returns nil for a nil record.
+func (s *Store) TxParams(record *audit.Record) (*db.CreateAuditRecordTxParams, error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	changes := record.Changes
+	if changes == nil {
+		changes = []audit.Change{}
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := &db.CreateAuditRecordTxParams{
+		Record: db.CreateAuditRecordParams{
+			Service:       record.Service,
+			Principal:     record.Principal,
+			PrincipalKind: record.PrincipalKind,
+			Method:        record.Method,
+			ResourceType:  record.ResourceType,
+			ResourceID:    record.ResourceID,
+			Changes:       encoded,
+			TraceID:       record.TraceID,
+			Outcome:       record.Outcome,
+			Error:         record.Error,
+			CreatedAt:     record.CreatedAt,
+		},
+	}
+	if s.eventSource != "" {
+		arg.AfterCreate = func(row *db.AuditLog) ([]*db.CreateOutboxEventParams, error) {
+			stored := *record
+			stored.ID = row.ID
+			event, err := outbox.NewEvent(s.eventSource, AggregateType, record.ResourceType+"/"+record.ResourceID, audit.RecordToEvent(&stored))
+			if err != nil {
+				return nil, err
+			}
+			return outboxpg.Params([]outbox.Event{event}), nil
+		}
+	}
+	return arg, nil
+}
+
+// Query returns the records matching the filter, newest first.
+func (s *Store) Query(ctx context.Context, filter audit.Filter) ([]audit.Record, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Store/QUERY")
+	defer span.End()
+
+	arg := &db.ListAuditRecordsParams{
+		Service:      optional(filter.Service),
+		Principal:    optional(filter.Principal),
+		Method:       optional(filter.Method),
+		ResourceType: optional(filter.ResourceType),
+		ResourceID:   optional(filter.ResourceID),
+		Outcome:      optional(filter.Outcome),
+		PageSize:     int32(filter.Limit),
+	}
+	if !filter.Since.IsZero() {
+		arg.Since = &filter.Since
+	}
+	if !filter.Until.IsZero() {
+		arg.Until = &filter.Until
+	}
+	if filter.BeforeID != 0 {
+		arg.BeforeID = &filter.BeforeID
+	}
+
+	rows, err := s.db.ListAuditRecords(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]audit.Record, 0, len(rows))
+	for _, row := range rows {
+		var changes []audit.Change
+		if err := json.Unmarshal(row.Changes, &changes); err != nil {
+			return nil, err
+		}
+		res = append(res, audit.Record{
+			ID:            row.ID,
+			Service:       row.Service,
+			Principal:     row.Principal,
+			PrincipalKind: row.PrincipalKind,
+			Method:        row.Method,
+			ResourceType:  row.ResourceType,
+			ResourceID:    row.ResourceID,
+			Changes:       changes,
+			TraceID:       row.TraceID,
+			Outcome:       row.Outcome,
+			Error:         row.Error,
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+	return res, nil
+}
+
+// optional returns nil for an empty filter value, which matches every record.
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
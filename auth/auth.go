@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Kinds of credentials a principal authenticates with.
+const (
+	KindJWT    = "jwt"
+	KindAPIKey = "api_key"
+)
+
+// ErrUnauthenticated is returned when a request carries no valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller: the sub claim of a JWT, or the name of an API key.
+	Subject string
+	// Kind is the kind of credentials the caller authenticated with.
+	Kind string
+	// Roles are the roles granted to the caller: the roles claim of a JWT, or the roles of an API key.
+	Roles []string
+	// credential is the verified credential, forwarded to the downstream services.
+	credential string
+}
+
+type principalKey struct{}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// NewContext returns a copy of ctx carrying the principal of its request.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of the request of ctx, if it was authenticated.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Config holds the credentials an authenticator accepts. At least one kind must be configured.
+type Config struct {
+	// JWTSecret verifies the JWTs signed with HS256.
+	JWTSecret string
+	// JWKSPath is the path of a JSON Web Key Set file whose RSA keys verify the JWTs signed with RS256.
+	JWKSPath string
+	// Issuer and Audience, if set, must match the iss and aud claims of the JWTs.
+	Issuer   string
+	Audience string
+	// APIKeysPath is the path of a file of NAME=HASH lines, where HASH is the hex-encoded SHA-256 hash of the
+	// API key of the caller named NAME, optionally followed by the roles of the key as in NAME=HASH:editor,operator.
+	// Empty lines and lines starting with # are skipped.
+	APIKeysPath string
+}
+
+// Authenticator verifies the credentials of requests.
+type Authenticator struct {
+	parser   *jwt.Parser
+	secret   []byte
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	apiKeys  map[string]apiKey
+}
+
+// apiKey is the caller an API key authenticates.
+type apiKey struct {
+	name  string
+	roles []string
+}
+
+// New creates an authenticator, loading its JWKS and API keys files.
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{
+		rsaKeys:  map[string]*rsa.PublicKey{},
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		apiKeys:  map[string]apiKey{},
+	}
+
+	var methods []string
+	if cfg.JWTSecret != "" {
+		a.secret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSPath != "" {
+		keys, err := loadJWKS(cfg.JWKSPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS: %w", err)
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	a.parser = &jwt.Parser{ValidMethods: methods, UseJSONNumber: true}
+
+	if cfg.APIKeysPath != "" {
+		keys, err := loadAPIKeys(cfg.APIKeysPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load API keys: %w", err)
+		}
+		a.apiKeys = keys
+	}
+
+	if len(methods) == 0 && len(a.apiKeys) == 0 {
+		return nil, errors.New("no JWT keys nor API keys configured")
+	}
+	return a, nil
+}
+
+// AuthenticateJWT verifies a JWT and returns the principal of its sub claim, with the roles of its roles claim.
+// The token must expire, and match the issuer and audience of the authenticator if they are set.
+func (a *Authenticator) AuthenticateJWT(token string) (*Principal, error) {
+	if len(a.parser.ValidMethods) == 0 {
+		return nil, fmt.Errorf("%w: JWTs are not accepted", ErrUnauthenticated)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.key); err != nil {
+		return nil, fmt.Errorf("%w: invalid token: %v", ErrUnauthenticated, err)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrUnauthenticated)
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("%w: token has the wrong issuer", ErrUnauthenticated)
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("%w: token has the wrong audience", ErrUnauthenticated)
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	roles, err := rolesClaim(claims["roles"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return &Principal{Subject: subject, Kind: KindJWT, Roles: roles, credential: token}, nil
+}
+
+// rolesClaim returns the roles of a roles claim, either an array of strings or a space-separated string.
+func rolesClaim(claim any) ([]string, error) {
+	switch v := claim.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []any:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			role, ok := r.(string)
+			if !ok {
+				return nil, errors.New("token has a role that is not a string")
+			}
+			roles = append(roles, role)
+		}
+		return roles, nil
+	default:
+		return nil, errors.New("token has invalid roles")
+	}
+}
+
+// key returns the key verifying a token: the secret for HS256, or the RSA key of its kid header for RS256.
+// A token without kid is verified by the only RSA key of the set.
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" && len(a.rsaKeys) == 1 {
+			for _, key := range a.rsaKeys {
+				return key, nil
+			}
+		}
+		if key, ok := a.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// AuthenticateAPIKey verifies an API key by its hash and returns the principal of its name.
+func (a *Authenticator) AuthenticateAPIKey(key string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	k, ok := a.apiKeys[hex.EncodeToString(hash[:])]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown API key", ErrUnauthenticated)
+	}
+	return &Principal{Subject: k.name, Kind: KindAPIKey, Roles: k.roles, credential: key}, nil
+}
+
+// authenticate verifies the bearer token or else the API key of a request.
+func (a *Authenticator) authenticate(authorization, apiKey string) (*Principal, error) {
+	if authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrUnauthenticated)
+		}
+		return a.AuthenticateJWT(strings.TrimSpace(token))
+	}
+	if apiKey != "" {
+		return a.AuthenticateAPIKey(apiKey)
+	}
+	return nil, fmt.Errorf("%w: no credentials", ErrUnauthenticated)
+}
+
+// jwks is a JSON Web Key Set.
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS returns the RSA signing keys of a JSON Web Key Set file by their id.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %q: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent of key %q", k.Kid)
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("duplicate key %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing key")
+	}
+	return keys, nil
+}
+
+// loadAPIKeys returns the callers of the API keys of a file by their hash.
+func loadAPIKeys(path string) (map[string]apiKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]apiKey{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		hash, roles, _ := strings.Cut(value, ":")
+		name, hash = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(hash))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: want NAME=HASH or NAME=HASH:ROLE,...", i+1)
+		}
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: API key %s is not a hex-encoded SHA-256 hash", i+1, name)
+		}
+		if _, ok := keys[hash]; ok {
+			return nil, fmt.Errorf("line %d: API key %s is a duplicate", i+1, name)
+		}
+		k := apiKey{name: name}
+		for _, role := range strings.Split(roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				k.roles = append(k.roles, role)
+			}
+		}
+		keys[hash] = k
+	}
+	return keys, nil
+}
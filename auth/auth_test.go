@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const secret = "test-secret"
+
+func writeFile(t *testing.T, name string, content []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "alice", "iss": "movieexample", "aud": []string{"movie"}, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	set, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "key-1",
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+
+	a, err := New(Config{JWTSecret: secret, JWKSPath: writeFile(t, "jwks.json", set), Issuer: "movieexample", Audience: "movie"})
+	require.NoError(t, err)
+
+	p, err := a.AuthenticateJWT(sign(t, jwt.SigningMethodHS256, []byte(secret), "", validClaims()))
+	require.NoError(t, err)
+	require.Equal(t, "alice", p.Subject)
+	require.Equal(t, KindJWT, p.Kind)
+
+	p, err = a.AuthenticateJWT(sign(t, jwt.SigningMethodRS256, rsaKey, "key-1", validClaims()))
+	require.NoError(t, err)
+	require.Equal(t, "alice", p.Subject)
+	require.Empty(t, p.Roles)
+
+	for _, roles := range []any{[]string{"editor", "operator"}, "editor operator"} {
+		claims := validClaims()
+		claims["roles"] = roles
+		p, err = a.AuthenticateJWT(sign(t, jwt.SigningMethodHS256, []byte(secret), "", claims))
+		require.NoError(t, err)
+		require.Equal(t, []string{"editor", "operator"}, p.Roles)
+	}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+	invalidRoles := validClaims()
+	invalidRoles["roles"] = []any{"editor", 1}
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "rating"
+	noSubject := validClaims()
+	delete(noSubject, "sub")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"wrong secret":   sign(t, jwt.SigningMethodHS256, []byte("other"), "", validClaims()),
+		"expired":        sign(t, jwt.SigningMethodHS256, []byte(secret), "", expired),
+		"no expiry":      sign(t, jwt.SigningMethodHS256, []byte(secret), "", noExpiry),
+		"wrong audience": sign(t, jwt.SigningMethodHS256, []byte(secret), "", wrongAudience),
+		"no subject":     sign(t, jwt.SigningMethodHS256, []byte(secret), "", noSubject),
+		"invalid roles":  sign(t, jwt.SigningMethodHS256, []byte(secret), "", invalidRoles),
+		"unknown key":    sign(t, jwt.SigningMethodRS256, rsaKey, "key-2", validClaims()),
+		"wrong key":      sign(t, jwt.SigningMethodRS256, otherKey, "key-1", validClaims()),
+		"HS384":          sign(t, jwt.SigningMethodHS384, []byte(secret), "", validClaims()),
+		"none":           sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+	} {
+		_, err := a.AuthenticateJWT(token)
+		require.ErrorIs(t, err, ErrUnauthenticated, name)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	hash := sha256.Sum256([]byte("s3cr3t-key"))
+	editorHash := sha256.Sum256([]byte("editor-key"))
+	a, err := New(Config{APIKeysPath: writeFile(t, "api-keys.env", []byte(
+		"rating-producer="+hex.EncodeToString(hash[:])+"\n"+
+			"# Catalog importer\n"+
+			"importer="+hex.EncodeToString(editorHash[:])+":editor, operator\n"))})
+	require.NoError(t, err)
+
+	p, err := a.AuthenticateAPIKey("s3cr3t-key")
+	require.NoError(t, err)
+	require.Equal(t, &Principal{Subject: "rating-producer", Kind: KindAPIKey, credential: "s3cr3t-key"}, p)
+	p, err = a.AuthenticateAPIKey("editor-key")
+	require.NoError(t, err)
+	require.Equal(t, []string{"editor", "operator"}, p.Roles)
+
+	_, err = a.AuthenticateAPIKey("guess")
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = a.AuthenticateJWT(sign(t, jwt.SigningMethodHS256, []byte(secret), "", validClaims()))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = New(Config{APIKeysPath: writeFile(t, "api-keys.env", []byte("rating-producer=plaintext\n"))})
+	require.Error(t, err)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a, err := New(Config{JWTSecret: secret})
+	require.NoError(t, err)
+	interceptor := a.UnaryServerInterceptor()
+	token := sign(t, jwt.SigningMethodHS256, []byte(secret), "", validClaims())
+
+	var principal *Principal
+	handler := func(ctx context.Context, req any) (any, error) {
+		principal, _ = FromContext(ctx)
+		return nil, nil
+	}
+	call := func(ctx context.Context, method string) error {
+		principal = nil
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	err = call(context.Background(), "/RatingService/PutRating")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NoError(t, call(context.Background(), "/grpc.health.v1.Health/Check"))
+	require.Nil(t, principal)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationMetadata, "Bearer "+token))
+	require.NoError(t, call(ctx, "/RatingService/PutRating"))
+	require.Equal(t, "alice", principal.Subject)
+
+	// The credentials of the principal are forwarded downstream.
+	var forwarded []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		forwarded = md.Get(authorizationMetadata)
+		return nil
+	}
+	require.NoError(t, UnaryClientInterceptor()(NewContext(context.Background(), principal), "/RatingService/PutRating", nil, nil, nil, invoker))
+	require.Equal(t, []string{"Bearer " + token}, forwarded)
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := New(Config{JWTSecret: secret})
+	require.NoError(t, err)
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := FromContext(r.Context())
+		w.Write([]byte(p.Subject))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/movie", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	r := httptest.NewRequest(http.MethodGet, "/movie", nil)
+	r.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(secret), "", validClaims()))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "alice", w.Body.String())
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Roles granted to principals.
+const (
+	// RoleEditor edits the catalog: it creates, updates, deletes and imports movie metadata.
+	RoleEditor = "editor"
+	// RoleOperator calls the admin methods operating the services.
+	RoleOperator = "operator"
+)
+
+// AdminMethods matches the methods of the admin services, whose names end with Admin.
+const AdminMethods = "/*Admin/*"
+
+// ErrPermissionDenied is returned when a principal lacks the roles required by a method.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Policy maps the methods to the roles allowed to call them. Its keys are path.Match patterns matching full gRPC
+// method names such as /MetadataService/PutMetadata when they contain a slash, or else method names without their
+// service, which are also the names of the HTTP handlers, such as PutMetadata or Delete*. A caller needs one of
+// the roles of every pattern matching a method. Methods no pattern matches are allowed to every caller.
+type Policy map[string][]string
+
+// Validate checks the patterns of the policy.
+func (p Policy) Validate() error {
+	for pattern, roles := range p {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if len(roles) == 0 {
+			return fmt.Errorf("pattern %q allows no role", pattern)
+		}
+	}
+	return nil
+}
+
+// required returns the sets of roles a caller of the method needs one role of, sorted by pattern.
+func (p Policy) required(method string) [][]string {
+	name := method[strings.LastIndex(method, "/")+1:]
+	patterns := make([]string, 0, len(p))
+	for pattern := range p {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			subject = method
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+
+	required := make([][]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		required = append(required, p[pattern])
+	}
+	return required
+}
+
+// Authorizer enforces a policy on the principals of requests and audits its denials.
+// A nil authorizer allows every request.
+type Authorizer struct {
+	policy   Policy
+	denied   *prometheus.CounterVec
+	onDenied func(ctx context.Context, method string, err error)
+}
+
+// NewAuthorizer creates an authorizer enforcing the policy.
+func NewAuthorizer(policy Policy) (*Authorizer, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Authorizer{
+		policy: policy,
+		denied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "auth",
+			Name:      "permission_denied_total",
+			Help:      "Number of requests denied for lack of roles, by method.",
+		}, []string{"method"}),
+	}, nil
+}
+
+// Collectors returns the Prometheus collectors of the authorization metrics.
+func (a *Authorizer) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{a.denied}
+}
+
+// OnDenied sets the function recording the denials in the audit log, called with the method denied and the error
+// returned. It must be set before the authorizer serves requests.
+func (a *Authorizer) OnDenied(record func(ctx context.Context, method string, err error)) {
+	if a == nil {
+		return
+	}
+	a.onDenied = record
+}
+
+// Authorize returns ErrPermissionDenied if the principal of ctx lacks the roles the policy requires to call the
+// method, a full gRPC method name or an HTTP handler name. Requests without principal are denied the methods
+// requiring roles. Denials are logged and recorded with the OnDenied function, if set.
+func (a *Authorizer) Authorize(ctx context.Context, method string) error {
+	if a == nil {
+		return nil
+	}
+	p, _ := FromContext(ctx)
+	for _, roles := range a.policy.required(method) {
+		if p != nil && hasAnyRole(p, roles) {
+			continue
+		}
+
+		a.denied.WithLabelValues(method).Inc()
+		attrs := []any{
+			slog.String("method", method),
+			slog.String("required_roles", strings.Join(roles, ",")),
+		}
+		if p != nil {
+			attrs = append(attrs,
+				slog.String("subject", p.Subject),
+				slog.String("kind", p.Kind),
+				slog.String("roles", strings.Join(p.Roles, ",")),
+			)
+		}
+		slog.Warn("Permission denied", attrs...)
+		err := fmt.Errorf("%w: %s requires one of the roles %s", ErrPermissionDenied, method, strings.Join(roles, ", "))
+		if a.onDenied != nil {
+			a.onDenied(ctx, method, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func hasAnyRole(p *Principal, roles []string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor rejecting the unary requests the policy denies with
+// PermissionDenied. It must run after the authentication.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.Authorize(ctx, info.FullMethod); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor rejecting the streams the policy denies with
+// PermissionDenied. It must run after the authentication.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.Authorize(ss.Context(), info.FullMethod); err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(srv, ss)
+	}
+}
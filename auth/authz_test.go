@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorize(t *testing.T) {
+	a, err := NewAuthorizer(Policy{
+		"Put*":       {RoleEditor},
+		"Delete*":    {RoleEditor},
+		AdminMethods: {RoleOperator},
+	})
+	require.NoError(t, err)
+
+	editor := NewContext(context.Background(), &Principal{Subject: "alice", Roles: []string{RoleEditor}})
+	operator := NewContext(context.Background(), &Principal{Subject: "bob", Roles: []string{RoleOperator}})
+	viewer := NewContext(context.Background(), &Principal{Subject: "carol"})
+
+	for _, tt := range []struct {
+		ctx     context.Context
+		method  string
+		allowed bool
+	}{
+		{viewer, "/MetadataService/GetMetadata", true},
+		{context.Background(), "/MetadataService/GetMetadata", true},
+		{editor, "/MetadataService/PutMetadata", true},
+		{editor, "PutMetadata", true},
+		{viewer, "/MetadataService/PutMetadata", false},
+		{viewer, "PutMetadata", false},
+		{context.Background(), "/MetadataService/DeleteMetadata", false},
+		{operator, "/MetadataService/PutMetadata", false},
+		{operator, "/MetadataAdmin/Reindex", true},
+		{editor, "/MetadataAdmin/Reindex", false},
+		// An admin method must satisfy every matching pattern.
+		{operator, "/MetadataAdmin/DeleteAll", false},
+	} {
+		err := a.Authorize(tt.ctx, tt.method)
+		if tt.allowed {
+			require.NoError(t, err, tt.method)
+		} else {
+			require.ErrorIs(t, err, ErrPermissionDenied, tt.method)
+		}
+	}
+
+	var nilAuthorizer *Authorizer
+	require.NoError(t, nilAuthorizer.Authorize(viewer, "/MetadataService/PutMetadata"))
+
+	_, err = NewAuthorizer(Policy{"[": {RoleEditor}})
+	require.Error(t, err)
+	_, err = NewAuthorizer(Policy{"Put*": nil})
+	require.Error(t, err)
+}
+
+func TestAuthorizerUnaryServerInterceptor(t *testing.T) {
+	a, err := NewAuthorizer(Policy{"Put*": {RoleEditor}})
+	require.NoError(t, err)
+	interceptor := a.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	ctx := NewContext(context.Background(), &Principal{Subject: "carol"})
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/MetadataService/PutMetadata"}, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/MetadataService/GetMetadata"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+}
+
+func TestAuthorizerOnDenied(t *testing.T) {
+	a, err := NewAuthorizer(Policy{"Put*": {RoleEditor}})
+	require.NoError(t, err)
+	var denied []string
+	a.OnDenied(func(ctx context.Context, method string, err error) {
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		denied = append(denied, method)
+	})
+
+	ctx := NewContext(context.Background(), &Principal{Subject: "carol"})
+	require.Error(t, a.Authorize(ctx, "/MetadataService/PutMetadata"))
+	require.NoError(t, a.Authorize(ctx, "/MetadataService/GetMetadata"))
+	require.Equal(t, []string{"/MetadataService/PutMetadata"}, denied)
+
+	var nilAuthorizer *Authorizer
+	nilAuthorizer.OnDenied(nil)
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys carrying the credentials of a gRPC request.
+const (
+	authorizationMetadata = "authorization"
+	apiKeyMetadata        = "x-api-key"
+)
+
+// public reports whether a gRPC method is served without authentication, as health checks are.
+func public(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// authenticateGRPC verifies the credentials of an incoming gRPC request and returns its context with its principal.
+func (a *Authenticator) authenticateGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	if public(fullMethod) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	p, err := a.authenticate(first(authorizationMetadata), first(apiKeyMetadata))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return NewContext(ctx, p), nil
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor rejecting the unary requests without valid credentials with
+// Unauthenticated, and adding the principal of the others to their context. Credentials are a JWT in the
+// authorization metadata as a bearer token, or an API key in the x-api-key metadata.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticateGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor rejecting the streams without valid credentials with
+// Unauthenticated, and adding the principal of the others to their context.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor forwarding the credentials of the principal of ctx to the
+// called service, so it authenticates the original caller.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if p, ok := FromContext(ctx); ok {
+			switch p.Kind {
+			case KindJWT:
+				ctx = metadata.AppendToOutgoingContext(ctx, authorizationMetadata, "Bearer "+p.credential)
+			case KindAPIKey:
+				ctx = metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, p.credential)
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// apiKeyHeader is the header carrying the API key of an HTTP request.
+const apiKeyHeader = "X-Api-Key"
+
+// Middleware returns an HTTP handler rejecting the requests without valid credentials with 401 Unauthorized, and
+// adding the principal of the others to their context. Credentials are a JWT in the Authorization header as
+// a bearer token, or an API key in the X-Api-Key header.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r.Header.Get("Authorization"), r.Header.Get(apiKeyHeader))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
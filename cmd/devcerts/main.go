@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// options defines the command line options of the certificate generation.
+type options struct {
+	dir      string
+	services string
+	hosts    string
+	validity time.Duration
+	newCA    bool
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.dir, "dir", "certs", "Directory to write the certificates to")
+	flag.StringVar(&opts.services, "services", "metadata,movie,rating", "Comma-separated names of the services to issue certificates to")
+	flag.StringVar(&opts.hosts, "hosts", "localhost,127.0.0.1", "Comma-separated host names and IP addresses added to every service certificate")
+	flag.DurationVar(&opts.validity, "validity", 30*24*time.Hour, "Validity of the service certificates")
+	flag.BoolVar(&opts.newCA, "new-ca", false, "Create a new CA even if the directory has one; the services must then be restarted")
+	flag.Parse()
+
+	if err := run(&opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run issues a certificate to every service, signed by the CA of the directory or a new one. Running it again
+// rotates the service certificates, which the running services reload.
+func run(opts *options) error {
+	if err := os.MkdirAll(opts.dir, 0o755); err != nil {
+		return err
+	}
+	caCert, caKey, err := loadCA(opts.dir)
+	if opts.newCA || errors.Is(err, os.ErrNotExist) {
+		if caCert, caKey, err = createCA(opts.dir); err != nil {
+			return fmt.Errorf("failed to create CA: %w", err)
+		}
+		fmt.Printf("Created CA %s\n", filepath.Join(opts.dir, "ca.pem"))
+	} else if err != nil {
+		return fmt.Errorf("failed to load CA: %w", err)
+	}
+
+	for _, service := range strings.Split(opts.services, ",") {
+		service = strings.TrimSpace(service)
+		if service == "" {
+			continue
+		}
+		certPath, keyPath, err := issue(opts, service, caCert, caKey)
+		if err != nil {
+			return fmt.Errorf("failed to issue certificate of %s: %w", service, err)
+		}
+		fmt.Printf("\n# %s\nTLS_ENABLED=true\nTLS_CA_PATH=%s\nTLS_CERT_PATH=%s\nTLS_KEY_PATH=%s\n",
+			service, filepath.Join(opts.dir, "ca.pem"), certPath, keyPath)
+	}
+	return nil
+}
+
+// loadCA reads the CA certificate and key of the directory.
+func loadCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, errors.New("invalid PEM file")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// createCA writes a new self-signed CA certificate and its key to the directory.
+func createCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "movieexample dev CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(filepath.Join(dir, "ca-key.pem"), "EC PRIVATE KEY", mustMarshalKey(key), 0o600); err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(filepath.Join(dir, "ca.pem"), "CERTIFICATE", der, 0o644); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// issue writes a certificate of the service signed by the CA, valid for serving and calling the other services,
+// with the service name and the hosts of the options as subject alternative names.
+func issue(opts *options, service string, caCert *x509.Certificate, caKey crypto.Signer) (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return "", "", err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: service},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(opts.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{service},
+	}
+	for _, host := range strings.Split(opts.hosts, ",") {
+		host = strings.TrimSpace(host)
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The key is written first: a service reloading in between fails to match the old certificate with the new
+	// key and keeps its previous certificates until the next reload.
+	certPath := filepath.Join(opts.dir, service+".pem")
+	keyPath := filepath.Join(opts.dir, service+"-key.pem")
+	if err := writePEM(keyPath, "EC PRIVATE KEY", mustMarshalKey(key), 0o600); err != nil {
+		return "", "", err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der, 0o644); err != nil {
+		return "", "", err
+	}
+	return certPath, keyPath, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func mustMarshalKey(key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+// writePEM writes a PEM block to a temporary file renamed to path, so readers never see a partial file.
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pem.Encode(tmp, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
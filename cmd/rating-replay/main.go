@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"main/config"
+	"main/database/db"
+	"main/eventbus"
+	"main/eventbus/pulsar"
+	"main/rating/repository/memory"
+	"main/rating/repository/postgres"
+	"main/rating/service"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	modeDryRun = "dry-run"
+	modeApply  = "apply"
+
+	targetLive  = "live"
+	targetFresh = "fresh"
+)
+
+// options defines the command line options of the replay.
+type options struct {
+	fromID   string
+	fromTime string
+	until    string
+	mode     string
+	target   string
+	swap     bool
+	show     int
+}
+
+// replayStats counts the outcome of every replayed message.
+type replayStats struct {
+	read       int
+	applied    int
+	duplicates int
+	failed     int
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.fromID, "from-id", "", "Message id to start the replay at, inclusive; excludes -from-time")
+	flag.StringVar(&opts.fromTime, "from-time", "", "RFC 3339 publish time to start the replay at")
+	flag.StringVar(&opts.until, "until", "", "RFC 3339 publish time to stop the replay at, defaults to the end of the topic")
+	flag.StringVar(&opts.mode, "mode", modeDryRun, "dry-run applies the events to an in-memory repository, apply writes them to the database")
+	flag.StringVar(&opts.target, "target", targetFresh, "Database target in apply mode: live applies missing events to the live tables, fresh rebuilds the tables in the "+db.ReplaySchema+" schema")
+	flag.BoolVar(&opts.swap, "swap", false, "Swap the rebuilt tables with the live ones after a fresh replay; stop the rating consumers first")
+	flag.IntVar(&opts.show, "show", 20, "Maximum number of records to print")
+	var cfg config.Rating
+	config.MustLoad(&cfg)
+
+	if err := opts.validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, &cfg, &opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (o *options) validate() error {
+	if o.mode != modeDryRun && o.mode != modeApply {
+		return fmt.Errorf("unknown mode %q", o.mode)
+	}
+	if o.target != targetLive && o.target != targetFresh {
+		return fmt.Errorf("unknown target %q", o.target)
+	}
+	// Messages skipped for being published before -from-time are still read, so HasNext reports messages Next
+	// never returns and the replay would wait at the end of the topic.
+	if o.fromID != "" && o.fromTime != "" {
+		return errors.New("-from-id and -from-time are mutually exclusive")
+	}
+	if o.swap && (o.mode != modeApply || o.target != targetFresh) {
+		return errors.New("-swap requires -mode apply and -target fresh")
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func run(ctx context.Context, cfg *config.Rating, opts *options) error {
+	fromTime, err := parseTime(opts.fromTime)
+	if err != nil {
+		return fmt.Errorf("invalid -from-time: %w", err)
+	}
+	until, err := parseTime(opts.until)
+	if err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+
+	bus, err := pulsar.New(cfg.Pulsar)
+	if err != nil {
+		return fmt.Errorf("failed to create pulsar client: %w", err)
+	}
+	defer bus.Close()
+
+	reader, err := bus.Reader(eventbus.ReaderOptions{
+		Topic:          cfg.TopicName,
+		StartMessageID: opts.fromID,
+		StartTime:      fromTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+
+	if opts.mode == modeDryRun {
+		repo := memory.New()
+		stats, err := replay(ctx, reader, service.New(repo, nil, cfg, nil), until)
+		if err != nil {
+			return err
+		}
+		printStats(stats)
+		aggregates, err := repo.Aggregates(ctx, time.Now(), cfg.TrendingHalfLife)
+		if err != nil {
+			return err
+		}
+		// The in-memory aggregates come in no particular order; sort them like the compared records of a fresh replay.
+		sort.Slice(aggregates, func(i, j int) bool {
+			if aggregates[i].RecordID != aggregates[j].RecordID {
+				return aggregates[i].RecordID < aggregates[j].RecordID
+			}
+			return aggregates[i].RecordType < aggregates[j].RecordType
+		})
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RECORD TYPE\tRECORD ID\tCOUNT\tAVERAGE")
+		for i, agg := range aggregates {
+			if i == opts.show {
+				break
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.3f\n", agg.RecordType, agg.RecordID, agg.RatingCount, agg.AverageValue)
+		}
+		return w.Flush()
+	}
+
+	conn, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	defer conn.Close()
+	store := db.NewStore(conn)
+
+	if opts.target == targetLive {
+		stats, err := replay(ctx, reader, service.New(postgres.New(store, nil), nil, cfg, nil), until)
+		printStats(stats)
+		return err
+	}
+
+	slog.Info("Preparing the replay schema", slog.String("schema", db.ReplaySchema))
+	if err := store.PrepareReplaySchema(ctx); err != nil {
+		return fmt.Errorf("failed to prepare replay schema: %w", err)
+	}
+	replayConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	replayConfig.ConnConfig.RuntimeParams["search_path"] = db.ReplaySchema
+	replayConn, err := pgxpool.NewWithConfig(ctx, replayConfig)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	defer replayConn.Close()
+
+	stats, err := replay(ctx, reader, service.New(postgres.New(db.NewStore(replayConn), nil), nil, cfg, nil), until)
+	if err != nil {
+		return err
+	}
+	printStats(stats)
+
+	diffs, err := store.CompareReplay(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to compare replay: %w", err)
+	}
+	fmt.Printf("%d records differ between the live and the replayed ratings\n", len(diffs))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RECORD TYPE\tRECORD ID\tLIVE COUNT\tLIVE AVERAGE\tREPLAY COUNT\tREPLAY AVERAGE")
+	for i, diff := range diffs {
+		if i == opts.show {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.3f\t%d\t%.3f\n", diff.RecordType, diff.MovieID, diff.LiveCount, diff.LiveAverage, diff.ReplayCount, diff.ReplayAverage)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !opts.swap {
+		fmt.Printf("Replayed tables kept in the %s schema, run again with -swap to replace the live ones\n", db.ReplaySchema)
+		return nil
+	}
+	if stats.failed > 0 {
+		return fmt.Errorf("refusing to swap: %d events failed to replay", stats.failed)
+	}
+	if err := store.SwapReplaySchema(ctx); err != nil {
+		return fmt.Errorf("failed to swap replay tables: %w", err)
+	}
+	fmt.Printf("Swapped the live rating tables, the previous ones are kept in the %s schema\n", db.ReplayBackupSchema)
+	return nil
+}
+
+// replay applies the messages of the reader through the rating event handler, in order,
+// until the end of the topic or the first message published after until.
+func replay(ctx context.Context, reader eventbus.Reader, svc *service.RatingService, until time.Time) (replayStats, error) {
+	var stats replayStats
+	for reader.HasNext() {
+		msg, err := reader.Next(ctx)
+		if err != nil {
+			return stats, err
+		}
+		if !until.IsZero() && msg.PublishTime().After(until) {
+			break
+		}
+		stats.read++
+
+		err = svc.HandleMessage(ctx, msg)
+		switch {
+		case err == nil:
+			stats.applied++
+		case errors.Is(err, service.ErrDuplicateEvent):
+			stats.duplicates++
+		default:
+			stats.failed++
+			slog.Warn("failed to replay rating event:", slog.String("msg_id", msg.ID()), slog.String("error", err.Error()))
+		}
+	}
+	return stats, ctx.Err()
+}
+
+func printStats(stats replayStats) {
+	fmt.Printf("Replayed %d events: %d applied, %d duplicates, %d failed\n", stats.read, stats.applied, stats.duplicates, stats.failed)
+}
@@ -2,87 +2,194 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"main/config"
+	"main/eventbus"
+	"main/eventbus/pulsar"
+	"main/eventcodec"
 	"main/rating/model"
-	"main/util"
+	"main/tracing"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"golang.org/x/time/rate"
 )
 
+// eventSource is the CloudEvents source of the produced rating events.
+const eventSource = "/producer"
+
+const serviceName = "rating-producer"
+
 func main() {
-	var config string
-	var data string
-	flag.StringVar(&config, "config", ".env", "Configuration path")
-	flag.StringVar(&data, "data", "data.json", "Load Rating data")
-	flag.Parse()
-	cfg := util.LoadConfig(config)
-
-	client, err := pulsar.NewClient(pulsar.ClientOptions{
-		URL:               cfg.PulsarURL,
-		ConnectionTimeout: cfg.ConnectionTimeout,
-		OperationTimeout:  cfg.OperationTimeout,
-	})
+	var data, distribution, contentType string
+	var synthetic bool
+	var users, movies, count, maxPending, batchSize int
+	var zipfS, eventRate float64
+	var seed int64
+	var batchDelay, progress time.Duration
+	flag.StringVar(&data, "data", "data.jsonl", "Rating events file in JSON lines format, - reads from stdin")
+	flag.BoolVar(&synthetic, "synthetic", false, "Generate synthetic rating events instead of reading a file")
+	flag.IntVar(&users, "users", 1000, "Number of synthetic users")
+	flag.IntVar(&movies, "movies", 100, "Number of synthetic movies")
+	flag.IntVar(&count, "count", 10000, "Number of synthetic rating events")
+	flag.StringVar(&distribution, "distribution", distributionUniform, "Movie distribution of synthetic events: uniform or zipf")
+	flag.Float64Var(&zipfS, "zipf-s", 1.1, "Exponent of the zipf distribution, must be greater than 1")
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "Seed of the synthetic event generator")
+	flag.Float64Var(&eventRate, "rate", 0, "Target rate in events per second, 0 is unlimited")
+	flag.IntVar(&maxPending, "max-pending", 0, "Maximum number of in-flight events, overrides MAX_PENDING_MESSAGES")
+	flag.IntVar(&batchSize, "batch-size", 0, "Maximum number of events in a batch, overrides BATCHING_MAX_MESSAGES")
+	flag.DurationVar(&batchDelay, "batch-delay", 0, "Maximum delay before a batch is sent, overrides BATCHING_MAX_PUBLISH_DELAY")
+	flag.DurationVar(&progress, "progress", 5*time.Second, "Progress report interval")
+	flag.StringVar(&contentType, "content-type", "", "Content type of the events, defaults to EVENT_CONTENT_TYPE")
+	var cfg config.RatingProducer
+	config.MustLoad(&cfg)
+
+	if maxPending > 0 {
+		cfg.Pulsar.MaxPendingMessages = maxPending
+	}
+	if batchSize > 0 {
+		cfg.Pulsar.BatchingMaxMessages = uint(batchSize)
+	}
+	if batchDelay > 0 {
+		cfg.Pulsar.BatchingMaxPublishDelay = batchDelay
+	}
+	if contentType == "" {
+		contentType = cfg.EventContentType
+	}
+
+	var source ratingSource
+	var err error
+	if synthetic {
+		fmt.Printf("Generating %d rating events by %d users for %d movies with %s distribution\n", count, users, movies, distribution)
+		source, err = newSyntheticSource(users, movies, count, distribution, zipfS, seed)
+	} else {
+		fmt.Println("Reading rating events from file:", data)
+		source, err = openFileSource(data)
+	}
+	if err != nil {
+		log.Fatal("failed to open rating source:", err)
+	}
+	defer source.Close()
+
+	tp, err := tracing.NewJaegerProvider(cfg.JaegerURL, serviceName)
+	if err != nil {
+		log.Fatal("failed to initialize Jaeger provider:", err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Println("failed to shutdown tracing provider:", err)
+		}
+	}()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	bus, err := pulsar.New(cfg.Pulsar)
 	if err != nil {
 		log.Fatal("failed to create pulsar client:", err)
 	}
-	defer client.Close()
+	defer bus.Close()
 
 	fmt.Println("Creating a Pulsar producer")
 
-	producer, err := client.CreateProducer(pulsar.ProducerOptions{
-		Topic: cfg.TopicName,
-	})
+	producer, err := bus.Publisher(cfg.TopicName)
 	if err != nil {
 		log.Fatal("failed to create producer:", err)
 	}
 	defer producer.Close()
 
-	fmt.Println("Reading rating events from file:", data)
-	ratingEvents, err := readRatingEvents(data)
-	if err != nil {
-		log.Fatal("failed to read events from file:", err)
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := produceRatingEvents(producer, cfg.TopicName, ratingEvents); err != nil {
-		log.Fatal("failed produce rating events:", err)
+	var limiter *rate.Limiter
+	if eventRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(eventRate), 1)
 	}
 
+	st := newStats()
+	reportCtx, stopReport := context.WithCancel(ctx)
+	go st.report(reportCtx, progress)
+
+	produceErr := produceRatingEvents(ctx, producer, cfg.TopicName, source, limiter, contentType, st)
 	if err := producer.Flush(); err != nil {
-		panic(err)
+		log.Println("failed to flush producer:", err)
 	}
-}
+	stopReport()
+	st.summary()
 
-func readRatingEvents(fileName string) ([]model.RatingEvent, error) {
-	f, err := os.Open(fileName)
-	if err != nil {
-		return nil, err
+	if produceErr != nil && !errors.Is(produceErr, context.Canceled) {
+		log.Fatal("failed to produce rating events:", produceErr)
 	}
-	defer f.Close()
-	var ratings []model.RatingEvent
-	if err := json.NewDecoder(f).Decode(&ratings); err != nil {
-		return nil, err
-	}
-	return ratings, nil
 }
 
-func produceRatingEvents(producer pulsar.Producer, topicName string, ratingEvents []model.RatingEvent) error {
-	for _, ratingEvent := range ratingEvents {
-		encodedEvent, err := json.Marshal(ratingEvent)
+// produceRatingEvents sends every event of the source asynchronously and waits until all of them are acknowledged.
+func produceRatingEvents(ctx context.Context, producer eventbus.Publisher, topic string, source ratingSource, limiter *rate.Limiter, contentType string, st *stats) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		ratingEvent, err := source.Next()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to read rating event: %w", err)
+		}
+		if ratingEvent.ID == "" {
+			ratingEvent.ID = uuid.NewString()
+		}
+		if ratingEvent.OccurredAt.IsZero() {
+			ratingEvent.OccurredAt = time.Now()
 		}
 
-		msgId, err := producer.Send(context.Background(), &pulsar.ProducerMessage{
-			Payload: encodedEvent,
-		})
+		encodedEvent, err := eventcodec.Encode(contentType, &eventcodec.Envelope{
+			ID:      ratingEvent.ID,
+			Source:  eventSource,
+			Subject: string(ratingEvent.RecordID),
+			Time:    ratingEvent.OccurredAt,
+		}, model.RatingEventToProto(ratingEvent))
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Produced event to topic %s: msgId = %s, value = %s\n", topicName, msgId.String(), string(encodedEvent))
+
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msg := &eventbus.OutgoingMessage{
+			Key:        string(ratingEvent.RecordID),
+			Payload:    encodedEvent,
+			Properties: map[string]string{eventcodec.PropertyContentType: contentType},
+			EventTime:  ratingEvent.OccurredAt,
+		}
+		spanCtx, span := tracing.StartProducerSpan(ctx, serviceName, topic, msg)
+
+		sentAt := time.Now()
+		st.sent.Add(1)
+		wg.Add(1)
+		producer.PublishAsync(spanCtx, msg, func(_ string, err error) {
+			defer wg.Done()
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				log.Println("failed to produce rating event:", err)
+			}
+			span.End()
+			st.done(time.Since(sentAt), err)
+		})
 	}
-	return nil
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"main/rating/model"
+	"math/rand"
+	"os"
+)
+
+// ratingSource defines a source of rating events to produce.
+type ratingSource interface {
+	// Next returns the next rating event, or io.EOF once the source is exhausted.
+	Next() (*model.RatingEvent, error)
+	Close() error
+}
+
+// fileSource streams rating events from a JSON lines file. A single JSON array of events is accepted as well.
+type fileSource struct {
+	r     io.ReadCloser
+	dec   *json.Decoder
+	array bool
+}
+
+// openFileSource opens a file source, reading from stdin if path is "-".
+func openFileSource(path string) (*fileSource, error) {
+	r := io.ReadCloser(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err != nil && err != io.EOF {
+		r.Close()
+		return nil, err
+	}
+
+	src := &fileSource{r: r, dec: json.NewDecoder(br)}
+	if first == '[' {
+		if _, err := src.dec.Token(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		src.array = true
+	}
+	return src, nil
+}
+
+// firstNonSpace returns the first non-whitespace byte of r without consuming it.
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+func (s *fileSource) Next() (*model.RatingEvent, error) {
+	if s.array && !s.dec.More() {
+		return nil, io.EOF
+	}
+	var event model.RatingEvent
+	if err := s.dec.Decode(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (s *fileSource) Close() error {
+	return s.r.Close()
+}
+
+// Movie popularity distributions of synthetic events.
+const (
+	distributionUniform = "uniform"
+	distributionZipf    = "zipf"
+)
+
+// syntheticSource generates count random ratings by users for movies. Movies are picked according to the
+// distribution: uniform, or zipf where a few movies receive most of the ratings.
+type syntheticSource struct {
+	users     int
+	remaining int
+	rnd       *rand.Rand
+	movie     func() int
+}
+
+func newSyntheticSource(users, movies, count int, distribution string, zipfS float64, seed int64) (*syntheticSource, error) {
+	if users < 1 || movies < 1 {
+		return nil, fmt.Errorf("users and movies must be positive")
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	src := &syntheticSource{users: users, remaining: count, rnd: rnd}
+	switch distribution {
+	case distributionUniform:
+		src.movie = func() int { return rnd.Intn(movies) }
+	case distributionZipf:
+		if zipfS <= 1 {
+			return nil, fmt.Errorf("zipf exponent must be greater than 1")
+		}
+		zipf := rand.NewZipf(rnd, zipfS, 1, uint64(movies-1))
+		src.movie = func() int { return int(zipf.Uint64()) }
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", distribution)
+	}
+	return src, nil
+}
+
+func (s *syntheticSource) Next() (*model.RatingEvent, error) {
+	if s.remaining == 0 {
+		return nil, io.EOF
+	}
+	s.remaining--
+	return &model.RatingEvent{
+		UserID:     model.UserID(fmt.Sprintf("user-%d", s.rnd.Intn(s.users)+1)),
+		RecordID:   model.RecordID(fmt.Sprintf("%d", s.movie()+1)),
+		RecordType: model.RecordTypeMovie,
+		Value:      model.RatingValue(s.rnd.Intn(5) + 1),
+		EventType:  model.RatingEventTypePut,
+	}, nil
+}
+
+func (s *syntheticSource) Close() error {
+	return nil
+}
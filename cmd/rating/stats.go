@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples is the size of the reservoir the latency percentiles are computed from.
+const latencySamples = 100000
+
+// stats tracks the sent events and the latency between queueing an event and its acknowledgement by the broker.
+type stats struct {
+	start  time.Time
+	sent   atomic.Int64
+	acked  atomic.Int64
+	failed atomic.Int64
+
+	mu      sync.Mutex
+	seen    int64
+	sum     time.Duration
+	max     time.Duration
+	samples []time.Duration
+	rnd     *rand.Rand
+}
+
+func newStats() *stats {
+	return &stats{
+		start:   time.Now(),
+		samples: make([]time.Duration, 0, latencySamples),
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// done records the outcome of a sent event.
+func (s *stats) done(latency time.Duration, err error) {
+	if err != nil {
+		s.failed.Add(1)
+		return
+	}
+	s.acked.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen++
+	s.sum += latency
+	if latency > s.max {
+		s.max = latency
+	}
+	// Reservoir sampling keeps a uniform sample of every latency seen with bounded memory.
+	if len(s.samples) < latencySamples {
+		s.samples = append(s.samples, latency)
+	} else if i := s.rnd.Int63n(s.seen); i < latencySamples {
+		s.samples[i] = latency
+	}
+}
+
+// report prints the progress every interval until the context is cancelled.
+func (s *stats) report(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastAcked int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		acked := s.acked.Load()
+		fmt.Printf("sent=%d acked=%d failed=%d in_flight=%d rate=%.0f/s\n",
+			s.sent.Load(), acked, s.failed.Load(), s.sent.Load()-acked-s.failed.Load(),
+			float64(acked-lastAcked)/interval.Seconds())
+		lastAcked = acked
+	}
+}
+
+// summary prints the final throughput and latency statistics.
+func (s *stats) summary() {
+	elapsed := time.Since(s.start)
+	acked := s.acked.Load()
+	fmt.Printf("Produced %d events in %s: %d acked, %d failed, %.0f events/s\n",
+		s.sent.Load(), elapsed.Round(time.Millisecond), acked, s.failed.Load(), float64(acked)/elapsed.Seconds())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen == 0 {
+		return
+	}
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	fmt.Printf("Latency: avg=%s p50=%s p90=%s p99=%s max=%s\n",
+		(s.sum / time.Duration(s.seen)).Round(time.Microsecond),
+		percentile(0.5).Round(time.Microsecond),
+		percentile(0.9).Round(time.Microsecond),
+		percentile(0.99).Round(time.Microsecond),
+		s.max.Round(time.Microsecond))
+}
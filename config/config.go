@@ -0,0 +1,287 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultPath is the configuration file read when no -config flag is given. Unlike an explicit path, it may be missing.
+const DefaultPath = ".env"
+
+// ErrPrintConfig is returned by Load when the -print-config flag is given.
+var ErrPrintConfig = errors.New("configuration print requested")
+
+// Validator is a configuration able to check its settings once they are loaded.
+type Validator interface {
+	// Validate adds the problems of the invalid settings to p.
+	Validate(p *Problems)
+}
+
+// Sources of a setting value, from the lowest precedence to the highest.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// setting is a configuration field holding the value of the environment variable named by its env tag.
+// Fields of different structs may share a variable, they then hold the same value.
+type setting struct {
+	env          string
+	defaultValue *string
+	description  string
+	secret       bool
+	fields       []reflect.Value
+	value        string
+	source       string
+}
+
+// Load loads the settings of cfg from layered sources: the env-default tags of its fields, then the configuration
+// file given by the -config flag, then the environment, then the command-line flags. Every setting gets a flag
+// named after its environment variable, such as -rating-port for RATING_PORT, unless fs already defines it.
+// Load returns ErrPrintConfig once the settings are loaded if the -print-config flag is given; otherwise it returns
+// the unparsable values and the validation problems of the configuration together, as a *ProblemsError.
+func Load(fs *flag.FlagSet, args []string, cfg Validator) error {
+	settings, err := settingsOf(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := fs.String("config", DefaultPath, "Configuration file path")
+	printConfig := fs.Bool("print-config", false, "Print the configuration with its secrets redacted and exit")
+	flags := map[string]string{}
+	for _, s := range settings {
+		name := flagName(s.env)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		usage := s.description
+		if usage == "" {
+			usage = "Overrides " + s.env
+		}
+		fs.Func(name, usage, func(value string) error {
+			flags[name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	file, err := readFile(fs, *path)
+	if err != nil {
+		return err
+	}
+
+	var p Problems
+	for _, s := range settings {
+		if s.defaultValue != nil {
+			s.value, s.source = *s.defaultValue, sourceDefault
+		}
+		if value, ok := file[s.env]; ok {
+			s.value, s.source = value, sourceFile
+		}
+		if value, ok := os.LookupEnv(s.env); ok {
+			s.value, s.source = value, sourceEnv
+		}
+		if value, ok := flags[flagName(s.env)]; ok {
+			s.value, s.source = value, sourceFlag
+		}
+		for _, field := range s.fields {
+			if err := setValue(field, s.value); err != nil {
+				p.Add(s.env, "invalid value %q from %s: %v", s.value, s.source, err)
+			}
+		}
+	}
+	if *printConfig {
+		return ErrPrintConfig
+	}
+	cfg.Validate(&p)
+	return p.Err()
+}
+
+// Validate returns the validation problems of cfg, if any.
+func Validate(cfg Validator) error {
+	var p Problems
+	cfg.Validate(&p)
+	return p.Err()
+}
+
+// MustLoad loads cfg from the command line of the program and exits with the configuration problems if it is
+// invalid. With the -print-config flag, it prints the configuration with its secrets redacted and exits.
+func MustLoad(cfg Validator) {
+	err := Load(flag.CommandLine, os.Args[1:], cfg)
+	if errors.Is(err, ErrPrintConfig) {
+		if err := Print(os.Stdout, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		err = Validate(cfg)
+		if err == nil {
+			os.Exit(0)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// readFile reads the settings of the configuration file at path. The default file is skipped if it does not exist.
+func readFile(fs *flag.FlagSet, path string) (map[string]string, error) {
+	explicit := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+
+	file, err := godotenv.Read(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configuration file: %w", err)
+	}
+	return file, nil
+}
+
+// Print writes the settings of cfg in the configuration file format. Secret settings and the passwords of URLs
+// are redacted.
+func Print(w io.Writer, cfg any) error {
+	settings, err := settingsOf(cfg)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		value := fmt.Sprint(s.fields[0].Interface())
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.env, redact(value, s.secret)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redact hides the password of a URL value, or the whole value of a secret that is not a URL.
+func redact(value string, secret bool) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	if secret && value != "" {
+		return "REDACTED"
+	}
+	return value
+}
+
+// flagName returns the command-line flag of an environment variable.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// settingsOf returns the settings of the struct cfg points to, in the order of its fields.
+func settingsOf(cfg any) ([]*setting, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("configuration must be a pointer to a struct, got %T", cfg)
+	}
+
+	var settings []*setting
+	byEnv := map[string]*setting{}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field, value := t.Field(i), v.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			env, ok := field.Tag.Lookup("env")
+			if !ok {
+				if value.Kind() == reflect.Struct {
+					walk(value)
+				}
+				continue
+			}
+
+			s, ok := byEnv[env]
+			if !ok {
+				s = &setting{
+					env:         env,
+					description: field.Tag.Get("env-description"),
+				}
+				byEnv[env] = s
+				settings = append(settings, s)
+			}
+			if def, ok := field.Tag.Lookup("env-default"); ok {
+				s.defaultValue = &def
+			}
+			s.secret = s.secret || field.Tag.Get("secret") == "true"
+			s.fields = append(s.fields, value)
+		}
+	}
+	walk(v.Elem())
+	return settings, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses a setting value into its field. An empty value sets the zero value.
+func setValue(field reflect.Value, value string) error {
+	if value == "" {
+		field.SetZero()
+		return nil
+	}
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package config
+
+import (
+	"fmt"
+	"main/auth"
+	"main/dynconfig"
+	"main/dynconfig/consul"
+	"main/dynconfig/file"
+	"main/eventbus"
+	"main/eventbus/pulsar"
+	"main/eventcodec"
+	"main/loadshed"
+	"main/mtls"
+	"main/outbox"
+	"strings"
+	"time"
+)
+
+// Service holds the settings shared by every service.
+type Service struct {
+	Environment     string        `env:"ENVIRONMENT" env-default:"dev"`
+	Host            string        `env:"HOST" env-default:"localhost"`
+	ConsulURL       string        `env:"CONSUL_URL" env-default:"localhost:8500"`
+	JaegerURL       string        `env:"JAEGER_URL" env-default:"localhost:4317"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"15s"`
+	Dynamic
+	Concurrency
+	Auth
+	TLS
+}
+
+// Validate implements Validator.
+func (c *Service) Validate(p *Problems) {
+	p.Required("ENVIRONMENT", c.Environment)
+	p.Required("HOST", c.Host)
+	p.HostPort("CONSUL_URL", c.ConsulURL)
+	p.HostPort("JAEGER_URL", c.JaegerURL)
+	p.Positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	c.Dynamic.Validate(p)
+	c.Concurrency.Validate(p)
+	c.Auth.Validate(p)
+	c.TLS.Validate(p)
+}
+
+// Sources of the dynamic configuration.
+const (
+	DynamicSourceNone   = "none"
+	DynamicSourceFile   = "file"
+	DynamicSourceConsul = "consul"
+)
+
+// Dynamic holds the settings of the source of the dynamic configuration, which is reloaded while the service runs.
+type Dynamic struct {
+	DynamicConfigSource       string        `env:"DYNAMIC_CONFIG_SOURCE" env-default:"none"`
+	DynamicConfigPath         string        `env:"DYNAMIC_CONFIG_PATH" env-default:"dynamic.env"`
+	DynamicConfigPrefix       string        `env:"DYNAMIC_CONFIG_PREFIX" env-default:"config"`
+	DynamicConfigPollInterval time.Duration `env:"DYNAMIC_CONFIG_POLL_INTERVAL" env-default:"5s"`
+}
+
+// Validate implements Validator.
+func (c *Dynamic) Validate(p *Problems) {
+	p.OneOf("DYNAMIC_CONFIG_SOURCE", c.DynamicConfigSource, DynamicSourceNone, DynamicSourceFile, DynamicSourceConsul)
+	switch c.DynamicConfigSource {
+	case DynamicSourceFile:
+		p.Required("DYNAMIC_CONFIG_PATH", c.DynamicConfigPath)
+		p.Positive("DYNAMIC_CONFIG_POLL_INTERVAL", c.DynamicConfigPollInterval)
+	case DynamicSourceConsul:
+		p.Required("DYNAMIC_CONFIG_PREFIX", c.DynamicConfigPrefix)
+	}
+}
+
+// DynamicSource returns the source of the dynamic configuration of the named service, or nil without one.
+// The settings of a service are stored under its own key prefix in Consul, such as config/movie.
+func (c *Service) DynamicSource(service string) (dynconfig.Source, error) {
+	switch c.Dynamic.DynamicConfigSource {
+	case DynamicSourceFile:
+		return file.New(c.DynamicConfigPath, c.DynamicConfigPollInterval), nil
+	case DynamicSourceConsul:
+		source, err := consul.New(c.ConsulURL, fmt.Sprintf("%s/%s", c.DynamicConfigPrefix, service))
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Auth holds the credentials accepted by the gRPC server. Requests are not authenticated unless AUTH_ENABLED is set.
+type Auth struct {
+	AuthEnabled     bool   `env:"AUTH_ENABLED" env-default:"false"`
+	AuthJWTSecret   string `env:"AUTH_JWT_SECRET" secret:"true"`
+	AuthJWKSPath    string `env:"AUTH_JWKS_PATH"`
+	AuthJWTIssuer   string `env:"AUTH_JWT_ISSUER"`
+	AuthJWTAudience string `env:"AUTH_JWT_AUDIENCE"`
+	AuthAPIKeysPath string `env:"AUTH_API_KEYS_PATH"`
+}
+
+// Validate implements Validator.
+func (c *Auth) Validate(p *Problems) {
+	if c.AuthEnabled && c.AuthJWTSecret == "" && c.AuthJWKSPath == "" && c.AuthAPIKeysPath == "" {
+		p.Add("AUTH_ENABLED", "requires AUTH_JWT_SECRET, AUTH_JWKS_PATH or AUTH_API_KEYS_PATH")
+	}
+}
+
+// AuthConfig returns the credentials accepted by the gRPC server, or nil if requests are not authenticated.
+func (c *Auth) AuthConfig() *auth.Config {
+	if !c.AuthEnabled {
+		return nil
+	}
+	return &auth.Config{
+		JWTSecret:   c.AuthJWTSecret,
+		JWKSPath:    c.AuthJWKSPath,
+		Issuer:      c.AuthJWTIssuer,
+		Audience:    c.AuthJWTAudience,
+		APIKeysPath: c.AuthAPIKeysPath,
+	}
+}
+
+// TLS holds the certificates of the mutual TLS between the services. Connections are not encrypted unless
+// TLS_ENABLED is set.
+type TLS struct {
+	TLSEnabled        bool          `env:"TLS_ENABLED" env-default:"false"`
+	TLSCAPath         string        `env:"TLS_CA_PATH"`
+	TLSCertPath       string        `env:"TLS_CERT_PATH"`
+	TLSKeyPath        string        `env:"TLS_KEY_PATH"`
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" env-default:"1m"`
+	// TLSAllowedClients is the comma-separated names of the services allowed to call the service, overriding the
+	// default of the service; "*" allows every client with a certificate of a trusted authority.
+	TLSAllowedClients string `env:"TLS_ALLOWED_CLIENTS"`
+}
+
+// Validate implements Validator.
+func (c *TLS) Validate(p *Problems) {
+	if !c.TLSEnabled {
+		return
+	}
+	p.Required("TLS_CA_PATH", c.TLSCAPath)
+	p.Required("TLS_CERT_PATH", c.TLSCertPath)
+	p.Required("TLS_KEY_PATH", c.TLSKeyPath)
+	p.Positive("TLS_RELOAD_INTERVAL", c.TLSReloadInterval)
+}
+
+// TLSConfig returns the certificates of the mutual TLS between the services, or nil if connections are not
+// encrypted. The service accepts the calls of the given clients unless TLS_ALLOWED_CLIENTS is set, and of every
+// client with a certificate of a trusted authority without either.
+func (c *TLS) TLSConfig(allowedClients ...string) *mtls.Config {
+	if !c.TLSEnabled {
+		return nil
+	}
+	switch c.TLSAllowedClients {
+	case "":
+	case "*":
+		allowedClients = nil
+	default:
+		allowedClients = nil
+		for _, name := range strings.Split(c.TLSAllowedClients, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				allowedClients = append(allowedClients, name)
+			}
+		}
+	}
+	return &mtls.Config{
+		CAPath:         c.TLSCAPath,
+		CertPath:       c.TLSCertPath,
+		KeyPath:        c.TLSKeyPath,
+		AllowedClients: allowedClients,
+		ReloadInterval: c.TLSReloadInterval,
+	}
+}
+
+// Concurrency holds the settings of the adaptive concurrency limiters of the gRPC server and clients.
+type Concurrency struct {
+	ConcurrencyInitialLimit     int     `env:"CONCURRENCY_INITIAL_LIMIT" env-default:"20"`
+	ConcurrencyMinLimit         int     `env:"CONCURRENCY_MIN_LIMIT" env-default:"1"`
+	ConcurrencyMaxLimit         int     `env:"CONCURRENCY_MAX_LIMIT" env-default:"1000"`
+	ConcurrencyLatencyTolerance float64 `env:"CONCURRENCY_LATENCY_TOLERANCE" env-default:"2"`
+	ConcurrencyBackoff          float64 `env:"CONCURRENCY_BACKOFF" env-default:"0.9"`
+}
+
+// Validate implements Validator.
+func (c *Concurrency) Validate(p *Problems) {
+	p.Min("CONCURRENCY_MIN_LIMIT", c.ConcurrencyMinLimit, 1)
+	p.Min("CONCURRENCY_MAX_LIMIT", c.ConcurrencyMaxLimit, c.ConcurrencyMinLimit)
+	if c.ConcurrencyInitialLimit < c.ConcurrencyMinLimit || c.ConcurrencyInitialLimit > c.ConcurrencyMaxLimit {
+		p.Add("CONCURRENCY_INITIAL_LIMIT", "must be between CONCURRENCY_MIN_LIMIT (%d) and CONCURRENCY_MAX_LIMIT (%d), got %d",
+			c.ConcurrencyMinLimit, c.ConcurrencyMaxLimit, c.ConcurrencyInitialLimit)
+	}
+	if c.ConcurrencyLatencyTolerance <= 1 {
+		p.Add("CONCURRENCY_LATENCY_TOLERANCE", "must be greater than 1, got %v", c.ConcurrencyLatencyTolerance)
+	}
+	if c.ConcurrencyBackoff <= 0 || c.ConcurrencyBackoff >= 1 {
+		p.Add("CONCURRENCY_BACKOFF", "must be between 0 and 1 exclusive, got %v", c.ConcurrencyBackoff)
+	}
+}
+
+// ConcurrencyConfig returns the settings of an adaptive concurrency limiter.
+func (c *Concurrency) ConcurrencyConfig() loadshed.Config {
+	return loadshed.Config{
+		InitialLimit: c.ConcurrencyInitialLimit,
+		MinLimit:     c.ConcurrencyMinLimit,
+		MaxLimit:     c.ConcurrencyMaxLimit,
+		Tolerance:    c.ConcurrencyLatencyTolerance,
+		Backoff:      c.ConcurrencyBackoff,
+	}
+}
+
+// EventBus holds the connection settings of the Pulsar event bus.
+type EventBus struct {
+	Pulsar pulsar.Config
+}
+
+// Validate implements Validator.
+func (c *EventBus) Validate(p *Problems) {
+	p.URL("PULSAR_URL", c.Pulsar.URL, "pulsar", "pulsar+ssl")
+	p.Positive("CONNECTION_TIMEOUT", c.Pulsar.ConnectionTimeout)
+	p.Positive("OPERATION_TIMEOUT", c.Pulsar.OperationTimeout)
+	p.Min("MAX_PENDING_MESSAGES", c.Pulsar.MaxPendingMessages, 1)
+	p.Min("BATCHING_MAX_MESSAGES", int(c.Pulsar.BatchingMaxMessages), 1)
+}
+
+// Outbox holds the settings of the outbox relay.
+type Outbox struct {
+	OutboxBatchSize    int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" env-default:"1s"`
+	OutboxRetryMin     time.Duration `env:"OUTBOX_RETRY_MIN" env-default:"1s"`
+	OutboxRetryMax     time.Duration `env:"OUTBOX_RETRY_MAX" env-default:"5m"`
+}
+
+// Validate implements Validator.
+func (c *Outbox) Validate(p *Problems) {
+	p.Min("OUTBOX_BATCH_SIZE", c.OutboxBatchSize, 1)
+	p.Positive("OUTBOX_POLL_INTERVAL", c.OutboxPollInterval)
+	p.Positive("OUTBOX_RETRY_MIN", c.OutboxRetryMin)
+	if c.OutboxRetryMax < c.OutboxRetryMin {
+		p.Add("OUTBOX_RETRY_MAX", "must not be shorter than OUTBOX_RETRY_MIN (%s), got %s", c.OutboxRetryMin, c.OutboxRetryMax)
+	}
+}
+
+// OutboxRelayConfig returns the settings of the outbox relay publishing the events of the given source to the given
+// topic.
+func (c *Outbox) OutboxRelayConfig(source, topic string) outbox.RelayConfig {
+	return outbox.RelayConfig{
+		Source:       source,
+		Topic:        topic,
+		BatchSize:    c.OutboxBatchSize,
+		PollInterval: c.OutboxPollInterval,
+		Retry: &eventbus.ExponentialBackoff{
+			Min: c.OutboxRetryMin,
+			Max: c.OutboxRetryMax,
+		},
+	}
+}
+
+// Audit holds the settings of the audit log.
+type Audit struct {
+	// AuditPublishEvents also publishes the audit records to the events topic of the service, through the outbox.
+	AuditPublishEvents bool `env:"AUDIT_PUBLISH_EVENTS" env-default:"false"`
+}
+
+// AuditEventSource returns the event source of the audit events of a service with the given event source, or
+// an empty source if the audit records are not published.
+func (c *Audit) AuditEventSource(source string) string {
+	if !c.AuditPublishEvents {
+		return ""
+	}
+	return source
+}
+
+// Metadata is the configuration of the metadata service.
+type Metadata struct {
+	Service
+	EventBus
+	Outbox
+	Audit
+	MetadataPort        int    `env:"METADATA_PORT" env-default:"8081"`
+	MetadataMetricsPort int    `env:"METADATA_METRICS_PORT" env-default:"8091"`
+	DatabaseURL         string `env:"DATABASE_URL" secret:"true"`
+	MetadataEventsTopic string `env:"METADATA_EVENTS_TOPIC" env-default:"metadata-events"`
+}
+
+// Validate implements Validator.
+func (c *Metadata) Validate(p *Problems) {
+	c.Service.Validate(p)
+	c.EventBus.Validate(p)
+	c.Outbox.Validate(p)
+	p.Port("METADATA_PORT", c.MetadataPort)
+	p.Port("METADATA_METRICS_PORT", c.MetadataMetricsPort)
+	p.URL("DATABASE_URL", c.DatabaseURL, "postgres", "postgresql")
+	p.Required("METADATA_EVENTS_TOPIC", c.MetadataEventsTopic)
+}
+
+// RatingConsumer holds the settings of the rating event consumer.
+type RatingConsumer struct {
+	TopicName             string        `env:"TOPIC_NAME" env-default:"ratings"`
+	SubscriberName        string        `env:"SUBSCRIBER_NAME" env-default:"rating-subscriber"`
+	SubscriptionType      string        `env:"SUBSCRIPTION_TYPE" env-default:"key_shared"`
+	ConsumerCount         int           `env:"CONSUMER_COUNT" env-default:"1"`
+	ConsumerConcurrency   int           `env:"CONSUMER_CONCURRENCY" env-default:"4"`
+	ReceiverQueueSize     int           `env:"RECEIVER_QUEUE_SIZE" env-default:"1000"`
+	ConsumerBatchSize     int           `env:"CONSUMER_BATCH_SIZE" env-default:"100"`
+	ConsumerBatchMaxDelay time.Duration `env:"CONSUMER_BATCH_MAX_DELAY" env-default:"50ms"`
+	MaxDeliveries         uint32        `env:"MAX_DELIVERIES" env-default:"5"`
+	DeadLetterTopic       string        `env:"DEAD_LETTER_TOPIC"`
+	NackBackoffMin        time.Duration `env:"NACK_BACKOFF_MIN" env-default:"1s"`
+	NackBackoffMax        time.Duration `env:"NACK_BACKOFF_MAX" env-default:"1m"`
+	// ProcessedEventsRetention is how long the ids of the applied events are kept to skip their redeliveries and
+	// replays. It must be longer than the retention of the topic, which bounds how old a replayed event can be.
+	ProcessedEventsRetention     time.Duration `env:"PROCESSED_EVENTS_RETENTION" env-default:"720h"`
+	ProcessedEventsPruneInterval time.Duration `env:"PROCESSED_EVENTS_PRUNE_INTERVAL" env-default:"1h"`
+}
+
+// Validate implements Validator.
+func (c *RatingConsumer) Validate(p *Problems) {
+	p.Required("TOPIC_NAME", c.TopicName)
+	p.Required("SUBSCRIBER_NAME", c.SubscriberName)
+	p.OneOf("SUBSCRIPTION_TYPE", c.SubscriptionType, string(eventbus.Exclusive), string(eventbus.Shared), string(eventbus.KeyShared))
+	p.Min("CONSUMER_COUNT", c.ConsumerCount, 1)
+	p.Min("CONSUMER_CONCURRENCY", c.ConsumerConcurrency, 1)
+	p.Min("RECEIVER_QUEUE_SIZE", c.ReceiverQueueSize, 1)
+	p.Min("CONSUMER_BATCH_SIZE", c.ConsumerBatchSize, 1)
+	p.Positive("CONSUMER_BATCH_MAX_DELAY", c.ConsumerBatchMaxDelay)
+	if c.MaxDeliveries > 0 && c.SubscriptionType == string(eventbus.Exclusive) {
+		p.Add("SUBSCRIPTION_TYPE", "must be %s or %s to dead letter messages after MAX_DELIVERIES (%d), or set MAX_DELIVERIES=0", eventbus.Shared, eventbus.KeyShared, c.MaxDeliveries)
+	}
+	p.Positive("NACK_BACKOFF_MIN", c.NackBackoffMin)
+	if c.NackBackoffMax < c.NackBackoffMin {
+		p.Add("NACK_BACKOFF_MAX", "must not be shorter than NACK_BACKOFF_MIN (%s), got %s", c.NackBackoffMin, c.NackBackoffMax)
+	}
+	p.Positive("PROCESSED_EVENTS_PRUNE_INTERVAL", c.ProcessedEventsPruneInterval)
+	if horizon := c.redeliveryHorizon(); c.ProcessedEventsRetention <= horizon {
+		p.Add("PROCESSED_EVENTS_RETENTION", "must be longer than the redelivery horizon of MAX_DELIVERIES and NACK_BACKOFF_MAX (%s), got %s", horizon, c.ProcessedEventsRetention)
+	}
+}
+
+// redeliveryHorizon returns the longest time a message can wait for its redeliveries with the maximum backoff.
+// Without MAX_DELIVERIES, messages are redelivered until applied and the horizon is the time of a single backoff.
+func (c *RatingConsumer) redeliveryHorizon() time.Duration {
+	return time.Duration(max(c.MaxDeliveries, 1)) * c.NackBackoffMax
+}
+
+// Rating is the configuration of the rating service.
+type Rating struct {
+	Service
+	EventBus
+	Outbox
+	Audit
+	RatingConsumer
+	RatingPort                 int           `env:"RATING_PORT" env-default:"8082"`
+	RatingMetricsPort          int           `env:"RATING_METRICS_PORT" env-default:"8092"`
+	DatabaseURL                string        `env:"DATABASE_URL" secret:"true"`
+	RatingEventsTopic          string        `env:"RATING_EVENTS_TOPIC" env-default:"rating-events"`
+	LeaderboardRefreshInterval time.Duration `env:"LEADERBOARD_REFRESH_INTERVAL" env-default:"1m"`
+	TrendingHalfLife           time.Duration `env:"TRENDING_HALF_LIFE" env-default:"72h"`
+}
+
+// Validate implements Validator.
+func (c *Rating) Validate(p *Problems) {
+	c.Service.Validate(p)
+	c.EventBus.Validate(p)
+	c.Outbox.Validate(p)
+	c.RatingConsumer.Validate(p)
+	p.Port("RATING_PORT", c.RatingPort)
+	p.Port("RATING_METRICS_PORT", c.RatingMetricsPort)
+	p.URL("DATABASE_URL", c.DatabaseURL, "postgres", "postgresql")
+	p.Required("RATING_EVENTS_TOPIC", c.RatingEventsTopic)
+	p.Positive("LEADERBOARD_REFRESH_INTERVAL", c.LeaderboardRefreshInterval)
+	p.Positive("TRENDING_HALF_LIFE", c.TrendingHalfLife)
+}
+
+// Movie is the configuration of the movie service.
+type Movie struct {
+	Service
+	MoviePort        int `env:"MOVIE_PORT" env-default:"8083"`
+	MovieMetricsPort int `env:"MOVIE_METRICS_PORT" env-default:"8093"`
+}
+
+// Validate implements Validator.
+func (c *Movie) Validate(p *Problems) {
+	c.Service.Validate(p)
+	p.Port("MOVIE_PORT", c.MoviePort)
+	p.Port("MOVIE_METRICS_PORT", c.MovieMetricsPort)
+}
+
+// RatingProducer is the configuration of the rating event producer.
+type RatingProducer struct {
+	EventBus
+	JaegerURL        string `env:"JAEGER_URL" env-default:"localhost:4317"`
+	TopicName        string `env:"TOPIC_NAME" env-default:"ratings"`
+	EventContentType string `env:"EVENT_CONTENT_TYPE" env-default:"application/cloudevents+protobuf"`
+}
+
+// Validate implements Validator.
+func (c *RatingProducer) Validate(p *Problems) {
+	c.EventBus.Validate(p)
+	p.HostPort("JAEGER_URL", c.JaegerURL)
+	p.Required("TOPIC_NAME", c.TopicName)
+	p.OneOf("EVENT_CONTENT_TYPE", c.EventContentType, eventcodec.ContentTypeProtobuf, eventcodec.ContentTypeJSON)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problems collects the problems of the invalid settings of a configuration, so they are reported all at once.
+// Only the first problem of a setting is kept.
+type Problems struct {
+	problems []Problem
+}
+
+// Problem describes an invalid setting.
+type Problem struct {
+	Env     string
+	Message string
+}
+
+// ProblemsError is the error of an invalid configuration, listing the problems of its settings.
+type ProblemsError struct {
+	Problems []Problem
+}
+
+func (e *ProblemsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration, %d problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Env, p.Message)
+	}
+	return b.String()
+}
+
+// Err returns a *ProblemsError listing the problems, or nil if there are none.
+func (p *Problems) Err() error {
+	if len(p.problems) == 0 {
+		return nil
+	}
+	return &ProblemsError{Problems: slices.Clone(p.problems)}
+}
+
+// Add adds a problem of the setting of the environment variable env.
+func (p *Problems) Add(env, format string, args ...any) {
+	for _, problem := range p.problems {
+		if problem.Env == env {
+			return
+		}
+	}
+	p.problems = append(p.problems, Problem{Env: env, Message: fmt.Sprintf(format, args...)})
+}
+
+// Required checks that a setting is set.
+func (p *Problems) Required(env, value string) {
+	if value == "" {
+		p.Add(env, "is required")
+	}
+}
+
+// Port checks that a setting is a TCP port.
+func (p *Problems) Port(env string, port int) {
+	if port < 1 || port > 65535 {
+		p.Add(env, "must be a port between 1 and 65535, got %d", port)
+	}
+}
+
+// HostPort checks that a setting is an address in the host:port form.
+func (p *Problems) HostPort(env, value string) {
+	if value == "" {
+		p.Add(env, "is required")
+		return
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		p.Add(env, "must be an address in the host:port form, got %q", value)
+		return
+	}
+	if n, err := strconv.Atoi(port); host == "" || err != nil || n < 1 || n > 65535 {
+		p.Add(env, "must be an address in the host:port form, got %q", value)
+	}
+}
+
+// URL checks that a setting is an absolute URL with one of the schemes.
+func (p *Problems) URL(env, value string, schemes ...string) {
+	if value == "" {
+		p.Add(env, "is required")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		p.Add(env, "must be an absolute URL, got %q", redact(value, true))
+		return
+	}
+	if !slices.Contains(schemes, u.Scheme) {
+		p.Add(env, "must be a URL with scheme %s, got %q", strings.Join(schemes, " or "), u.Scheme)
+	}
+}
+
+// Positive checks that a duration setting is greater than zero.
+func (p *Problems) Positive(env string, d time.Duration) {
+	if d <= 0 {
+		p.Add(env, "must be a positive duration, got %s", d)
+	}
+}
+
+// Min checks that a numeric setting is at least min.
+func (p *Problems) Min(env string, value, min int) {
+	if value < min {
+		p.Add(env, "must be at least %d, got %d", min, value)
+	}
+}
+
+// OneOf checks that a setting is one of the allowed values.
+func (p *Problems) OneOf(env, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		p.Add(env, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"main/util"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteProcessedEventsBefore(t *testing.T) {
+	eventID := util.RandomString(16)
+	inserted, err := testStore.CreateProcessedEvent(context.Background(), eventID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), inserted)
+
+	_, err = testStore.DeleteProcessedEventsBefore(context.Background(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = testStore.GetProcessedEvent(context.Background(), eventID)
+	require.NoError(t, err)
+
+	deleted, err := testStore.DeleteProcessedEventsBefore(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+	_, err = testStore.GetProcessedEvent(context.Background(), eventID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
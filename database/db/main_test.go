@@ -2,8 +2,9 @@ package db
 
 import (
 	"context"
+	"flag"
 	"log"
-	"main/util"
+	"main/config"
 	"os"
 	"testing"
 
@@ -13,7 +14,10 @@ import (
 var testStore Store
 
 func TestMain(m *testing.M) {
-	cfg := util.LoadConfig("../../.env")
+	var cfg config.Rating
+	if err := config.Load(flag.NewFlagSet("db", flag.ExitOnError), []string{"-config", "../../.env"}, &cfg); err != nil {
+		log.Fatal(err)
+	}
 	connPool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
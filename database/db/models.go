@@ -4,7 +4,24 @@
 
 package db
 
-import ()
+import (
+	"time"
+)
+
+type AuditLog struct {
+	ID            int64     `db:"id" json:"id"`
+	Service       string    `db:"service" json:"service"`
+	Principal     string    `db:"principal" json:"principal"`
+	PrincipalKind string    `db:"principal_kind" json:"principal_kind"`
+	Method        string    `db:"method" json:"method"`
+	ResourceType  string    `db:"resource_type" json:"resource_type"`
+	ResourceID    string    `db:"resource_id" json:"resource_id"`
+	Changes       []byte    `db:"changes" json:"changes"`
+	TraceID       string    `db:"trace_id" json:"trace_id"`
+	Outcome       string    `db:"outcome" json:"outcome"`
+	Error         string    `db:"error" json:"error"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
 
 type Movie struct {
 	ID          string `db:"id" json:"id"`
@@ -13,10 +30,32 @@ type Movie struct {
 	Director    string `db:"director" json:"director"`
 }
 
+type OutboxEvent struct {
+	ID            int64      `db:"id" json:"id"`
+	EventID       string     `db:"event_id" json:"event_id"`
+	EventType     string     `db:"event_type" json:"event_type"`
+	AggregateType string     `db:"aggregate_type" json:"aggregate_type"`
+	AggregateID   string     `db:"aggregate_id" json:"aggregate_id"`
+	Payload       []byte     `db:"payload" json:"payload"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	PublishedAt   *time.Time `db:"published_at" json:"published_at"`
+	Attempts      int32      `db:"attempts" json:"attempts"`
+	LastError     *string    `db:"last_error" json:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	Source        string     `db:"source" json:"source"`
+}
+
+type ProcessedEvent struct {
+	EventID     string    `db:"event_id" json:"event_id"`
+	ProcessedAt time.Time `db:"processed_at" json:"processed_at"`
+}
+
 type Rating struct {
-	ID         int64  `db:"id" json:"id"`
-	MovieID    string `db:"movie_id" json:"movie_id"`
-	RecordType string `db:"record_type" json:"record_type"`
-	UserID     string `db:"user_id" json:"user_id"`
-	Value      int32  `db:"value" json:"value"`
+	ID         int64     `db:"id" json:"id"`
+	MovieID    string    `db:"movie_id" json:"movie_id"`
+	RecordType string    `db:"record_type" json:"record_type"`
+	UserID     string    `db:"user_id" json:"user_id"`
+	Value      int32     `db:"value" json:"value"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
 }
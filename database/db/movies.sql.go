@@ -110,6 +110,37 @@ func (q *Queries) ListMovies(ctx context.Context, arg *ListMoviesParams) ([]*Mov
 	return items, nil
 }
 
+const listMoviesByIDs = `-- name: ListMoviesByIDs :many
+SELECT id, title, description, director FROM movies
+WHERE id = ANY($1::text[])
+ORDER BY id
+`
+
+func (q *Queries) ListMoviesByIDs(ctx context.Context, ids []string) ([]*Movie, error) {
+	rows, err := q.db.Query(ctx, listMoviesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Movie{}
+	for rows.Next() {
+		var i Movie
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Director,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateMovie = `-- name: UpdateMovie :one
 UPDATE movies
 SET
@@ -144,3 +175,44 @@ func (q *Queries) UpdateMovie(ctx context.Context, arg *UpdateMovieParams) (*Mov
 	)
 	return &i, err
 }
+
+const upsertMovie = `-- name: UpsertMovie :one
+INSERT INTO movies (
+  id,
+  title,
+  description,
+  director
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (id) DO UPDATE
+SET
+  title = EXCLUDED.title,
+  description = EXCLUDED.description,
+  director = EXCLUDED.director
+RETURNING id, title, description, director
+`
+
+type UpsertMovieParams struct {
+	ID          string `db:"id" json:"id"`
+	Title       string `db:"title" json:"title"`
+	Description string `db:"description" json:"description"`
+	Director    string `db:"director" json:"director"`
+}
+
+func (q *Queries) UpsertMovie(ctx context.Context, arg *UpsertMovieParams) (*Movie, error) {
+	row := q.db.QueryRow(ctx, upsertMovie,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.Director,
+	)
+	var i Movie
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Director,
+	)
+	return &i, err
+}
@@ -43,3 +43,23 @@ func TestGetMovie(t *testing.T) {
 	require.Equal(t, movie1.Description, movie2.Description)
 	require.Equal(t, movie1.Director, movie2.Director)
 }
+
+func TestPutMovieTx(t *testing.T) {
+	movie := createRandomMovie(t)
+
+	arg := PutMovieTxParams{Movie: UpsertMovieParams{
+		ID:          movie.ID,
+		Title:       util.RandomString(8),
+		Description: util.RandomString(16),
+		Director:    movie.Director,
+	}}
+	result, err := testStore.PutMovieTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Movie.Title, result.Movie.Title)
+
+	updated, err := testStore.GetMovie(context.Background(), movie.ID)
+	require.NoError(t, err)
+	require.Equal(t, arg.Movie.Title, updated.Title)
+	require.Equal(t, arg.Movie.Description, updated.Description)
+	require.Equal(t, movie.Director, updated.Director)
+}
@@ -6,19 +6,37 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
+	// The advisory lock of the aggregate keeps the relays of other transactions off its later events until this
+	// transaction ends, so the events of an aggregate are published in order.
+	ClaimPendingOutboxEvents(ctx context.Context, arg *ClaimPendingOutboxEventsParams) ([]*OutboxEvent, error)
+	CreateAuditRecord(ctx context.Context, arg *CreateAuditRecordParams) (*AuditLog, error)
 	CreateMovie(ctx context.Context, arg *CreateMovieParams) (*Movie, error)
+	CreateOutboxEvent(ctx context.Context, arg *CreateOutboxEventParams) (*OutboxEvent, error)
+	CreateProcessedEvent(ctx context.Context, eventID string) (int64, error)
 	CreateRating(ctx context.Context, arg *CreateRatingParams) (*Rating, error)
 	DeleteMovie(ctx context.Context, id string) error
+	DeleteProcessedEventsBefore(ctx context.Context, processedAt time.Time) (int64, error)
 	DeleteRating(ctx context.Context, id int64) error
 	GetMovie(ctx context.Context, id string) (*Movie, error)
+	GetOldestPendingOutboxEvent(ctx context.Context, source string) (time.Time, error)
+	GetProcessedEvent(ctx context.Context, eventID string) (*ProcessedEvent, error)
 	GetRating(ctx context.Context, id int64) (*Rating, error)
+	GetRatingAggregate(ctx context.Context, arg *GetRatingAggregateParams) (*GetRatingAggregateRow, error)
+	ListAuditRecords(ctx context.Context, arg *ListAuditRecordsParams) ([]*AuditLog, error)
 	ListMovies(ctx context.Context, arg *ListMoviesParams) ([]*Movie, error)
+	ListMoviesByIDs(ctx context.Context, ids []string) ([]*Movie, error)
+	ListRatingAggregates(ctx context.Context, arg *ListRatingAggregatesParams) ([]*ListRatingAggregatesRow, error)
 	ListRatings(ctx context.Context, arg *ListRatingsParams) ([]*Rating, error)
+	ListRatingsSince(ctx context.Context, arg *ListRatingsSinceParams) ([]*Rating, error)
+	MarkOutboxEventFailed(ctx context.Context, arg *MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, arg *MarkOutboxEventPublishedParams) error
 	UpdateMovie(ctx context.Context, arg *UpdateMovieParams) (*Movie, error)
 	UpdateRating(ctx context.Context, arg *UpdateRatingParams) (*Rating, error)
+	UpsertMovie(ctx context.Context, arg *UpsertMovieParams) (*Movie, error)
 }
 
 var _ Querier = (*Queries)(nil)
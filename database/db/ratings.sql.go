@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createRating = `-- name: CreateRating :one
@@ -14,17 +15,20 @@ INSERT INTO ratings (
   movie_id,
   record_type,
   user_id,
-  value
+  value,
+  created_at,
+  updated_at
 ) VALUES (
-  $1, $2, $3, $4
-) RETURNING id, movie_id, record_type, user_id, value
+  $1, $2, $3, $4, $5, $5
+) RETURNING id, movie_id, record_type, user_id, value, created_at, updated_at
 `
 
 type CreateRatingParams struct {
-	MovieID    string `db:"movie_id" json:"movie_id"`
-	RecordType string `db:"record_type" json:"record_type"`
-	UserID     string `db:"user_id" json:"user_id"`
-	Value      int32  `db:"value" json:"value"`
+	MovieID    string    `db:"movie_id" json:"movie_id"`
+	RecordType string    `db:"record_type" json:"record_type"`
+	UserID     string    `db:"user_id" json:"user_id"`
+	Value      int32     `db:"value" json:"value"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 }
 
 func (q *Queries) CreateRating(ctx context.Context, arg *CreateRatingParams) (*Rating, error) {
@@ -33,6 +37,7 @@ func (q *Queries) CreateRating(ctx context.Context, arg *CreateRatingParams) (*R
 		arg.RecordType,
 		arg.UserID,
 		arg.Value,
+		arg.CreatedAt,
 	)
 	var i Rating
 	err := row.Scan(
@@ -41,6 +46,8 @@ func (q *Queries) CreateRating(ctx context.Context, arg *CreateRatingParams) (*R
 		&i.RecordType,
 		&i.UserID,
 		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return &i, err
 }
@@ -56,7 +63,7 @@ func (q *Queries) DeleteRating(ctx context.Context, id int64) error {
 }
 
 const getRating = `-- name: GetRating :one
-SELECT id, movie_id, record_type, user_id, value FROM ratings
+SELECT id, movie_id, record_type, user_id, value, created_at, updated_at FROM ratings
 WHERE id = $1
 ORDER BY id
 LIMIT 1
@@ -71,12 +78,89 @@ func (q *Queries) GetRating(ctx context.Context, id int64) (*Rating, error) {
 		&i.RecordType,
 		&i.UserID,
 		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return &i, err
 }
 
+const getRatingAggregate = `-- name: GetRatingAggregate :one
+SELECT
+  COUNT(*)::bigint AS rating_count,
+  COALESCE(AVG(value), 0)::float8 AS average_value
+FROM ratings
+WHERE movie_id = $1 AND record_type = $2
+`
+
+type GetRatingAggregateParams struct {
+	MovieID    string `db:"movie_id" json:"movie_id"`
+	RecordType string `db:"record_type" json:"record_type"`
+}
+
+type GetRatingAggregateRow struct {
+	RatingCount  int64   `db:"rating_count" json:"rating_count"`
+	AverageValue float64 `db:"average_value" json:"average_value"`
+}
+
+func (q *Queries) GetRatingAggregate(ctx context.Context, arg *GetRatingAggregateParams) (*GetRatingAggregateRow, error) {
+	row := q.db.QueryRow(ctx, getRatingAggregate, arg.MovieID, arg.RecordType)
+	var i GetRatingAggregateRow
+	err := row.Scan(&i.RatingCount, &i.AverageValue)
+	return &i, err
+}
+
+const listRatingAggregates = `-- name: ListRatingAggregates :many
+SELECT
+  movie_id,
+  record_type,
+  COUNT(*)::bigint AS rating_count,
+  AVG(value)::float8 AS average_value,
+  SUM(value * EXP(GREATEST(LN(0.5) * EXTRACT(EPOCH FROM ($1::timestamptz - created_at)) / $2::float8, -700)))::float8 AS trending_score
+FROM ratings
+GROUP BY movie_id, record_type
+`
+
+type ListRatingAggregatesParams struct {
+	Now             time.Time `db:"now" json:"now"`
+	HalfLifeSeconds float64   `db:"half_life_seconds" json:"half_life_seconds"`
+}
+
+type ListRatingAggregatesRow struct {
+	MovieID       string  `db:"movie_id" json:"movie_id"`
+	RecordType    string  `db:"record_type" json:"record_type"`
+	RatingCount   int64   `db:"rating_count" json:"rating_count"`
+	AverageValue  float64 `db:"average_value" json:"average_value"`
+	TrendingScore float64 `db:"trending_score" json:"trending_score"`
+}
+
+func (q *Queries) ListRatingAggregates(ctx context.Context, arg *ListRatingAggregatesParams) ([]*ListRatingAggregatesRow, error) {
+	rows, err := q.db.Query(ctx, listRatingAggregates, arg.Now, arg.HalfLifeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*ListRatingAggregatesRow{}
+	for rows.Next() {
+		var i ListRatingAggregatesRow
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.RecordType,
+			&i.RatingCount,
+			&i.AverageValue,
+			&i.TrendingScore,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRatings = `-- name: ListRatings :many
-SELECT id, movie_id, record_type, user_id, value FROM ratings
+SELECT id, movie_id, record_type, user_id, value, created_at, updated_at FROM ratings
 WHERE movie_id = $1 AND record_type = $2
 `
 
@@ -100,6 +184,47 @@ func (q *Queries) ListRatings(ctx context.Context, arg *ListRatingsParams) ([]*R
 			&i.RecordType,
 			&i.UserID,
 			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRatingsSince = `-- name: ListRatingsSince :many
+SELECT id, movie_id, record_type, user_id, value, created_at, updated_at FROM ratings
+WHERE movie_id = $1 AND record_type = $2 AND created_at >= $3
+`
+
+type ListRatingsSinceParams struct {
+	MovieID    string    `db:"movie_id" json:"movie_id"`
+	RecordType string    `db:"record_type" json:"record_type"`
+	Since      time.Time `db:"since" json:"since"`
+}
+
+func (q *Queries) ListRatingsSince(ctx context.Context, arg *ListRatingsSinceParams) ([]*Rating, error) {
+	rows, err := q.db.Query(ctx, listRatingsSince, arg.MovieID, arg.RecordType, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Rating{}
+	for rows.Next() {
+		var i Rating
+		if err := rows.Scan(
+			&i.ID,
+			&i.MovieID,
+			&i.RecordType,
+			&i.UserID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -117,10 +242,11 @@ SET
   movie_id = COALESCE($1, movie_id),
   record_type = COALESCE($2, record_type),
   user_id = COALESCE($3, user_id),
-  value = COALESCE($4, value)
+  value = COALESCE($4, value),
+  updated_at = now()
 WHERE
   id = $5
-RETURNING id, movie_id, record_type, user_id, value
+RETURNING id, movie_id, record_type, user_id, value, created_at, updated_at
 `
 
 type UpdateRatingParams struct {
@@ -146,6 +272,8 @@ func (q *Queries) UpdateRating(ctx context.Context, arg *UpdateRatingParams) (*R
 		&i.RecordType,
 		&i.UserID,
 		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return &i, err
 }
@@ -4,16 +4,22 @@ import (
 	"context"
 	"main/util"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 func createRandomRating(t *testing.T, movieId, recordType string) *Rating {
+	return createRandomRatingAt(t, movieId, recordType, time.Now())
+}
+
+func createRandomRatingAt(t *testing.T, movieId, recordType string, createdAt time.Time) *Rating {
 	arg := &CreateRatingParams{
 		MovieID:    movieId,
 		RecordType: recordType,
 		UserID:     util.RandomString(8),
 		Value:      int32(util.RandomInt(0, 10)),
+		CreatedAt:  createdAt,
 	}
 
 	rating, err := testStore.CreateRating(context.Background(), arg)
@@ -24,6 +30,8 @@ func createRandomRating(t *testing.T, movieId, recordType string) *Rating {
 	require.Equal(t, arg.RecordType, rating.RecordType)
 	require.Equal(t, arg.UserID, rating.UserID)
 	require.Equal(t, arg.Value, rating.Value)
+	require.WithinDuration(t, arg.CreatedAt, rating.CreatedAt, time.Second)
+	require.WithinDuration(t, arg.CreatedAt, rating.UpdatedAt, time.Second)
 
 	return rating
 }
@@ -58,3 +66,31 @@ func TestListRatings(t *testing.T) {
 		require.Equal(t, lastAccount.RecordType, rating.RecordType)
 	}
 }
+
+func TestListRatingsSince(t *testing.T) {
+	movieId := util.RandomString(8)
+	recordType := util.RandomString(8)
+
+	since := time.Now().Add(-24 * time.Hour)
+	for i := 0; i < 5; i++ {
+		createRandomRatingAt(t, movieId, recordType, since.Add(-time.Hour))
+	}
+	for i := 0; i < 5; i++ {
+		createRandomRatingAt(t, movieId, recordType, since.Add(time.Hour))
+	}
+
+	arg := &ListRatingsSinceParams{
+		MovieID:    movieId,
+		RecordType: recordType,
+		Since:      since,
+	}
+
+	ratings, err := testStore.ListRatingsSince(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, ratings, 5)
+
+	for _, rating := range ratings {
+		require.NotEmpty(t, rating)
+		require.False(t, rating.CreatedAt.Before(since))
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// ReplaySchema is the schema a replay rebuilds the rating tables in, before they are compared and swapped
+	// with the live ones. Connections writing the replay use it as their search path.
+	ReplaySchema = "rating_replay"
+	// ReplayBackupSchema keeps the live rating tables replaced by the last swap.
+	ReplayBackupSchema = "rating_replay_backup"
+)
+
+// replayTables are the tables rebuilt by a replay and swapped with the live ones.
+var replayTables = []string{"ratings", "processed_events"}
+
+// PrepareReplaySchema recreates the replay schema with empty copies of the rating tables.
+// The outbox table is copied too, so that replayed writes succeed, but it is never relayed.
+func (store *SqlStore) PrepareReplaySchema(ctx context.Context) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		stmts := []string{
+			fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", ReplaySchema),
+			fmt.Sprintf("CREATE SCHEMA %s", ReplaySchema),
+		}
+		for _, table := range append(replayTables, "outbox_events") {
+			stmts = append(stmts, fmt.Sprintf("CREATE TABLE %s.%s (LIKE public.%s INCLUDING ALL)", ReplaySchema, table, table))
+		}
+		// Copied serial columns still use the sequences of the live tables, give them their own.
+		for _, table := range []string{"ratings", "outbox_events"} {
+			stmts = append(stmts,
+				fmt.Sprintf("CREATE SEQUENCE %s.%s_id_seq OWNED BY %s.%s.id", ReplaySchema, table, ReplaySchema, table),
+				fmt.Sprintf("ALTER TABLE %s.%s ALTER COLUMN id SET DEFAULT nextval('%s.%s_id_seq')", ReplaySchema, table, ReplaySchema, table),
+			)
+		}
+		return execAll(ctx, q, stmts)
+	})
+}
+
+// ReplayDiff defines a record whose ratings differ between the live and the replay tables.
+type ReplayDiff struct {
+	MovieID       string  `db:"movie_id" json:"movie_id"`
+	RecordType    string  `db:"record_type" json:"record_type"`
+	LiveCount     int64   `db:"live_count" json:"live_count"`
+	LiveAverage   float64 `db:"live_average" json:"live_average"`
+	ReplayCount   int64   `db:"replay_count" json:"replay_count"`
+	ReplayAverage float64 `db:"replay_average" json:"replay_average"`
+}
+
+var compareReplay = fmt.Sprintf(`
+WITH live AS (
+  SELECT movie_id, record_type, COUNT(*)::bigint AS rating_count, AVG(value)::float8 AS average_value
+  FROM public.ratings
+  GROUP BY movie_id, record_type
+), replay AS (
+  SELECT movie_id, record_type, COUNT(*)::bigint AS rating_count, AVG(value)::float8 AS average_value
+  FROM %s.ratings
+  GROUP BY movie_id, record_type
+)
+SELECT
+  COALESCE(live.movie_id, replay.movie_id) AS movie_id,
+  COALESCE(live.record_type, replay.record_type) AS record_type,
+  COALESCE(live.rating_count, 0) AS live_count,
+  COALESCE(live.average_value, 0) AS live_average,
+  COALESCE(replay.rating_count, 0) AS replay_count,
+  COALESCE(replay.average_value, 0) AS replay_average
+FROM live
+FULL OUTER JOIN replay ON live.movie_id = replay.movie_id AND live.record_type = replay.record_type
+WHERE live.rating_count IS DISTINCT FROM replay.rating_count
+  OR ABS(live.average_value - replay.average_value) > 1e-9
+ORDER BY 1, 2
+`, ReplaySchema)
+
+// CompareReplay returns the records whose rating count or average differs between the live and the replay tables.
+func (store *SqlStore) CompareReplay(ctx context.Context) ([]*ReplayDiff, error) {
+	rows, err := store.conn.Query(ctx, compareReplay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*ReplayDiff{}
+	for rows.Next() {
+		var i ReplayDiff
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.RecordType,
+			&i.LiveCount,
+			&i.LiveAverage,
+			&i.ReplayCount,
+			&i.ReplayAverage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SwapReplaySchema replaces the live rating tables with the replay ones in a single transaction.
+// The replaced tables are kept in the backup schema until the next swap.
+func (store *SqlStore) SwapReplaySchema(ctx context.Context) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		stmts := []string{
+			fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", ReplayBackupSchema),
+			fmt.Sprintf("CREATE SCHEMA %s", ReplayBackupSchema),
+		}
+		for _, table := range replayTables {
+			stmts = append(stmts,
+				fmt.Sprintf("LOCK TABLE public.%s IN ACCESS EXCLUSIVE MODE", table),
+				fmt.Sprintf("ALTER TABLE public.%s SET SCHEMA %s", table, ReplayBackupSchema),
+				fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA public", ReplaySchema, table),
+			)
+		}
+		stmts = append(stmts, fmt.Sprintf("DROP SCHEMA %s CASCADE", ReplaySchema))
+		return execAll(ctx, q, stmts)
+	})
+}
+
+// execAll executes the given statements in order.
+func execAll(ctx context.Context, q *Queries, stmts []string) error {
+	for _, stmt := range stmts {
+		if _, err := q.db.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,23 @@
 package db
 
-import "github.com/jackc/pgx/v5/pgxpool"
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
 
 type Store interface {
 	Querier
+	ClaimOutboxEventsTx(ctx context.Context, arg ClaimOutboxEventsTxParams) error
+	CreateAuditRecordTx(ctx context.Context, arg CreateAuditRecordTxParams) (CreateAuditRecordTxResult, error)
+	CreateRatingTx(ctx context.Context, arg CreateRatingTxParams) (CreateRatingTxResult, error)
+	CreateRatingOnceTx(ctx context.Context, arg CreateRatingOnceTxParams) (CreateRatingOnceTxResult, error)
+	CreateRatingsOnceTx(ctx context.Context, arg CreateRatingsOnceTxParams) ([]CreateRatingOnceTxResult, error)
+	PrepareReplaySchema(ctx context.Context) error
+	PutMovieTx(ctx context.Context, arg PutMovieTxParams) (PutMovieTxResult, error)
+	CompareReplay(ctx context.Context) ([]*ReplayDiff, error)
+	SwapReplaySchema(ctx context.Context) error
 }
 
 // SqlStore provides all functions to execute db queries and transactions
@@ -19,3 +33,22 @@ func NewStore(conn *pgxpool.Pool) Store {
 		Queries: New(conn),
 	}
 }
+
+// execTx executes a function within a database transaction
+func (store *SqlStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := New(tx)
+	err = fn(q)
+	if err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,63 @@
+package db
+
+import "context"
+
+// CreateAuditRecordTxParams contains the input parameters of the create audit record transaction
+type CreateAuditRecordTxParams struct {
+	Record      CreateAuditRecordParams
+	AfterCreate func(record *AuditLog) ([]*CreateOutboxEventParams, error)
+}
+
+// CreateAuditRecordTxResult is the result of the create audit record transaction
+type CreateAuditRecordTxResult struct {
+	Record *AuditLog
+	Events []*OutboxEvent
+}
+
+// CreateAuditRecordTx appends an audit record and records its outbox events in a single transaction.
+func (store *SqlStore) CreateAuditRecordTx(ctx context.Context, arg CreateAuditRecordTxParams) (CreateAuditRecordTxResult, error) {
+	var result CreateAuditRecordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Record, result.Events, err = createAuditRecordWithOutbox(ctx, q, &arg)
+		return err
+	})
+
+	return result, err
+}
+
+// createAuditRecordWithOutbox appends an audit record and, if AfterCreate is set, the outbox events it builds.
+func createAuditRecordWithOutbox(ctx context.Context, q *Queries, arg *CreateAuditRecordTxParams) (*AuditLog, []*OutboxEvent, error) {
+	record, err := q.CreateAuditRecord(ctx, &arg.Record)
+	if err != nil {
+		return nil, nil, err
+	}
+	if arg.AfterCreate == nil {
+		return record, nil, nil
+	}
+
+	params, err := arg.AfterCreate(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := createOutboxEvents(ctx, q, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, events, nil
+}
+
+// createMutationAuditRecord appends the audit record of a mutation if arg is set, adding its outbox events to the
+// events of the mutation.
+func createMutationAuditRecord(ctx context.Context, q *Queries, arg *CreateAuditRecordTxParams, events *[]*OutboxEvent) (*AuditLog, error) {
+	if arg == nil {
+		return nil, nil
+	}
+	record, auditEvents, err := createAuditRecordWithOutbox(ctx, q, arg)
+	if err != nil {
+		return nil, err
+	}
+	*events = append(*events, auditEvents...)
+	return record, nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import "context"
+
+// PutMovieTxParams contains the input parameters of the put movie transaction
+type PutMovieTxParams struct {
+	Movie    UpsertMovieParams
+	AfterPut func(movie *Movie) ([]*CreateOutboxEventParams, error)
+	// Audit is the audit record of the write, appended in the same transaction if set.
+	Audit *CreateAuditRecordTxParams
+}
+
+// PutMovieTxResult is the result of the put movie transaction
+type PutMovieTxResult struct {
+	Movie       *Movie
+	Events      []*OutboxEvent
+	AuditRecord *AuditLog
+}
+
+// PutMovieTx creates or replaces a movie and records its outbox events and audit record in a single transaction.
+func (store *SqlStore) PutMovieTx(ctx context.Context, arg PutMovieTxParams) (PutMovieTxResult, error) {
+	var result PutMovieTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Movie, err = q.UpsertMovie(ctx, &arg.Movie)
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterPut != nil {
+			params, err := arg.AfterPut(result.Movie)
+			if err != nil {
+				return err
+			}
+			result.Events, err = createOutboxEvents(ctx, q, params)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.AuditRecord, err = createMutationAuditRecord(ctx, q, arg.Audit, &result.Events)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,22 @@
+package db
+
+import "context"
+
+// ClaimOutboxEventsTxParams contains the input parameters of the claim outbox events transaction
+type ClaimOutboxEventsTxParams struct {
+	Pending ClaimPendingOutboxEventsParams
+	Publish func(events []*OutboxEvent, q Querier) error
+}
+
+// ClaimOutboxEventsTx claims the pending outbox events of a source and publishes them in a single transaction.
+// Concurrent transactions skip the claimed events and the later events of their aggregates until it ends.
+// The publishing outcomes recorded with the queries passed to Publish are committed if it returns nil.
+func (store *SqlStore) ClaimOutboxEventsTx(ctx context.Context, arg ClaimOutboxEventsTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		events, err := q.ClaimPendingOutboxEvents(ctx, &arg.Pending)
+		if err != nil {
+			return err
+		}
+		return arg.Publish(events, q)
+	})
+}
@@ -0,0 +1,162 @@
+package db
+
+import "context"
+
+// RatingOutboxFunc builds the outbox events for a created rating given the aggregate of its record after the write
+type RatingOutboxFunc func(rating *Rating, aggregate *GetRatingAggregateRow) ([]*CreateOutboxEventParams, error)
+
+// CreateRatingTxParams contains the input parameters of the create rating transaction
+type CreateRatingTxParams struct {
+	Rating      CreateRatingParams
+	AfterCreate RatingOutboxFunc
+	// Audit is the audit record of the write, appended in the same transaction if set.
+	Audit *CreateAuditRecordTxParams
+}
+
+// CreateRatingTxResult is the result of the create rating transaction
+type CreateRatingTxResult struct {
+	Rating      *Rating
+	Events      []*OutboxEvent
+	AuditRecord *AuditLog
+}
+
+// CreateRatingTx creates a rating and records its outbox events and audit record in a single transaction.
+func (store *SqlStore) CreateRatingTx(ctx context.Context, arg CreateRatingTxParams) (CreateRatingTxResult, error) {
+	var result CreateRatingTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Rating, result.Events, err = createRatingWithOutbox(ctx, q, &arg.Rating, arg.AfterCreate)
+		if err != nil {
+			return err
+		}
+		result.AuditRecord, err = createMutationAuditRecord(ctx, q, arg.Audit, &result.Events)
+		return err
+	})
+
+	return result, err
+}
+
+// CreateRatingOnceTxParams contains the input parameters of the create rating once transaction
+type CreateRatingOnceTxParams struct {
+	EventID     string
+	Rating      CreateRatingParams
+	AfterCreate RatingOutboxFunc
+	// Audit is the audit record of the write, appended in the same transaction if set and the event is not a duplicate.
+	Audit *CreateAuditRecordTxParams
+}
+
+// CreateRatingOnceTxResult is the result of the create rating once transaction
+type CreateRatingOnceTxResult struct {
+	Rating      *Rating
+	Events      []*OutboxEvent
+	AuditRecord *AuditLog
+	Duplicate   bool
+}
+
+// CreateRatingOnceTx records the event as processed and creates its rating and audit record in a single transaction.
+// If the event was already processed, no rating is created and the result is marked as duplicate.
+func (store *SqlStore) CreateRatingOnceTx(ctx context.Context, arg CreateRatingOnceTxParams) (CreateRatingOnceTxResult, error) {
+	var result CreateRatingOnceTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		inserted, err := q.CreateProcessedEvent(ctx, arg.EventID)
+		if err != nil {
+			return err
+		}
+		if inserted == 0 {
+			result.Duplicate = true
+			return nil
+		}
+
+		result.Rating, result.Events, err = createRatingWithOutbox(ctx, q, &arg.Rating, arg.AfterCreate)
+		if err != nil {
+			return err
+		}
+		result.AuditRecord, err = createMutationAuditRecord(ctx, q, arg.Audit, &result.Events)
+		return err
+	})
+
+	return result, err
+}
+
+// createRatingWithOutbox creates a rating and, if afterCreate is set, the outbox events it builds.
+func createRatingWithOutbox(ctx context.Context, q *Queries, arg *CreateRatingParams, afterCreate RatingOutboxFunc) (*Rating, []*OutboxEvent, error) {
+	rating, err := q.CreateRating(ctx, arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if afterCreate == nil {
+		return rating, nil, nil
+	}
+
+	aggregate, err := q.GetRatingAggregate(ctx, &GetRatingAggregateParams{
+		MovieID:    rating.MovieID,
+		RecordType: rating.RecordType,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params, err := afterCreate(rating, aggregate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, err := createOutboxEvents(ctx, q, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rating, events, nil
+}
+
+// createOutboxEvents records the given outbox events.
+func createOutboxEvents(ctx context.Context, q *Queries, params []*CreateOutboxEventParams) ([]*OutboxEvent, error) {
+	events := make([]*OutboxEvent, 0, len(params))
+	for _, p := range params {
+		event, err := q.CreateOutboxEvent(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CreateRatingsOnceTxParams contains the input parameters of the create ratings once transaction
+type CreateRatingsOnceTxParams struct {
+	Ratings []CreateRatingOnceTxParams
+}
+
+// CreateRatingsOnceTx applies a batch of rating events in a single transaction, skipping the events already processed.
+// The results are in the order of the input ratings. If any write fails, the whole batch is rolled back.
+func (store *SqlStore) CreateRatingsOnceTx(ctx context.Context, arg CreateRatingsOnceTxParams) ([]CreateRatingOnceTxResult, error) {
+	var results []CreateRatingOnceTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		results = make([]CreateRatingOnceTxResult, len(arg.Ratings))
+		for i := range arg.Ratings {
+			item := &arg.Ratings[i]
+			inserted, err := q.CreateProcessedEvent(ctx, item.EventID)
+			if err != nil {
+				return err
+			}
+			if inserted == 0 {
+				results[i].Duplicate = true
+				continue
+			}
+
+			results[i].Rating, results[i].Events, err = createRatingWithOutbox(ctx, q, &item.Rating, item.AfterCreate)
+			if err != nil {
+				return err
+			}
+			results[i].AuditRecord, err = createMutationAuditRecord(ctx, q, item.Audit, &results[i].Events)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return results, err
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"main/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRatingOnceTx(t *testing.T) {
+	arg := CreateRatingOnceTxParams{
+		EventID: util.RandomString(16),
+		Rating: CreateRatingParams{
+			MovieID:    util.RandomString(8),
+			RecordType: util.RandomString(8),
+			UserID:     util.RandomString(8),
+			Value:      int32(util.RandomInt(0, 10)),
+			CreatedAt:  time.Now(),
+		},
+	}
+	arg.Audit = &CreateAuditRecordTxParams{
+		Record: CreateAuditRecordParams{
+			Service:      "rating",
+			Method:       "ConsumeRatingEvent",
+			ResourceType: "rating",
+			ResourceID:   arg.Rating.RecordType + "/" + arg.Rating.MovieID + "/" + arg.Rating.UserID,
+			Changes:      []byte(`[]`),
+			Outcome:      "success",
+			CreatedAt:    time.Now(),
+		},
+	}
+
+	result, err := testStore.CreateRatingOnceTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result.Duplicate)
+	require.NotEmpty(t, result.Rating)
+	require.Equal(t, arg.Rating.MovieID, result.Rating.MovieID)
+	require.Equal(t, arg.Rating.Value, result.Rating.Value)
+	require.NotNil(t, result.AuditRecord)
+	require.Equal(t, arg.Audit.Record.ResourceID, result.AuditRecord.ResourceID)
+
+	event, err := testStore.GetProcessedEvent(context.Background(), arg.EventID)
+	require.NoError(t, err)
+	require.Equal(t, arg.EventID, event.EventID)
+
+	result, err = testStore.CreateRatingOnceTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.Duplicate)
+	require.Nil(t, result.Rating)
+	require.Nil(t, result.AuditRecord)
+
+	ratings, err := testStore.ListRatings(context.Background(), &ListRatingsParams{
+		MovieID:    arg.Rating.MovieID,
+		RecordType: arg.Rating.RecordType,
+	})
+	require.NoError(t, err)
+	require.Len(t, ratings, 1)
+}
+
+func TestCreateRatingTx(t *testing.T) {
+	movieID := util.RandomString(8)
+	createRandomRating(t, movieID, "movie")
+
+	arg := CreateRatingTxParams{
+		Rating: CreateRatingParams{
+			MovieID:    movieID,
+			RecordType: "movie",
+			UserID:     util.RandomString(8),
+			Value:      int32(util.RandomInt(0, 10)),
+			CreatedAt:  time.Now(),
+		},
+		AfterCreate: func(rating *Rating, aggregate *GetRatingAggregateRow) ([]*CreateOutboxEventParams, error) {
+			require.Equal(t, int64(2), aggregate.RatingCount)
+			return []*CreateOutboxEventParams{{
+				EventID:       util.RandomString(16),
+				EventType:     "RatingChanged",
+				AggregateType: rating.RecordType,
+				AggregateID:   rating.MovieID,
+				Payload:       []byte(`{}`),
+				CreatedAt:     time.Now(),
+			}}, nil
+		},
+	}
+
+	result, err := testStore.CreateRatingTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Rating)
+	require.Len(t, result.Events, 1)
+	require.Equal(t, movieID, result.Events[0].AggregateID)
+	require.Nil(t, result.Events[0].PublishedAt)
+}
+
+func TestCreateRatingsOnceTx(t *testing.T) {
+	movieID := util.RandomString(8)
+	duplicateID := util.RandomString(16)
+
+	_, err := testStore.CreateProcessedEvent(context.Background(), duplicateID)
+	require.NoError(t, err)
+
+	arg := CreateRatingsOnceTxParams{}
+	for _, eventID := range []string{util.RandomString(16), duplicateID, util.RandomString(16)} {
+		arg.Ratings = append(arg.Ratings, CreateRatingOnceTxParams{
+			EventID: eventID,
+			Rating: CreateRatingParams{
+				MovieID:    movieID,
+				RecordType: "movie",
+				UserID:     util.RandomString(8),
+				Value:      int32(util.RandomInt(0, 10)),
+				CreatedAt:  time.Now(),
+			},
+		})
+	}
+
+	results, err := testStore.CreateRatingsOnceTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.False(t, results[0].Duplicate)
+	require.True(t, results[1].Duplicate)
+	require.False(t, results[2].Duplicate)
+
+	ratings, err := testStore.ListRatings(context.Background(), &ListRatingsParams{
+		MovieID:    movieID,
+		RecordType: "movie",
+	})
+	require.NoError(t, err)
+	require.Len(t, ratings, 2)
+}
@@ -0,0 +1,62 @@
+package dbtrace
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector is a Prometheus collector exposing the saturation of a connection pool: its connections by state
+// and the time spent waiting to acquire them.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquireCount      *prometheus.Desc
+	acquireSeconds    *prometheus.Desc
+	emptyAcquireCount *prometheus.Desc
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*PoolCollector)(nil)
+
+// NewPoolCollector creates a collector reading the statistics of the pool when scraped.
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("db", "pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:              pool,
+		acquireCount:      desc("acquires_total", "Number of connections acquired from the pool."),
+		acquireSeconds:    desc("acquire_wait_seconds_total", "Time spent waiting to acquire connections from the pool."),
+		emptyAcquireCount: desc("empty_acquires_total", "Number of acquires that waited for a connection because the pool was empty."),
+		acquiredConns:     desc("acquired_connections", "Number of connections currently in use."),
+		idleConns:         desc("idle_connections", "Number of idle connections in the pool."),
+		totalConns:        desc("total_connections", "Number of connections in the pool, in use, idle or being established."),
+		maxConns:          desc("max_connections", "Maximum number of connections of the pool."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquireCount
+	ch <- c.acquireSeconds
+	ch <- c.emptyAcquireCount
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+}
+
+// Collect implements prometheus.Collector.
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireSeconds, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+}
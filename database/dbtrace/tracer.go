@@ -0,0 +1,124 @@
+package dbtrace
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbRowsAffectedKey is the attribute key of the number of rows affected by a statement.
+const dbRowsAffectedKey = attribute.Key("db.rows_affected")
+
+// Outcomes of a query, as labelled in the query metrics.
+const (
+	outcomeOK    = "ok"
+	outcomeError = "error"
+)
+
+// Tracer is a pgx query tracer starting a client span for every statement executed on a connection
+// and recording the statement latency. Statements are named after their sqlc query name.
+type Tracer struct {
+	tracerName string
+	duration   *prometheus.HistogramVec
+}
+
+var _ pgx.QueryTracer = (*Tracer)(nil)
+
+// New creates a query tracer for the connections of the given service.
+func New(serviceName string) *Tracer {
+	return &Tracer{
+		tracerName: serviceName + "-pgx",
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "db",
+			Subsystem: "client",
+			Name:      "query_duration_seconds",
+			Help:      "Time taken to execute database statements, by statement and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"statement", "outcome"}),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the query metrics.
+func (t *Tracer) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{t.duration}
+}
+
+type queryKey struct{}
+
+// query is the state of a statement carried between the start and the end of its execution.
+type query struct {
+	statement string
+	start     time.Time
+}
+
+// TraceQueryStart starts the span of a statement as a child of the span in ctx.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	statement := StatementName(data.SQL)
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperation(statement),
+		semconv.DBStatement(data.SQL),
+	}
+	if conn != nil {
+		attrs = append(attrs, semconv.DBName(conn.Config().Database), semconv.DBUser(conn.Config().User))
+	}
+
+	ctx, _ = otel.Tracer(t.tracerName).Start(ctx, statement,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return context.WithValue(ctx, queryKey{}, query{statement: statement, start: time.Now()})
+}
+
+// TraceQueryEnd ends the span of a statement, recording its rows affected or error, and observes its latency.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryKey{}).(query)
+	if !ok {
+		return
+	}
+
+	span := trace.SpanFromContext(ctx)
+	outcome := outcomeOK
+	if data.Err != nil {
+		outcome = outcomeError
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(dbRowsAffectedKey.Int64(data.CommandTag.RowsAffected()))
+	}
+	span.End()
+
+	seconds := time.Since(q.start).Seconds()
+	observer := t.duration.WithLabelValues(q.statement, outcome)
+	spanCtx := span.SpanContext()
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && spanCtx.IsSampled() {
+		exemplarObserver.ObserveWithExemplar(seconds, prometheus.Labels{"trace_id": spanCtx.TraceID().String()})
+		return
+	}
+	observer.Observe(seconds)
+}
+
+// StatementName returns the name of a statement: the query name of the "-- name: Name :kind" comment that sqlc
+// generates, or else its leading SQL keyword, such as BEGIN or COMMIT for the statements of a transaction.
+func StatementName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name:"); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(strings.TrimSuffix(fields[0], ";"))
+}
@@ -0,0 +1,48 @@
+package dbtrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStatementName(t *testing.T) {
+	require.Equal(t, "GetMovie", StatementName("-- name: GetMovie :one\nSELECT id FROM movies WHERE id = $1"))
+	require.Equal(t, "BEGIN", StatementName("begin"))
+	require.Equal(t, "CREATE", StatementName("  create schema replay;"))
+	require.Equal(t, "UNKNOWN", StatementName(""))
+}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tracer := New("test")
+	parentCtx, parent := otel.Tracer("test").Start(context.Background(), "Repository/PUT")
+
+	ctx := tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{SQL: "-- name: CreateMovie :one\nINSERT INTO movies"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("INSERT 0 1")})
+	ctx = tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{SQL: "-- name: GetMovie :one\nSELECT"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	create, get := spans[0], spans[1]
+	require.Equal(t, "CreateMovie", create.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	require.Contains(t, create.Attributes(), dbRowsAffectedKey.Int64(1))
+	require.Equal(t, "GetMovie", get.Name())
+	require.Equal(t, codes.Error, get.Status().Code)
+
+	require.Equal(t, 2, testutil.CollectAndCount(tracer.duration))
+}
@@ -14,7 +14,60 @@ CREATE TABLE "ratings" (
   "movie_id" text NOT NULL,
   "record_type" text NOT NULL,
   "user_id" text NOT NULL,
-  "value" integer NOT NULL
+  "value" integer NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "processed_events" (
+  "event_id" text PRIMARY KEY,
+  "processed_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "event_id" text UNIQUE NOT NULL,
+  "event_type" text NOT NULL,
+  "source" text NOT NULL,
+  "aggregate_type" text NOT NULL,
+  "aggregate_id" text NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" text,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "service" text NOT NULL,
+  "principal" text NOT NULL,
+  "principal_kind" text NOT NULL,
+  "method" text NOT NULL,
+  "resource_type" text NOT NULL,
+  "resource_id" text NOT NULL,
+  "changes" jsonb NOT NULL,
+  "trace_id" text NOT NULL,
+  "outcome" text NOT NULL,
+  "error" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
 );
 
 CREATE INDEX ON "ratings" ("movie_id", "record_type");
+
+CREATE INDEX ON "ratings" ("movie_id", "record_type", "created_at");
+
+CREATE INDEX ON "processed_events" ("processed_at");
+
+CREATE INDEX ON "outbox_events" ("source", "aggregate_type", "aggregate_id", "id");
+
+CREATE INDEX ON "outbox_events" ("source", "id");
+
+CREATE INDEX ON "audit_log" ("resource_type", "resource_id", "id");
+
+CREATE INDEX ON "audit_log" ("principal", "id");
+
+CREATE INDEX ON "audit_log" ("service", "id");
+
+CREATE INDEX ON "audit_log" ("created_at");
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "ratings_movie_id_record_type_created_at_idx";
+
+ALTER TABLE "ratings" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "ratings" DROP COLUMN IF EXISTS "created_at";
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE "ratings" ADD COLUMN IF NOT EXISTS "created_at" timestamptz NOT NULL DEFAULT (now());
+ALTER TABLE "ratings" ADD COLUMN IF NOT EXISTS "updated_at" timestamptz NOT NULL DEFAULT (now());
+
+CREATE INDEX IF NOT EXISTS "ratings_movie_id_record_type_created_at_idx" ON "ratings" ("movie_id", "record_type", "created_at");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS processed_events;
\ No newline at end of file
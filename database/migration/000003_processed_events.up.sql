@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS "processed_events" (
+  "event_id" text PRIMARY KEY,
+  "processed_at" timestamptz NOT NULL DEFAULT (now())
+);
\ No newline at end of file
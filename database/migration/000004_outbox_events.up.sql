@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "event_id" text UNIQUE NOT NULL,
+  "event_type" text NOT NULL,
+  "aggregate_type" text NOT NULL,
+  "aggregate_id" text NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" text,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "outbox_events" ("aggregate_type", "aggregate_id", "id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("id") WHERE "published_at" IS NULL;
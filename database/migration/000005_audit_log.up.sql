@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "service" text NOT NULL,
+  "principal" text NOT NULL,
+  "principal_kind" text NOT NULL,
+  "method" text NOT NULL,
+  "resource_type" text NOT NULL,
+  "resource_id" text NOT NULL,
+  "changes" jsonb NOT NULL,
+  "trace_id" text NOT NULL,
+  "outcome" text NOT NULL,
+  "error" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_log" ("resource_type", "resource_id", "id");
+
+CREATE INDEX ON "audit_log" ("principal", "id");
+
+CREATE INDEX ON "audit_log" ("created_at");
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON "audit_log"
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
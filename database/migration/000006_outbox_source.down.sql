@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "outbox_events_source_aggregate_type_aggregate_id_id_idx";
+
+DROP INDEX IF EXISTS "outbox_events_source_id_idx";
+
+CREATE INDEX ON "outbox_events" ("aggregate_type", "aggregate_id", "id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("id") WHERE "published_at" IS NULL;
+
+ALTER TABLE "outbox_events" DROP COLUMN IF EXISTS "source";
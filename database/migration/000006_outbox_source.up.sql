@@ -0,0 +1,13 @@
+ALTER TABLE "outbox_events" ADD COLUMN "source" text NOT NULL DEFAULT '';
+
+UPDATE "outbox_events" SET "source" = "payload"->>'source';
+
+ALTER TABLE "outbox_events" ALTER COLUMN "source" DROP DEFAULT;
+
+DROP INDEX IF EXISTS "outbox_events_aggregate_type_aggregate_id_id_idx";
+
+DROP INDEX IF EXISTS "outbox_events_id_idx";
+
+CREATE INDEX ON "outbox_events" ("source", "aggregate_type", "aggregate_id", "id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("source", "id") WHERE "published_at" IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "audit_log_service_id_idx";
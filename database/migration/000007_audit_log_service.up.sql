@@ -0,0 +1 @@
+CREATE INDEX ON "audit_log" ("service", "id");
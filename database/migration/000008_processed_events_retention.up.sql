@@ -0,0 +1 @@
+CREATE INDEX ON "processed_events" ("processed_at");
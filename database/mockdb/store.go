@@ -12,6 +12,7 @@ import (
 	context "context"
 	db "main/database/db"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,80 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// ClaimOutboxEventsTx mocks base method.
+func (m *MockStore) ClaimOutboxEventsTx(arg0 context.Context, arg1 db.ClaimOutboxEventsTxParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxEventsTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimOutboxEventsTx indicates an expected call of ClaimOutboxEventsTx.
+func (mr *MockStoreMockRecorder) ClaimOutboxEventsTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxEventsTx", reflect.TypeOf((*MockStore)(nil).ClaimOutboxEventsTx), arg0, arg1)
+}
+
+// ClaimPendingOutboxEvents mocks base method.
+func (m *MockStore) ClaimPendingOutboxEvents(arg0 context.Context, arg1 *db.ClaimPendingOutboxEventsParams) ([]*db.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPendingOutboxEvents", arg0, arg1)
+	ret0, _ := ret[0].([]*db.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPendingOutboxEvents indicates an expected call of ClaimPendingOutboxEvents.
+func (mr *MockStoreMockRecorder) ClaimPendingOutboxEvents(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPendingOutboxEvents", reflect.TypeOf((*MockStore)(nil).ClaimPendingOutboxEvents), arg0, arg1)
+}
+
+// CompareReplay mocks base method.
+func (m *MockStore) CompareReplay(arg0 context.Context) ([]*db.ReplayDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareReplay", arg0)
+	ret0, _ := ret[0].([]*db.ReplayDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareReplay indicates an expected call of CompareReplay.
+func (mr *MockStoreMockRecorder) CompareReplay(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareReplay", reflect.TypeOf((*MockStore)(nil).CompareReplay), arg0)
+}
+
+// CreateAuditRecord mocks base method.
+func (m *MockStore) CreateAuditRecord(arg0 context.Context, arg1 *db.CreateAuditRecordParams) (*db.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditRecord", arg0, arg1)
+	ret0, _ := ret[0].(*db.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuditRecord indicates an expected call of CreateAuditRecord.
+func (mr *MockStoreMockRecorder) CreateAuditRecord(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditRecord", reflect.TypeOf((*MockStore)(nil).CreateAuditRecord), arg0, arg1)
+}
+
+// CreateAuditRecordTx mocks base method.
+func (m *MockStore) CreateAuditRecordTx(arg0 context.Context, arg1 db.CreateAuditRecordTxParams) (db.CreateAuditRecordTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditRecordTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateAuditRecordTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuditRecordTx indicates an expected call of CreateAuditRecordTx.
+func (mr *MockStoreMockRecorder) CreateAuditRecordTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditRecordTx", reflect.TypeOf((*MockStore)(nil).CreateAuditRecordTx), arg0, arg1)
+}
+
 // CreateMovie mocks base method.
 func (m *MockStore) CreateMovie(arg0 context.Context, arg1 *db.CreateMovieParams) (*db.Movie, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +129,36 @@ func (mr *MockStoreMockRecorder) CreateMovie(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMovie", reflect.TypeOf((*MockStore)(nil).CreateMovie), arg0, arg1)
 }
 
+// CreateOutboxEvent mocks base method.
+func (m *MockStore) CreateOutboxEvent(arg0 context.Context, arg1 *db.CreateOutboxEventParams) (*db.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOutboxEvent", arg0, arg1)
+	ret0, _ := ret[0].(*db.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOutboxEvent indicates an expected call of CreateOutboxEvent.
+func (mr *MockStoreMockRecorder) CreateOutboxEvent(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOutboxEvent", reflect.TypeOf((*MockStore)(nil).CreateOutboxEvent), arg0, arg1)
+}
+
+// CreateProcessedEvent mocks base method.
+func (m *MockStore) CreateProcessedEvent(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProcessedEvent", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProcessedEvent indicates an expected call of CreateProcessedEvent.
+func (mr *MockStoreMockRecorder) CreateProcessedEvent(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProcessedEvent", reflect.TypeOf((*MockStore)(nil).CreateProcessedEvent), arg0, arg1)
+}
+
 // CreateRating mocks base method.
 func (m *MockStore) CreateRating(arg0 context.Context, arg1 *db.CreateRatingParams) (*db.Rating, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +174,51 @@ func (mr *MockStoreMockRecorder) CreateRating(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRating", reflect.TypeOf((*MockStore)(nil).CreateRating), arg0, arg1)
 }
 
+// CreateRatingOnceTx mocks base method.
+func (m *MockStore) CreateRatingOnceTx(arg0 context.Context, arg1 db.CreateRatingOnceTxParams) (db.CreateRatingOnceTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRatingOnceTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateRatingOnceTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRatingOnceTx indicates an expected call of CreateRatingOnceTx.
+func (mr *MockStoreMockRecorder) CreateRatingOnceTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRatingOnceTx", reflect.TypeOf((*MockStore)(nil).CreateRatingOnceTx), arg0, arg1)
+}
+
+// CreateRatingTx mocks base method.
+func (m *MockStore) CreateRatingTx(arg0 context.Context, arg1 db.CreateRatingTxParams) (db.CreateRatingTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRatingTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateRatingTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRatingTx indicates an expected call of CreateRatingTx.
+func (mr *MockStoreMockRecorder) CreateRatingTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRatingTx", reflect.TypeOf((*MockStore)(nil).CreateRatingTx), arg0, arg1)
+}
+
+// CreateRatingsOnceTx mocks base method.
+func (m *MockStore) CreateRatingsOnceTx(arg0 context.Context, arg1 db.CreateRatingsOnceTxParams) ([]db.CreateRatingOnceTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRatingsOnceTx", arg0, arg1)
+	ret0, _ := ret[0].([]db.CreateRatingOnceTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRatingsOnceTx indicates an expected call of CreateRatingsOnceTx.
+func (mr *MockStoreMockRecorder) CreateRatingsOnceTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRatingsOnceTx", reflect.TypeOf((*MockStore)(nil).CreateRatingsOnceTx), arg0, arg1)
+}
+
 // DeleteMovie mocks base method.
 func (m *MockStore) DeleteMovie(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -83,6 +233,21 @@ func (mr *MockStoreMockRecorder) DeleteMovie(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMovie", reflect.TypeOf((*MockStore)(nil).DeleteMovie), arg0, arg1)
 }
 
+// DeleteProcessedEventsBefore mocks base method.
+func (m *MockStore) DeleteProcessedEventsBefore(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProcessedEventsBefore", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProcessedEventsBefore indicates an expected call of DeleteProcessedEventsBefore.
+func (mr *MockStoreMockRecorder) DeleteProcessedEventsBefore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProcessedEventsBefore", reflect.TypeOf((*MockStore)(nil).DeleteProcessedEventsBefore), arg0, arg1)
+}
+
 // DeleteRating mocks base method.
 func (m *MockStore) DeleteRating(arg0 context.Context, arg1 int64) error {
 	m.ctrl.T.Helper()
@@ -112,6 +277,36 @@ func (mr *MockStoreMockRecorder) GetMovie(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMovie", reflect.TypeOf((*MockStore)(nil).GetMovie), arg0, arg1)
 }
 
+// GetOldestPendingOutboxEvent mocks base method.
+func (m *MockStore) GetOldestPendingOutboxEvent(arg0 context.Context, arg1 string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOldestPendingOutboxEvent", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOldestPendingOutboxEvent indicates an expected call of GetOldestPendingOutboxEvent.
+func (mr *MockStoreMockRecorder) GetOldestPendingOutboxEvent(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOldestPendingOutboxEvent", reflect.TypeOf((*MockStore)(nil).GetOldestPendingOutboxEvent), arg0, arg1)
+}
+
+// GetProcessedEvent mocks base method.
+func (m *MockStore) GetProcessedEvent(arg0 context.Context, arg1 string) (*db.ProcessedEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProcessedEvent", arg0, arg1)
+	ret0, _ := ret[0].(*db.ProcessedEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProcessedEvent indicates an expected call of GetProcessedEvent.
+func (mr *MockStoreMockRecorder) GetProcessedEvent(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProcessedEvent", reflect.TypeOf((*MockStore)(nil).GetProcessedEvent), arg0, arg1)
+}
+
 // GetRating mocks base method.
 func (m *MockStore) GetRating(arg0 context.Context, arg1 int64) (*db.Rating, error) {
 	m.ctrl.T.Helper()
@@ -127,6 +322,36 @@ func (mr *MockStoreMockRecorder) GetRating(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRating", reflect.TypeOf((*MockStore)(nil).GetRating), arg0, arg1)
 }
 
+// GetRatingAggregate mocks base method.
+func (m *MockStore) GetRatingAggregate(arg0 context.Context, arg1 *db.GetRatingAggregateParams) (*db.GetRatingAggregateRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRatingAggregate", arg0, arg1)
+	ret0, _ := ret[0].(*db.GetRatingAggregateRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRatingAggregate indicates an expected call of GetRatingAggregate.
+func (mr *MockStoreMockRecorder) GetRatingAggregate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRatingAggregate", reflect.TypeOf((*MockStore)(nil).GetRatingAggregate), arg0, arg1)
+}
+
+// ListAuditRecords mocks base method.
+func (m *MockStore) ListAuditRecords(arg0 context.Context, arg1 *db.ListAuditRecordsParams) ([]*db.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditRecords", arg0, arg1)
+	ret0, _ := ret[0].([]*db.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditRecords indicates an expected call of ListAuditRecords.
+func (mr *MockStoreMockRecorder) ListAuditRecords(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditRecords", reflect.TypeOf((*MockStore)(nil).ListAuditRecords), arg0, arg1)
+}
+
 // ListMovies mocks base method.
 func (m *MockStore) ListMovies(arg0 context.Context, arg1 *db.ListMoviesParams) ([]*db.Movie, error) {
 	m.ctrl.T.Helper()
@@ -142,6 +367,36 @@ func (mr *MockStoreMockRecorder) ListMovies(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMovies", reflect.TypeOf((*MockStore)(nil).ListMovies), arg0, arg1)
 }
 
+// ListMoviesByIDs mocks base method.
+func (m *MockStore) ListMoviesByIDs(arg0 context.Context, arg1 []string) ([]*db.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMoviesByIDs", arg0, arg1)
+	ret0, _ := ret[0].([]*db.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMoviesByIDs indicates an expected call of ListMoviesByIDs.
+func (mr *MockStoreMockRecorder) ListMoviesByIDs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMoviesByIDs", reflect.TypeOf((*MockStore)(nil).ListMoviesByIDs), arg0, arg1)
+}
+
+// ListRatingAggregates mocks base method.
+func (m *MockStore) ListRatingAggregates(arg0 context.Context, arg1 *db.ListRatingAggregatesParams) ([]*db.ListRatingAggregatesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRatingAggregates", arg0, arg1)
+	ret0, _ := ret[0].([]*db.ListRatingAggregatesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRatingAggregates indicates an expected call of ListRatingAggregates.
+func (mr *MockStoreMockRecorder) ListRatingAggregates(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRatingAggregates", reflect.TypeOf((*MockStore)(nil).ListRatingAggregates), arg0, arg1)
+}
+
 // ListRatings mocks base method.
 func (m *MockStore) ListRatings(arg0 context.Context, arg1 *db.ListRatingsParams) ([]*db.Rating, error) {
 	m.ctrl.T.Helper()
@@ -157,6 +412,92 @@ func (mr *MockStoreMockRecorder) ListRatings(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRatings", reflect.TypeOf((*MockStore)(nil).ListRatings), arg0, arg1)
 }
 
+// ListRatingsSince mocks base method.
+func (m *MockStore) ListRatingsSince(arg0 context.Context, arg1 *db.ListRatingsSinceParams) ([]*db.Rating, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRatingsSince", arg0, arg1)
+	ret0, _ := ret[0].([]*db.Rating)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRatingsSince indicates an expected call of ListRatingsSince.
+func (mr *MockStoreMockRecorder) ListRatingsSince(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRatingsSince", reflect.TypeOf((*MockStore)(nil).ListRatingsSince), arg0, arg1)
+}
+
+// MarkOutboxEventFailed mocks base method.
+func (m *MockStore) MarkOutboxEventFailed(arg0 context.Context, arg1 *db.MarkOutboxEventFailedParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventFailed", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventFailed indicates an expected call of MarkOutboxEventFailed.
+func (mr *MockStoreMockRecorder) MarkOutboxEventFailed(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventFailed", reflect.TypeOf((*MockStore)(nil).MarkOutboxEventFailed), arg0, arg1)
+}
+
+// MarkOutboxEventPublished mocks base method.
+func (m *MockStore) MarkOutboxEventPublished(arg0 context.Context, arg1 *db.MarkOutboxEventPublishedParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventPublished", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventPublished indicates an expected call of MarkOutboxEventPublished.
+func (mr *MockStoreMockRecorder) MarkOutboxEventPublished(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventPublished", reflect.TypeOf((*MockStore)(nil).MarkOutboxEventPublished), arg0, arg1)
+}
+
+// PrepareReplaySchema mocks base method.
+func (m *MockStore) PrepareReplaySchema(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareReplaySchema", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PrepareReplaySchema indicates an expected call of PrepareReplaySchema.
+func (mr *MockStoreMockRecorder) PrepareReplaySchema(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareReplaySchema", reflect.TypeOf((*MockStore)(nil).PrepareReplaySchema), arg0)
+}
+
+// PutMovieTx mocks base method.
+func (m *MockStore) PutMovieTx(arg0 context.Context, arg1 db.PutMovieTxParams) (db.PutMovieTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutMovieTx", arg0, arg1)
+	ret0, _ := ret[0].(db.PutMovieTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutMovieTx indicates an expected call of PutMovieTx.
+func (mr *MockStoreMockRecorder) PutMovieTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutMovieTx", reflect.TypeOf((*MockStore)(nil).PutMovieTx), arg0, arg1)
+}
+
+// SwapReplaySchema mocks base method.
+func (m *MockStore) SwapReplaySchema(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SwapReplaySchema", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SwapReplaySchema indicates an expected call of SwapReplaySchema.
+func (mr *MockStoreMockRecorder) SwapReplaySchema(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SwapReplaySchema", reflect.TypeOf((*MockStore)(nil).SwapReplaySchema), arg0)
+}
+
 // UpdateMovie mocks base method.
 func (m *MockStore) UpdateMovie(arg0 context.Context, arg1 *db.UpdateMovieParams) (*db.Movie, error) {
 	m.ctrl.T.Helper()
@@ -186,3 +527,18 @@ func (mr *MockStoreMockRecorder) UpdateRating(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRating", reflect.TypeOf((*MockStore)(nil).UpdateRating), arg0, arg1)
 }
+
+// UpsertMovie mocks base method.
+func (m *MockStore) UpsertMovie(arg0 context.Context, arg1 *db.UpsertMovieParams) (*db.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertMovie", arg0, arg1)
+	ret0, _ := ret[0].(*db.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertMovie indicates an expected call of UpsertMovie.
+func (mr *MockStoreMockRecorder) UpsertMovie(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertMovie", reflect.TypeOf((*MockStore)(nil).UpsertMovie), arg0, arg1)
+}
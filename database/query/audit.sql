@@ -0,0 +1,30 @@
+-- name: CreateAuditRecord :one
+INSERT INTO audit_log (
+  service,
+  principal,
+  principal_kind,
+  method,
+  resource_type,
+  resource_id,
+  changes,
+  trace_id,
+  outcome,
+  error,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: ListAuditRecords :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(service)::text IS NULL OR service = sqlc.narg(service))
+  AND (sqlc.narg(principal)::text IS NULL OR principal = sqlc.narg(principal))
+  AND (sqlc.narg(method)::text IS NULL OR method = sqlc.narg(method))
+  AND (sqlc.narg(resource_type)::text IS NULL OR resource_type = sqlc.narg(resource_type))
+  AND (sqlc.narg(resource_id)::text IS NULL OR resource_id = sqlc.narg(resource_id))
+  AND (sqlc.narg(outcome)::text IS NULL OR outcome = sqlc.narg(outcome))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size);
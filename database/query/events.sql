@@ -0,0 +1,15 @@
+-- name: CreateProcessedEvent :execrows
+INSERT INTO processed_events (
+  event_id
+) VALUES (
+  $1
+) ON CONFLICT DO NOTHING;
+
+-- name: GetProcessedEvent :one
+SELECT * FROM processed_events
+WHERE event_id = $1
+LIMIT 1;
+
+-- name: DeleteProcessedEventsBefore :execrows
+DELETE FROM processed_events
+WHERE processed_at < $1;
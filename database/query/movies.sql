@@ -20,6 +20,11 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListMoviesByIDs :many
+SELECT * FROM movies
+WHERE id = ANY(sqlc.arg(ids)::text[])
+ORDER BY id;
+
 -- name: UpdateMovie :one
 UPDATE movies
 SET
@@ -32,4 +37,20 @@ RETURNING *;
 
 -- name: DeleteMovie :exec
 DELETE FROM movies
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: UpsertMovie :one
+INSERT INTO movies (
+  id,
+  title,
+  description,
+  director
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (id) DO UPDATE
+SET
+  title = EXCLUDED.title,
+  description = EXCLUDED.description,
+  director = EXCLUDED.director
+RETURNING *;
\ No newline at end of file
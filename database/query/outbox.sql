@@ -0,0 +1,53 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+  event_id,
+  event_type,
+  aggregate_type,
+  aggregate_id,
+  payload,
+  created_at,
+  source
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ClaimPendingOutboxEvents :many
+-- The advisory lock of the aggregate keeps the relays of other transactions off its later events until this
+-- transaction ends, so the events of an aggregate are published in order.
+SELECT * FROM outbox_events e
+WHERE e.source = sqlc.arg(source)
+  AND e.published_at IS NULL
+  AND e.next_attempt_at <= sqlc.arg(now)
+  AND NOT EXISTS (
+    SELECT 1 FROM outbox_events p
+    WHERE p.source = e.source
+      AND p.aggregate_type = e.aggregate_type
+      AND p.aggregate_id = e.aggregate_id
+      AND p.published_at IS NULL
+      AND p.id < e.id
+      AND p.next_attempt_at > sqlc.arg(now)
+  )
+  AND pg_try_advisory_xact_lock(hashtextextended(e.source || '/' || e.aggregate_type || '/' || e.aggregate_id, 0))
+ORDER BY e.id
+LIMIT sqlc.arg(batch_size)
+FOR UPDATE SKIP LOCKED;
+
+-- name: GetOldestPendingOutboxEvent :one
+SELECT created_at FROM outbox_events
+WHERE source = $1
+  AND published_at IS NULL
+ORDER BY id
+LIMIT 1;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = sqlc.arg(published_at)
+WHERE id = sqlc.arg(id);
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET
+  attempts = attempts + 1,
+  last_error = sqlc.arg(last_error),
+  next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
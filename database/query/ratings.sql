@@ -3,9 +3,11 @@ INSERT INTO ratings (
   movie_id,
   record_type,
   user_id,
-  value
+  value,
+  created_at,
+  updated_at
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5, $5
 ) RETURNING *;
 
 -- name: GetRating :one
@@ -18,13 +20,35 @@ LIMIT 1;
 SELECT * FROM ratings
 WHERE movie_id = $1 AND record_type = $2;
 
+-- name: ListRatingsSince :many
+SELECT * FROM ratings
+WHERE movie_id = $1 AND record_type = $2 AND created_at >= sqlc.arg(since);
+
+-- name: GetRatingAggregate :one
+SELECT
+  COUNT(*)::bigint AS rating_count,
+  COALESCE(AVG(value), 0)::float8 AS average_value
+FROM ratings
+WHERE movie_id = $1 AND record_type = $2;
+
+-- name: ListRatingAggregates :many
+SELECT
+  movie_id,
+  record_type,
+  COUNT(*)::bigint AS rating_count,
+  AVG(value)::float8 AS average_value,
+  SUM(value * EXP(GREATEST(LN(0.5) * EXTRACT(EPOCH FROM (sqlc.arg(now)::timestamptz - created_at)) / sqlc.arg(half_life_seconds)::float8, -700)))::float8 AS trending_score
+FROM ratings
+GROUP BY movie_id, record_type;
+
 -- name: UpdateRating :one
 UPDATE ratings
 SET
   movie_id = COALESCE(sqlc.narg(movie_id), movie_id),
   record_type = COALESCE(sqlc.narg(record_type), record_type),
   user_id = COALESCE(sqlc.narg(user_id), user_id),
-  value = COALESCE(sqlc.narg(value), value)
+  value = COALESCE(sqlc.narg(value), value),
+  updated_at = now()
 WHERE
   id = sqlc.arg(id)
 RETURNING *;
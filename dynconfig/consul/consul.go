@@ -0,0 +1,79 @@
+package consul
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+// Wait times of the watch: the longest a blocking query waits for a change, and the delay before retrying a failed one.
+const (
+	queryWaitTime = 5 * time.Minute
+	retryDelay    = 5 * time.Second
+)
+
+// Source defines a source of dynamic settings stored in the Consul KV store under a key prefix.
+// The key of a setting is its path relative to the prefix, such as LOG_LEVEL for config/movie/LOG_LEVEL.
+type Source struct {
+	kv     *consul.KV
+	prefix string
+}
+
+// New creates a Consul-based source of the dynamic settings under the key prefix.
+func New(addr string, prefix string) (*Source, error) {
+	config := consul.DefaultConfig()
+	config.Address = addr
+	client, err := consul.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		kv:     client.KV(),
+		prefix: strings.TrimSuffix(prefix, "/") + "/",
+	}, nil
+}
+
+// Watch calls update with the settings under the prefix once, and again every time they change, until ctx is
+// cancelled. Changes are watched with blocking queries; failed queries are retried.
+func (s *Source) Watch(ctx context.Context, update func(values map[string]string)) error {
+	var index uint64
+	for {
+		opts := (&consul.QueryOptions{WaitIndex: index, WaitTime: queryWaitTime}).WithContext(ctx)
+		pairs, meta, err := s.kv.List(s.prefix, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Error("failed to watch dynamic configuration:", slog.String("prefix", s.prefix), slog.String("error", err.Error()))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		// The index only grows between changes; a lower one means the store was reset.
+		if index != 0 && meta.LastIndex == index {
+			continue
+		}
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		values := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			key := strings.TrimPrefix(pair.Key, s.prefix)
+			if key == "" || strings.HasSuffix(key, "/") {
+				continue
+			}
+			values[key] = string(pair.Value)
+		}
+		update(values)
+	}
+}
@@ -0,0 +1,370 @@
+package dynconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source provides the dynamic settings of a service as key/value pairs and watches them for changes.
+type Source interface {
+	// Watch calls update with all the settings once, and again every time they change, until ctx is cancelled.
+	Watch(ctx context.Context, update func(values map[string]string)) error
+}
+
+// Results of a reload, as labelled in the reload metrics.
+const (
+	reloadApplied   = "applied"
+	reloadUnchanged = "unchanged"
+	reloadRejected  = "rejected"
+)
+
+// featurePrefix is the key prefix of feature flags.
+const featurePrefix = "FEATURE_"
+
+// setting is a dynamic setting registered on the store.
+type setting struct {
+	def   string
+	value string
+	// parse checks a value and returns the function applying it.
+	parse func(value string) (apply func(), err error)
+}
+
+// Store holds the dynamic settings of a service. Settings are registered with a default value, then every reload
+// of the source is checked as a whole: if any registered setting has an invalid value, the reload is rejected and
+// the settings keep their values; otherwise the changed settings are applied and the subscribers are notified.
+// Keys of the source without a registered setting are ignored.
+type Store struct {
+	mu          sync.Mutex
+	settings    map[string]*setting
+	features    map[string]*Bool
+	values      map[string]string
+	subscribers []func()
+	version     uint64
+	loaded      chan struct{}
+	loadOnce    sync.Once
+
+	versionGauge prometheus.Gauge
+	lastReload   prometheus.Gauge
+	reloads      *prometheus.CounterVec
+}
+
+// New creates a store of dynamic settings holding their default values.
+func New() *Store {
+	return &Store{
+		settings: map[string]*setting{},
+		features: map[string]*Bool{},
+		loaded:   make(chan struct{}),
+		versionGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dynconfig",
+			Name:      "version",
+			Help:      "Version of the dynamic configuration, incremented every time a change is applied.",
+		}),
+		lastReload: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dynconfig",
+			Name:      "last_reload_timestamp_seconds",
+			Help:      "Time of the last reload of the dynamic configuration that applied a change.",
+		}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dynconfig",
+			Name:      "reloads_total",
+			Help:      "Number of reloads of the dynamic configuration, by result.",
+		}, []string{"result"}),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the store metrics.
+func (s *Store) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{s.versionGauge, s.lastReload, s.reloads}
+}
+
+// Version returns the number of changes applied to the settings.
+func (s *Store) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Loaded returns a channel closed once the store received the settings of its source.
+func (s *Store) Loaded() <-chan struct{} {
+	return s.loaded
+}
+
+// Subscribe calls fn with the current settings, and again after every reload applying a change.
+func (s *Store) Subscribe(fn func()) {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, fn)
+	s.mu.Unlock()
+	fn()
+}
+
+// Run watches the source and reloads the settings on every change until ctx is cancelled.
+func (s *Store) Run(ctx context.Context, source Source) error {
+	return source.Watch(ctx, func(values map[string]string) {
+		if err := s.Update(values); err != nil {
+			slog.Error("rejected dynamic configuration:", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// Update reloads the settings from the values of the source. Registered settings missing from values get back their
+// default. Update returns the problems of the invalid values if it rejects the reload.
+func (s *Store) Update(values map[string]string) error {
+	defer s.loadOnce.Do(func() { close(s.loaded) })
+
+	type change struct {
+		key, old, new string
+		apply         func()
+	}
+
+	s.mu.Lock()
+	s.values = make(map[string]string, len(values))
+	for k, v := range values {
+		s.values[k] = v
+	}
+
+	keys := make([]string, 0, len(s.settings))
+	for key := range s.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changes []change
+	var errs []error
+	for _, key := range keys {
+		st := s.settings[key]
+		value, ok := values[key]
+		if !ok {
+			value = st.def
+		}
+		if value == st.value {
+			continue
+		}
+		apply, err := st.parse(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", key, value, err))
+			continue
+		}
+		changes = append(changes, change{key: key, old: st.value, new: value, apply: apply})
+	}
+
+	if len(errs) > 0 {
+		s.mu.Unlock()
+		s.reloads.WithLabelValues(reloadRejected).Inc()
+		return errors.Join(errs...)
+	}
+	if len(changes) == 0 {
+		s.mu.Unlock()
+		s.reloads.WithLabelValues(reloadUnchanged).Inc()
+		return nil
+	}
+
+	for _, c := range changes {
+		c.apply()
+		s.settings[c.key].value = c.new
+		slog.Info("Dynamic setting changed", slog.String("key", c.key), slog.String("old", c.old), slog.String("new", c.new))
+	}
+	s.version++
+	version := s.version
+	subscribers := append([]func(){}, s.subscribers...)
+	s.mu.Unlock()
+
+	s.versionGauge.Set(float64(version))
+	s.lastReload.SetToCurrentTime()
+	s.reloads.WithLabelValues(reloadApplied).Inc()
+	for _, fn := range subscribers {
+		fn()
+	}
+	return nil
+}
+
+// register adds a setting to the store and applies its current value, or its default if the value is invalid.
+// It panics if the key is already registered or the default is invalid, as both are programming errors.
+func (s *Store) register(key, def string, parse func(string) (func(), error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.settings[key]; ok {
+		panic(fmt.Sprintf("dynconfig: setting %s registered twice", key))
+	}
+	applyDefault, err := parse(def)
+	if err != nil {
+		panic(fmt.Sprintf("dynconfig: invalid default of %s: %v", key, err))
+	}
+
+	st := &setting{def: def, value: def, parse: parse}
+	s.settings[key] = st
+	if value, ok := s.values[key]; ok && value != def {
+		if apply, err := parse(value); err == nil {
+			apply()
+			st.value = value
+			return
+		}
+		slog.Error("invalid dynamic setting, using its default:", slog.String("key", key), slog.String("value", value))
+	}
+	applyDefault()
+}
+
+// Duration is a dynamic duration setting.
+type Duration struct {
+	v atomic.Int64
+}
+
+// Load returns the current value of the setting.
+func (d *Duration) Load() time.Duration {
+	return time.Duration(d.v.Load())
+}
+
+// Duration registers a duration setting, which must not be negative.
+func (s *Store) Duration(key string, def time.Duration) *Duration {
+	d := &Duration{}
+	s.register(key, def.String(), func(value string) (func(), error) {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		if v < 0 {
+			return nil, errors.New("must not be negative")
+		}
+		return func() { d.v.Store(int64(v)) }, nil
+	})
+	return d
+}
+
+// Int is a dynamic integer setting.
+type Int struct {
+	v atomic.Int64
+}
+
+// Load returns the current value of the setting.
+func (i *Int) Load() int {
+	return int(i.v.Load())
+}
+
+// Int registers an integer setting, which must not be negative.
+func (s *Store) Int(key string, def int) *Int {
+	i := &Int{}
+	s.register(key, strconv.Itoa(def), func(value string) (func(), error) {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if v < 0 {
+			return nil, errors.New("must not be negative")
+		}
+		return func() { i.v.Store(int64(v)) }, nil
+	})
+	return i
+}
+
+// Float is a dynamic floating-point setting.
+type Float struct {
+	bits atomic.Uint64
+}
+
+// Load returns the current value of the setting.
+func (f *Float) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Float registers a floating-point setting, which must be a finite number that is not negative.
+func (s *Store) Float(key string, def float64) *Float {
+	f := &Float{}
+	s.register(key, strconv.FormatFloat(def, 'g', -1, 64), func(value string) (func(), error) {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, errors.New("must be a finite number that is not negative")
+		}
+		return func() { f.bits.Store(math.Float64bits(v)) }, nil
+	})
+	return f
+}
+
+// Bool is a dynamic boolean setting.
+type Bool struct {
+	v atomic.Bool
+}
+
+// Load returns the current value of the setting.
+func (b *Bool) Load() bool {
+	return b.v.Load()
+}
+
+// Bool registers a boolean setting.
+func (s *Store) Bool(key string, def bool) *Bool {
+	b := &Bool{}
+	s.register(key, strconv.FormatBool(def), func(value string) (func(), error) {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { b.v.Store(v) }, nil
+	})
+	return b
+}
+
+// String is a dynamic string setting.
+type String struct {
+	v atomic.Pointer[string]
+}
+
+// Load returns the current value of the setting.
+func (s *String) Load() string {
+	return *s.v.Load()
+}
+
+// String registers a string setting, whose values are checked by validate if it is not nil.
+func (s *Store) String(key string, def string, validate func(value string) error) *String {
+	str := &String{}
+	s.register(key, def, func(value string) (func(), error) {
+		if validate != nil {
+			if err := validate(value); err != nil {
+				return nil, err
+			}
+		}
+		return func() { str.v.Store(&value) }, nil
+	})
+	return str
+}
+
+// LogLevel registers a setting controlling the level of a logger, such as debug, info, warn or error.
+func (s *Store) LogLevel(key string, level *slog.LevelVar, def slog.Level) {
+	s.register(key, strings.ToLower(def.String()), func(value string) (func(), error) {
+		var v slog.Level
+		if err := v.UnmarshalText([]byte(value)); err != nil {
+			return nil, err
+		}
+		return func() { level.Set(v) }, nil
+	})
+}
+
+// Feature registers the flag of a feature under the FEATURE_ key prefix, such as FEATURE_MOVIE_RATINGS for the
+// MOVIE_RATINGS feature.
+func (s *Store) Feature(name string, def bool) *Bool {
+	b := s.Bool(featurePrefix+name, def)
+	s.mu.Lock()
+	s.features[name] = b
+	s.mu.Unlock()
+	return b
+}
+
+// Enabled reports whether a registered feature is enabled. Unregistered features are disabled.
+func (s *Store) Enabled(feature string) bool {
+	s.mu.Lock()
+	b, ok := s.features[feature]
+	s.mu.Unlock()
+	return ok && b.Load()
+}
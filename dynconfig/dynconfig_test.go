@@ -0,0 +1,87 @@
+package dynconfig
+
+import (
+	"context"
+	"log/slog"
+	"main/dynconfig/memory"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	store := New()
+	timeout := store.Duration("TIMEOUT", time.Second)
+	limit := store.Float("RATE_LIMIT", 0)
+	ratings := store.Feature("RATINGS", true)
+	var level slog.LevelVar
+	store.LogLevel("LOG_LEVEL", &level, slog.LevelInfo)
+
+	var notified atomic.Int32
+	store.Subscribe(func() { notified.Add(1) })
+	require.Equal(t, int32(1), notified.Load())
+
+	// An invalid value rejects the whole reload.
+	require.Error(t, store.Update(map[string]string{"TIMEOUT": "2s", "RATE_LIMIT": "-1"}))
+	require.Equal(t, time.Second, timeout.Load())
+	require.Equal(t, uint64(0), store.Version())
+	require.Equal(t, int32(1), notified.Load())
+
+	require.NoError(t, store.Update(map[string]string{
+		"TIMEOUT":          "2s",
+		"RATE_LIMIT":       "10.5",
+		"FEATURE_RATINGS":  "false",
+		"LOG_LEVEL":        "debug",
+		"UNREGISTERED_KEY": "ignored",
+	}))
+	require.Equal(t, 2*time.Second, timeout.Load())
+	require.Equal(t, 10.5, limit.Load())
+	require.False(t, ratings.Load())
+	require.False(t, store.Enabled("RATINGS"))
+	require.Equal(t, slog.LevelDebug, level.Level())
+	require.Equal(t, uint64(1), store.Version())
+	require.Equal(t, int32(2), notified.Load())
+
+	// Settings missing from a reload get back their default.
+	require.NoError(t, store.Update(map[string]string{"TIMEOUT": "2s"}))
+	require.Equal(t, 0.0, limit.Load())
+	require.True(t, store.Enabled("RATINGS"))
+	require.Equal(t, slog.LevelInfo, level.Level())
+	require.Equal(t, uint64(2), store.Version())
+
+	require.NoError(t, store.Update(map[string]string{"TIMEOUT": "2s"}))
+	require.Equal(t, uint64(2), store.Version())
+	require.Equal(t, 1.0, testutil.ToFloat64(store.reloads.WithLabelValues(reloadRejected)))
+	require.Equal(t, 2.0, testutil.ToFloat64(store.reloads.WithLabelValues(reloadApplied)))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.reloads.WithLabelValues(reloadUnchanged)))
+	require.Equal(t, 2.0, testutil.ToFloat64(store.versionGauge))
+}
+
+func TestRun(t *testing.T) {
+	store := New()
+	burst := store.Int("RATE_BURST", 1)
+	source := memory.New(map[string]string{"RATE_BURST": "5"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- store.Run(ctx, source) }()
+
+	select {
+	case <-store.Loaded():
+	case <-time.After(time.Second):
+		t.Fatal("store not loaded")
+	}
+	require.Equal(t, 5, burst.Load())
+
+	source.Set(map[string]string{"RATE_BURST": "8"})
+	require.Eventually(t, func() bool { return burst.Load() == 8 }, time.Second, 10*time.Millisecond)
+
+	source.Delete("RATE_BURST")
+	require.Eventually(t, func() bool { return burst.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,62 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Source defines a source of dynamic settings read from a file in the KEY=VALUE format of configuration files.
+// The file is polled for changes; a missing file holds no settings.
+type Source struct {
+	path     string
+	interval time.Duration
+}
+
+// New creates a source of dynamic settings reading the file at path every interval.
+func New(path string, interval time.Duration) *Source {
+	return &Source{
+		path:     path,
+		interval: interval,
+	}
+}
+
+// Watch calls update with the settings of the file once, and again every time its content changes,
+// until ctx is cancelled. A file that fails to parse is skipped until its content changes again.
+func (s *Source) Watch(ctx context.Context, update func(values map[string]string)) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var last []byte
+	first := true
+	for {
+		content, err := os.ReadFile(s.path)
+		if errors.Is(err, os.ErrNotExist) {
+			content, err = []byte{}, nil
+		}
+
+		if err != nil {
+			slog.Error("failed to read dynamic configuration file:", slog.String("path", s.path), slog.String("error", err.Error()))
+		} else if first || !bytes.Equal(content, last) {
+			last = content
+			values, err := godotenv.UnmarshalBytes(content)
+			if err != nil {
+				slog.Error("failed to parse dynamic configuration file:", slog.String("path", s.path), slog.String("error", err.Error()))
+			} else {
+				first = false
+				update(values)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// Source defines an in-memory source of dynamic settings.
+type Source struct {
+	sync.Mutex
+	values  map[string]string
+	changed chan struct{}
+}
+
+// New creates an in-memory source holding the given settings.
+func New(values map[string]string) *Source {
+	s := &Source{
+		values:  map[string]string{},
+		changed: make(chan struct{}),
+	}
+	for k, v := range values {
+		s.values[k] = v
+	}
+	return s
+}
+
+// Set sets the settings, notifying the watchers once.
+func (s *Source) Set(values map[string]string) {
+	s.Lock()
+	defer s.Unlock()
+	for k, v := range values {
+		s.values[k] = v
+	}
+	s.notify()
+}
+
+// Delete removes a setting, notifying the watchers.
+func (s *Source) Delete(key string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.values, key)
+	s.notify()
+}
+
+// notify wakes up the watchers. The lock must be held.
+func (s *Source) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of the settings and the channel closed on their next change.
+func (s *Source) snapshot() (map[string]string, <-chan struct{}) {
+	s.Lock()
+	defer s.Unlock()
+	values := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values, s.changed
+}
+
+// Watch calls update with the settings once, and again every time they change, until ctx is cancelled.
+func (s *Source) Watch(ctx context.Context, update func(values map[string]string)) error {
+	for {
+		values, changed := s.snapshot()
+		update(values)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Message defines a message received from a topic.
+type Message interface {
+	// ID returns the broker-assigned identifier of the message, unique among the messages of its topic.
+	ID() string
+	// Key returns the key the message was published with.
+	Key() string
+	// Payload returns the message body.
+	Payload() []byte
+	// Properties returns the application-defined message properties.
+	Properties() map[string]string
+	// EventTime returns the time set by the publisher, or the zero time if none was set.
+	EventTime() time.Time
+	// PublishTime returns the time the message was published.
+	PublishTime() time.Time
+	// RedeliveryCount returns how many times the message was delivered before.
+	RedeliveryCount() uint32
+}
+
+// OutgoingMessage defines a message to be published to a topic.
+type OutgoingMessage struct {
+	Key        string
+	Payload    []byte
+	Properties map[string]string
+	EventTime  time.Time
+}
+
+// Publisher defines a topic publisher.
+type Publisher interface {
+	// Publish sends a message to the topic and returns its identifier.
+	Publish(ctx context.Context, msg *OutgoingMessage) (string, error)
+	// PublishAsync queues a message to be sent in a batch and calls callback with its identifier once it is persisted.
+	// It blocks while the publisher has too many pending messages.
+	PublishAsync(ctx context.Context, msg *OutgoingMessage, callback func(id string, err error))
+	// Flush waits until all the published messages are persisted.
+	Flush() error
+	// Close releases the publisher.
+	Close()
+}
+
+// Subscriber defines a subscription to a topic.
+type Subscriber interface {
+	// Receive blocks until a message is available or the context is done.
+	Receive(ctx context.Context) (Message, error)
+	// Ack acknowledges a message as processed.
+	Ack(msg Message) error
+	// AckCumulative acknowledges a message and every message of the topic before it, including the ones
+	// negatively acknowledged and not redelivered yet. Only exclusive subscriptions support it.
+	AckCumulative(msg Message) error
+	// Nack negatively acknowledges a message so it gets redelivered later.
+	Nack(msg Message)
+	// Close releases the subscriber.
+	Close()
+}
+
+// Reader defines a non-durable cursor over a topic. Messages read are not acknowledged.
+type Reader interface {
+	// Next blocks until the next message is available or the context is done.
+	Next(ctx context.Context) (Message, error)
+	// HasNext reports whether a message is available to read.
+	HasNext() bool
+	// Close releases the reader.
+	Close()
+}
+
+// ReaderOptions defines where a reader starts reading its topic.
+type ReaderOptions struct {
+	Topic string
+	// StartMessageID is the id of the first message to read. Empty starts at the earliest message.
+	StartMessageID string
+	// StartTime skips the messages published before it.
+	StartTime time.Time
+}
+
+// Bus defines a message bus able to create publishers, subscribers and readers.
+type Bus interface {
+	// Publisher creates a publisher for the given topic.
+	Publisher(topic string) (Publisher, error)
+	// Subscribe creates a subscriber with the given options.
+	Subscribe(opts SubscriptionOptions) (Subscriber, error)
+	// Reader creates a reader positioned according to the given options.
+	Reader(opts ReaderOptions) (Reader, error)
+	// Close releases the bus and its connections.
+	Close()
+}
+
+// SubscriptionType defines how messages of a subscription are dispatched to its subscribers.
+type SubscriptionType string
+
+const (
+	// Exclusive allows a single subscriber on the subscription.
+	Exclusive = SubscriptionType("exclusive")
+	// Shared dispatches messages to all subscribers in a round-robin fashion, without ordering guarantees.
+	Shared = SubscriptionType("shared")
+	// KeyShared dispatches messages with the same key to the same subscriber, preserving per-key ordering.
+	KeyShared = SubscriptionType("key_shared")
+)
+
+// SubscriptionOptions defines the options of a subscription.
+type SubscriptionOptions struct {
+	Topic        string
+	Subscription string
+	// Type is the subscription type, Exclusive if empty.
+	Type SubscriptionType
+	// ReceiverQueueSize is the number of messages prefetched by the subscriber. Zero uses the bus default.
+	ReceiverQueueSize int
+	// MaxDeliveries is the number of deliveries after which a message is moved to the dead letter topic.
+	// Zero disables dead lettering. Dead lettering requires a Shared or KeyShared subscription, Pulsar counting
+	// the redeliveries of their messages only.
+	MaxDeliveries uint32
+	// DeadLetterTopic is the topic failing messages are moved to.
+	DeadLetterTopic string
+	// NackBackoff computes the redelivery delay of negatively acknowledged messages.
+	NackBackoff BackoffPolicy
+}
+
+// BackoffPolicy computes the delay before a message is redelivered.
+type BackoffPolicy interface {
+	// Next returns the delay before the next redelivery of a message that was redelivered redeliveryCount times.
+	Next(redeliveryCount uint32) time.Duration
+}
+
+// ExponentialBackoff doubles the redelivery delay on every attempt, starting at Min and up to Max.
+type ExponentialBackoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Next returns the delay before the next redelivery of a message.
+func (b *ExponentialBackoff) Next(redeliveryCount uint32) time.Duration {
+	d := b.Min
+	for i := uint32(0); i < redeliveryCount && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		return b.Max
+	}
+	return d
+}
+
+var (
+	// ErrClosed is returned when using a closed publisher or subscriber.
+	ErrClosed = errors.New("eventbus: closed")
+	// ErrSubscriptionBusy is returned when subscribing to an exclusive subscription that already has a subscriber.
+	ErrSubscriptionBusy = errors.New("eventbus: exclusive subscription already has a subscriber")
+	// ErrCumulativeAckUnsupported is returned when acknowledging cumulatively on a shared subscription.
+	ErrCumulativeAckUnsupported = errors.New("eventbus: cumulative acknowledgement not supported by the subscription type")
+	// ErrDeadLetterUnsupported is returned when subscribing to an exclusive subscription with dead lettering.
+	ErrDeadLetterUnsupported = errors.New("eventbus: dead lettering not supported by the subscription type")
+	// ErrInvalidMessageID is returned when a reader start message id cannot be parsed or found.
+	ErrInvalidMessageID = errors.New("eventbus: invalid message id")
+)
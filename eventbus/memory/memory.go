@@ -0,0 +1,477 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"main/eventbus"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bus defines an in-memory message bus.
+// Topics keep every published message and new subscriptions start at the beginning of their topic.
+// Subscribers sharing a subscription name split its messages between them according to the subscription type.
+type Bus struct {
+	sync.Mutex
+	topics  map[string]*topic
+	changed chan struct{}
+	closed  bool
+}
+
+type topic struct {
+	name          string
+	messages      []*message
+	subscriptions map[string]*subscription
+}
+
+type subscription struct {
+	topic       *topic
+	opts        eventbus.SubscriptionOptions
+	next        int
+	pending     []*pendingMessage
+	inflight    map[uint64]*inflightMessage
+	subscribers int
+}
+
+type pendingMessage struct {
+	msg         *message
+	availableAt time.Time
+}
+
+type inflightMessage struct {
+	msg   *message
+	owner *subscriber
+}
+
+// New creates a new in-memory message bus.
+func New() *Bus {
+	return &Bus{
+		topics:  map[string]*topic{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Publisher creates a publisher for the given topic.
+func (b *Bus) Publisher(topic string) (eventbus.Publisher, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return nil, eventbus.ErrClosed
+	}
+	b.topic(topic)
+	return &publisher{bus: b, topic: topic}, nil
+}
+
+// Subscribe creates a subscriber with the given options.
+func (b *Bus) Subscribe(opts eventbus.SubscriptionOptions) (eventbus.Subscriber, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return nil, eventbus.ErrClosed
+	}
+	if opts.MaxDeliveries > 0 && opts.Type != eventbus.Shared && opts.Type != eventbus.KeyShared {
+		return nil, eventbus.ErrDeadLetterUnsupported
+	}
+	t := b.topic(opts.Topic)
+	sub, ok := t.subscriptions[opts.Subscription]
+	if !ok {
+		sub = &subscription{
+			topic:    t,
+			opts:     opts,
+			inflight: map[uint64]*inflightMessage{},
+		}
+		t.subscriptions[opts.Subscription] = sub
+	}
+	if sub.subscribers > 0 && (sub.opts.Type == "" || sub.opts.Type == eventbus.Exclusive) {
+		return nil, eventbus.ErrSubscriptionBusy
+	}
+	sub.subscribers++
+	return &subscriber{bus: b, sub: sub}, nil
+}
+
+// Reader creates a reader positioned according to the given options.
+// Message ids are in the topic:sequence form returned by Message.ID.
+func (b *Bus) Reader(opts eventbus.ReaderOptions) (eventbus.Reader, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.closed {
+		return nil, eventbus.ErrClosed
+	}
+	t := b.topic(opts.Topic)
+	r := &reader{bus: b, topic: t, startTime: opts.StartTime}
+	if opts.StartMessageID != "" {
+		next := -1
+		for i, m := range t.messages {
+			if m.id == opts.StartMessageID {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("%w: %q", eventbus.ErrInvalidMessageID, opts.StartMessageID)
+		}
+		r.next = next
+	}
+	return r, nil
+}
+
+// Close closes the bus, unblocking all pending receives.
+func (b *Bus) Close() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.closed = true
+	b.notify()
+}
+
+// topic returns the topic with the given name, creating it if needed. The caller must hold the lock.
+func (b *Bus) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{
+			name:          name,
+			subscriptions: map[string]*subscription{},
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// publish appends a message to a topic. The caller must hold the lock.
+func (b *Bus) publish(topicName string, msg *message) string {
+	t := b.topic(topicName)
+	msg.seq = uint64(len(t.messages))
+	msg.id = fmt.Sprintf("%s:%d", topicName, msg.seq)
+	t.messages = append(t.messages, msg)
+	b.notify()
+	return msg.id
+}
+
+// notify wakes up every blocked receive. The caller must hold the lock.
+func (b *Bus) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+type publisher struct {
+	bus   *Bus
+	topic string
+}
+
+func (p *publisher) Publish(ctx context.Context, msg *eventbus.OutgoingMessage) (string, error) {
+	p.bus.Lock()
+	defer p.bus.Unlock()
+
+	if p.bus.closed {
+		return "", eventbus.ErrClosed
+	}
+	props := make(map[string]string, len(msg.Properties))
+	for k, v := range msg.Properties {
+		props[k] = v
+	}
+	return p.bus.publish(p.topic, &message{
+		key:         msg.Key,
+		payload:     append([]byte(nil), msg.Payload...),
+		properties:  props,
+		eventTime:   msg.EventTime,
+		publishTime: time.Now(),
+	}), nil
+}
+
+func (p *publisher) PublishAsync(ctx context.Context, msg *eventbus.OutgoingMessage, callback func(id string, err error)) {
+	callback(p.Publish(ctx, msg))
+}
+
+func (p *publisher) Flush() error {
+	return nil
+}
+
+func (p *publisher) Close() {}
+
+type subscriber struct {
+	bus    *Bus
+	sub    *subscription
+	closed bool
+}
+
+// Receive returns redelivered messages that are due first, then new messages in publish order.
+func (s *subscriber) Receive(ctx context.Context) (eventbus.Message, error) {
+	for {
+		s.bus.Lock()
+		if s.bus.closed || s.closed {
+			s.bus.Unlock()
+			return nil, eventbus.ErrClosed
+		}
+
+		now := time.Now()
+		if msg := s.sub.take(s, now); msg != nil {
+			s.sub.inflight[msg.seq] = &inflightMessage{msg: msg, owner: s}
+			s.bus.Unlock()
+			return msg, nil
+		}
+		changed := s.bus.changed
+		wait := s.sub.nextAvailable(now)
+		s.bus.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *subscriber) Ack(msg eventbus.Message) error {
+	s.bus.Lock()
+	defer s.bus.Unlock()
+
+	delete(s.sub.inflight, msg.(*message).seq)
+	return nil
+}
+
+// AckCumulative acknowledges the message and every earlier message, dropping their pending redeliveries.
+func (s *subscriber) AckCumulative(msg eventbus.Message) error {
+	s.bus.Lock()
+	defer s.bus.Unlock()
+
+	if s.sub.opts.Type == eventbus.Shared || s.sub.opts.Type == eventbus.KeyShared {
+		return eventbus.ErrCumulativeAckUnsupported
+	}
+	seq := msg.(*message).seq
+	for inflightSeq := range s.sub.inflight {
+		if inflightSeq <= seq {
+			delete(s.sub.inflight, inflightSeq)
+		}
+	}
+	pending := s.sub.pending[:0]
+	for _, p := range s.sub.pending {
+		if p.msg.seq > seq {
+			pending = append(pending, p)
+		}
+	}
+	s.sub.pending = pending
+	return nil
+}
+
+// Nack schedules a message for redelivery after its backoff, or moves it to the dead letter topic
+// once it was delivered MaxDeliveries times.
+func (s *subscriber) Nack(msg eventbus.Message) {
+	s.bus.Lock()
+	defer s.bus.Unlock()
+
+	m := msg.(*message)
+	if _, ok := s.sub.inflight[m.seq]; !ok {
+		return
+	}
+	delete(s.sub.inflight, m.seq)
+	s.sub.redeliver(s.bus, m, time.Now())
+	s.bus.notify()
+}
+
+// Close closes the subscriber, making its unacknowledged messages available to other subscribers.
+func (s *subscriber) Close() {
+	s.bus.Lock()
+	defer s.bus.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.sub.subscribers--
+	for seq, in := range s.sub.inflight {
+		if in.owner != s {
+			continue
+		}
+		delete(s.sub.inflight, seq)
+		s.sub.enqueue(in.msg.redelivery(), time.Now())
+	}
+	s.bus.notify()
+}
+
+type reader struct {
+	bus       *Bus
+	topic     *topic
+	next      int
+	startTime time.Time
+	closed    bool
+}
+
+func (r *reader) Next(ctx context.Context) (eventbus.Message, error) {
+	for {
+		r.bus.Lock()
+		if r.bus.closed || r.closed {
+			r.bus.Unlock()
+			return nil, eventbus.ErrClosed
+		}
+		if msg := r.take(); msg != nil {
+			r.bus.Unlock()
+			return msg, nil
+		}
+		changed := r.bus.changed
+		r.bus.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (r *reader) HasNext() bool {
+	r.bus.Lock()
+	defer r.bus.Unlock()
+
+	for _, m := range r.topic.messages[r.next:] {
+		if !m.publishTime.Before(r.startTime) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reader) Close() {
+	r.bus.Lock()
+	defer r.bus.Unlock()
+
+	r.closed = true
+	r.bus.notify()
+}
+
+// take returns the next message published at or after the start time. The caller must hold the lock.
+func (r *reader) take() *message {
+	for ; r.next < len(r.topic.messages); r.next++ {
+		if m := r.topic.messages[r.next]; !m.publishTime.Before(r.startTime) {
+			r.next++
+			return m.delivery()
+		}
+	}
+	return nil
+}
+
+// take removes and returns the next message deliverable to the subscriber. The caller must hold the lock.
+// Key_Shared subscriptions only deliver a message once every earlier message with the same key was acknowledged,
+// and never while another subscriber holds unacknowledged messages with that key.
+func (s *subscription) take(to *subscriber, now time.Time) *message {
+	for ; s.next < len(s.topic.messages); s.next++ {
+		s.pending = append(s.pending, &pendingMessage{msg: s.topic.messages[s.next].delivery()})
+	}
+
+	blocked := map[string]bool{}
+	if s.opts.Type == eventbus.KeyShared {
+		for _, in := range s.inflight {
+			if in.owner != to {
+				blocked[in.msg.key] = true
+			}
+		}
+	}
+
+	for i, p := range s.pending {
+		if s.opts.Type == eventbus.KeyShared && blocked[p.msg.key] {
+			continue
+		}
+		if p.availableAt.After(now) {
+			blocked[p.msg.key] = true
+			continue
+		}
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		return p.msg
+	}
+	return nil
+}
+
+// nextAvailable returns how long until the next pending redelivery is due, or zero if there is none.
+func (s *subscription) nextAvailable(now time.Time) time.Duration {
+	var wait time.Duration
+	for _, p := range s.pending {
+		d := p.availableAt.Sub(now)
+		if d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// redeliver schedules a negatively acknowledged message. The caller must hold the lock.
+func (s *subscription) redeliver(bus *Bus, m *message, now time.Time) {
+	if s.opts.MaxDeliveries > 0 && m.redeliveryCount+1 >= s.opts.MaxDeliveries {
+		bus.publish(s.deadLetterTopic(), &message{
+			key:         m.key,
+			payload:     m.payload,
+			properties:  m.properties,
+			eventTime:   m.eventTime,
+			publishTime: now,
+		})
+		return
+	}
+
+	var delay time.Duration
+	if s.opts.NackBackoff != nil {
+		delay = s.opts.NackBackoff.Next(m.redeliveryCount)
+	}
+	s.enqueue(m.redelivery(), now.Add(delay))
+}
+
+// enqueue adds a message to the pending redeliveries, keeping them in publish order.
+func (s *subscription) enqueue(m *message, availableAt time.Time) {
+	s.pending = append(s.pending, &pendingMessage{msg: m, availableAt: availableAt})
+	sort.SliceStable(s.pending, func(i, j int) bool {
+		return s.pending[i].msg.seq < s.pending[j].msg.seq
+	})
+}
+
+func (s *subscription) deadLetterTopic() string {
+	if s.opts.DeadLetterTopic != "" {
+		return s.opts.DeadLetterTopic
+	}
+	return s.topic.name + "-" + s.opts.Subscription + "-DLQ"
+}
+
+type message struct {
+	id              string
+	seq             uint64
+	key             string
+	payload         []byte
+	properties      map[string]string
+	eventTime       time.Time
+	publishTime     time.Time
+	redeliveryCount uint32
+}
+
+// delivery returns a copy of a stored message to be handed to a subscriber.
+func (m *message) delivery() *message {
+	c := *m
+	return &c
+}
+
+// redelivery returns a copy of the message with its redelivery count incremented.
+func (m *message) redelivery() *message {
+	c := *m
+	c.redeliveryCount++
+	return &c
+}
+
+func (m *message) ID() string                    { return m.id }
+func (m *message) Key() string                   { return m.key }
+func (m *message) Payload() []byte               { return m.payload }
+func (m *message) Properties() map[string]string { return m.properties }
+func (m *message) EventTime() time.Time          { return m.eventTime }
+func (m *message) PublishTime() time.Time        { return m.publishTime }
+func (m *message) RedeliveryCount() uint32       { return m.redeliveryCount }
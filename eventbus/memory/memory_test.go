@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"main/eventbus"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func publishN(t *testing.T, bus *Bus, topic string, n int) {
+	publisher, err := bus.Publisher(topic)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	for i := 0; i < n; i++ {
+		_, err := publisher.Publish(context.Background(), &eventbus.OutgoingMessage{
+			Key:     fmt.Sprintf("key-%d", i),
+			Payload: []byte(fmt.Sprintf("msg-%d", i)),
+		})
+		require.NoError(t, err)
+	}
+}
+
+func receive(t *testing.T, sub eventbus.Subscriber) eventbus.Message {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := sub.Receive(ctx)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestReceiveInOrder(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	publishN(t, bus, "ratings", 5)
+
+	for i := 0; i < 5; i++ {
+		msg := receive(t, sub)
+		require.Equal(t, fmt.Sprintf("msg-%d", i), string(msg.Payload()))
+		require.Equal(t, fmt.Sprintf("key-%d", i), msg.Key())
+		require.NoError(t, sub.Ack(msg))
+	}
+}
+
+func TestNackRedeliversInOrder(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	publishN(t, bus, "ratings", 2)
+
+	first := receive(t, sub)
+	sub.Nack(first)
+
+	redelivered := receive(t, sub)
+	require.Equal(t, first.ID(), redelivered.ID())
+	require.Equal(t, uint32(1), redelivered.RedeliveryCount())
+	require.NoError(t, sub.Ack(redelivered))
+
+	second := receive(t, sub)
+	require.Equal(t, "msg-1", string(second.Payload()))
+	require.Equal(t, uint32(0), second.RedeliveryCount())
+}
+
+func TestNackBackoff(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{
+		Topic:        "ratings",
+		Subscription: "test",
+		NackBackoff:  &eventbus.ExponentialBackoff{Min: 100 * time.Millisecond, Max: time.Second},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	publishN(t, bus, "ratings", 1)
+
+	msg := receive(t, sub)
+	nackedAt := time.Now()
+	sub.Nack(msg)
+
+	redelivered := receive(t, sub)
+	require.Equal(t, msg.ID(), redelivered.ID())
+	require.GreaterOrEqual(t, time.Since(nackedAt), 100*time.Millisecond)
+}
+
+func TestDeadLetter(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{
+		Topic:           "ratings",
+		Subscription:    "test",
+		Type:            eventbus.Shared,
+		MaxDeliveries:   3,
+		DeadLetterTopic: "ratings-dlq",
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	dlq, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings-dlq", Subscription: "test"})
+	require.NoError(t, err)
+	defer dlq.Close()
+
+	publishN(t, bus, "ratings", 2)
+
+	for i := uint32(0); i < 3; i++ {
+		msg := receive(t, sub)
+		require.Equal(t, "msg-0", string(msg.Payload()))
+		require.Equal(t, i, msg.RedeliveryCount())
+		sub.Nack(msg)
+	}
+
+	next := receive(t, sub)
+	require.Equal(t, "msg-1", string(next.Payload()))
+
+	dead := receive(t, dlq)
+	require.Equal(t, "msg-0", string(dead.Payload()))
+	require.Equal(t, "key-0", dead.Key())
+}
+
+func TestDeadLetterRequiresSharedSubscription(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	_, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test", MaxDeliveries: 3})
+	require.ErrorIs(t, err, eventbus.ErrDeadLetterUnsupported)
+}
+
+func TestSharedSubscription(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	opts := eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test", Type: eventbus.Shared}
+	sub1, err := bus.Subscribe(opts)
+	require.NoError(t, err)
+	sub2, err := bus.Subscribe(opts)
+	require.NoError(t, err)
+	defer sub2.Close()
+
+	publishN(t, bus, "ratings", 2)
+
+	msg := receive(t, sub1)
+	require.Equal(t, "msg-0", string(msg.Payload()))
+	require.Equal(t, "msg-1", string(receive(t, sub2).Payload()))
+
+	sub1.Close()
+	redelivered := receive(t, sub2)
+	require.Equal(t, "msg-0", string(redelivered.Payload()))
+	require.Equal(t, uint32(1), redelivered.RedeliveryCount())
+}
+
+func TestExclusiveSubscription(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	opts := eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test", Type: eventbus.Exclusive}
+	sub, err := bus.Subscribe(opts)
+	require.NoError(t, err)
+
+	_, err = bus.Subscribe(opts)
+	require.ErrorIs(t, err, eventbus.ErrSubscriptionBusy)
+
+	sub.Close()
+	sub, err = bus.Subscribe(opts)
+	require.NoError(t, err)
+	sub.Close()
+}
+
+func TestKeySharedSubscription(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	opts := eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test", Type: eventbus.KeyShared}
+	sub1, err := bus.Subscribe(opts)
+	require.NoError(t, err)
+	defer sub1.Close()
+	sub2, err := bus.Subscribe(opts)
+	require.NoError(t, err)
+	defer sub2.Close()
+
+	publisher, err := bus.Publisher("ratings")
+	require.NoError(t, err)
+	for i, key := range []string{"a", "a", "b"} {
+		_, err := publisher.Publish(context.Background(), &eventbus.OutgoingMessage{
+			Key:     key,
+			Payload: []byte(fmt.Sprintf("msg-%d", i)),
+		})
+		require.NoError(t, err)
+	}
+
+	first := receive(t, sub1)
+	require.Equal(t, "msg-0", string(first.Payload()))
+
+	// The second message with key "a" is held back while sub1 has key "a" in flight.
+	other := receive(t, sub2)
+	require.Equal(t, "msg-2", string(other.Payload()))
+	require.NoError(t, sub2.Ack(other))
+
+	require.NoError(t, sub1.Ack(first))
+	second := receive(t, sub2)
+	require.Equal(t, "msg-1", string(second.Payload()))
+}
+
+func TestReceiveCancelled(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = sub.Receive(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReaderStartsAtMessageID(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	publishN(t, bus, "ratings", 3)
+
+	reader, err := bus.Reader(eventbus.ReaderOptions{Topic: "ratings", StartMessageID: "ratings:1"})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	for _, want := range []string{"msg-1", "msg-2"} {
+		require.True(t, reader.HasNext())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		msg, err := reader.Next(ctx)
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, want, string(msg.Payload()))
+	}
+	require.False(t, reader.HasNext())
+
+	_, err = bus.Reader(eventbus.ReaderOptions{Topic: "ratings", StartMessageID: "ratings:42"})
+	require.ErrorIs(t, err, eventbus.ErrInvalidMessageID)
+}
+
+func TestReaderStartTime(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	publishN(t, bus, "ratings", 2)
+	start := time.Now()
+	time.Sleep(time.Millisecond)
+	publishN(t, bus, "ratings", 1)
+
+	reader, err := bus.Reader(eventbus.ReaderOptions{Topic: "ratings", StartTime: start})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := reader.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ratings:2", msg.ID())
+	require.False(t, reader.HasNext())
+}
+
+func TestAckCumulative(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+
+	publishN(t, bus, "ratings", 3)
+
+	receive(t, sub)
+	second := receive(t, sub)
+	require.NoError(t, sub.AckCumulative(second))
+
+	// Closing the subscriber only redelivers the messages after the cumulatively acknowledged one.
+	sub.Close()
+	sub, err = bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Equal(t, "msg-2", string(receive(t, sub).Payload()))
+
+	shared, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "shared", Type: eventbus.Shared})
+	require.NoError(t, err)
+	defer shared.Close()
+	require.ErrorIs(t, shared.AckCumulative(receive(t, shared)), eventbus.ErrCumulativeAckUnsupported)
+}
@@ -0,0 +1,281 @@
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"main/eventbus"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// Config defines the Pulsar connection settings.
+type Config struct {
+	URL               string        `env:"PULSAR_URL" env-default:"pulsar://localhost:6650"`
+	ConnectionTimeout time.Duration `env:"CONNECTION_TIMEOUT" env-default:"30s"`
+	OperationTimeout  time.Duration `env:"OPERATION_TIMEOUT" env-default:"30s"`
+	// Producer batching settings.
+	BatchingMaxPublishDelay time.Duration `env:"BATCHING_MAX_PUBLISH_DELAY" env-default:"10ms"`
+	BatchingMaxMessages     uint          `env:"BATCHING_MAX_MESSAGES" env-default:"1000"`
+	MaxPendingMessages      int           `env:"MAX_PENDING_MESSAGES" env-default:"1000"`
+}
+
+// Bus defines a Pulsar-based message bus.
+type Bus struct {
+	client pulsar.Client
+	cfg    Config
+}
+
+// New creates a new Pulsar-based message bus.
+func New(cfg Config) (*Bus, error) {
+	client, err := pulsar.NewClient(pulsar.ClientOptions{
+		URL:               cfg.URL,
+		ConnectionTimeout: cfg.ConnectionTimeout,
+		OperationTimeout:  cfg.OperationTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Bus{
+		client: client,
+		cfg:    cfg,
+	}, nil
+}
+
+// Publisher creates a publisher for the given topic.
+func (b *Bus) Publisher(topic string) (eventbus.Publisher, error) {
+	producer, err := b.client.CreateProducer(pulsar.ProducerOptions{
+		Topic: topic,
+		// Key-based batching keeps messages with different keys in separate batches,
+		// which Key_Shared subscriptions need to dispatch them by key.
+		BatcherBuilderType:      pulsar.KeyBasedBatchBuilder,
+		BatchingMaxPublishDelay: b.cfg.BatchingMaxPublishDelay,
+		BatchingMaxMessages:     b.cfg.BatchingMaxMessages,
+		MaxPendingMessages:      b.cfg.MaxPendingMessages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{producer: producer}, nil
+}
+
+// Subscribe creates a subscriber with the given options.
+func (b *Bus) Subscribe(opts eventbus.SubscriptionOptions) (eventbus.Subscriber, error) {
+	if opts.MaxDeliveries > 0 && opts.Type != eventbus.Shared && opts.Type != eventbus.KeyShared {
+		// The broker does not count the redeliveries of exclusive subscriptions, so the messages would never
+		// reach the dead letter topic.
+		return nil, eventbus.ErrDeadLetterUnsupported
+	}
+	options := pulsar.ConsumerOptions{
+		Topic:             opts.Topic,
+		SubscriptionName:  opts.Subscription,
+		Type:              subscriptionType(opts.Type),
+		ReceiverQueueSize: opts.ReceiverQueueSize,
+	}
+	if opts.Type == eventbus.KeyShared {
+		options.KeySharedPolicy = &pulsar.KeySharedPolicy{
+			Mode: pulsar.KeySharedPolicyModeAutoSplit,
+		}
+	}
+	if opts.MaxDeliveries > 0 {
+		options.DLQ = &pulsar.DLQPolicy{
+			MaxDeliveries:   opts.MaxDeliveries,
+			DeadLetterTopic: opts.DeadLetterTopic,
+		}
+	}
+	if opts.NackBackoff != nil {
+		options.NackBackoffPolicy = opts.NackBackoff
+	}
+
+	consumer, err := b.client.Subscribe(options)
+	if err != nil {
+		return nil, err
+	}
+	return &subscriber{consumer: consumer, typ: opts.Type}, nil
+}
+
+// Reader creates a reader positioned according to the given options.
+// Message ids are in the form returned by Message.ID; ids without a batch index read batched messages from the
+// start of their batch. A start time without a start message id seeks the reader by publish time.
+func (b *Bus) Reader(opts eventbus.ReaderOptions) (eventbus.Reader, error) {
+	start := pulsar.EarliestMessageID()
+	if opts.StartMessageID != "" {
+		id, err := parseMessageID(opts.StartMessageID)
+		if err != nil {
+			return nil, err
+		}
+		start = id
+	}
+
+	r, err := b.client.CreateReader(pulsar.ReaderOptions{
+		Topic:                   opts.Topic,
+		StartMessageID:          start,
+		StartMessageIDInclusive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.StartMessageID == "" && !opts.StartTime.IsZero() {
+		if err := r.SeekByTime(opts.StartTime); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return &reader{reader: r, startTime: opts.StartTime}, nil
+}
+
+// Close closes the underlying Pulsar client.
+func (b *Bus) Close() {
+	b.client.Close()
+}
+
+func subscriptionType(t eventbus.SubscriptionType) pulsar.SubscriptionType {
+	switch t {
+	case eventbus.Shared:
+		return pulsar.Shared
+	case eventbus.KeyShared:
+		return pulsar.KeyShared
+	default:
+		return pulsar.Exclusive
+	}
+}
+
+type publisher struct {
+	producer pulsar.Producer
+}
+
+func (p *publisher) Publish(ctx context.Context, msg *eventbus.OutgoingMessage) (string, error) {
+	id, err := p.producer.Send(ctx, &pulsar.ProducerMessage{
+		Key:        msg.Key,
+		Payload:    msg.Payload,
+		Properties: msg.Properties,
+		EventTime:  msg.EventTime,
+	})
+	if err != nil {
+		return "", err
+	}
+	return formatMessageID(id), nil
+}
+
+func (p *publisher) PublishAsync(ctx context.Context, msg *eventbus.OutgoingMessage, callback func(id string, err error)) {
+	p.producer.SendAsync(ctx, &pulsar.ProducerMessage{
+		Key:        msg.Key,
+		Payload:    msg.Payload,
+		Properties: msg.Properties,
+		EventTime:  msg.EventTime,
+	}, func(id pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+		if err != nil {
+			callback("", err)
+			return
+		}
+		callback(formatMessageID(id), nil)
+	})
+}
+
+func (p *publisher) Flush() error {
+	return p.producer.Flush()
+}
+
+func (p *publisher) Close() {
+	p.producer.Close()
+}
+
+type subscriber struct {
+	consumer pulsar.Consumer
+	typ      eventbus.SubscriptionType
+}
+
+func (s *subscriber) Receive(ctx context.Context) (eventbus.Message, error) {
+	msg, err := s.consumer.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &message{msg: msg}, nil
+}
+
+func (s *subscriber) Ack(msg eventbus.Message) error {
+	return s.consumer.Ack(msg.(*message).msg)
+}
+
+func (s *subscriber) AckCumulative(msg eventbus.Message) error {
+	if s.typ == eventbus.Shared || s.typ == eventbus.KeyShared {
+		return eventbus.ErrCumulativeAckUnsupported
+	}
+	return s.consumer.AckCumulative(msg.(*message).msg)
+}
+
+func (s *subscriber) Nack(msg eventbus.Message) {
+	s.consumer.Nack(msg.(*message).msg)
+}
+
+func (s *subscriber) Close() {
+	s.consumer.Close()
+}
+
+type reader struct {
+	reader    pulsar.Reader
+	startTime time.Time
+}
+
+func (r *reader) Next(ctx context.Context) (eventbus.Message, error) {
+	for {
+		msg, err := r.reader.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if msg.PublishTime().Before(r.startTime) {
+			continue
+		}
+		return &message{msg: msg}, nil
+	}
+}
+
+func (r *reader) HasNext() bool {
+	return r.reader.HasNext()
+}
+
+func (r *reader) Close() {
+	r.reader.Close()
+}
+
+// formatMessageID formats a message id in the ledger:entry:partition form, followed by :index for the messages
+// after the first of a batch. The messages of a batch share their entry, so the index keeps their ids unique.
+func formatMessageID(id pulsar.MessageID) string {
+	if id.BatchIdx() > 0 {
+		return fmt.Sprintf("%s:%d", id.String(), id.BatchIdx())
+	}
+	return id.String()
+}
+
+// parseMessageID parses a message id in the form returned by formatMessageID. An id without batch index stands for
+// the start of its entry.
+func parseMessageID(s string) (pulsar.MessageID, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("%w: %q", eventbus.ErrInvalidMessageID, s)
+	}
+	nums := [4]int64{3: -1}
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", eventbus.ErrInvalidMessageID, s)
+		}
+		nums[i] = n
+	}
+	return pulsar.NewMessageID(nums[0], nums[1], int32(nums[3]), int32(nums[2])), nil
+}
+
+// message adapts a Pulsar message to the eventbus.Message interface.
+type message struct {
+	msg pulsar.Message
+}
+
+func (m *message) ID() string                    { return formatMessageID(m.msg.ID()) }
+func (m *message) Key() string                   { return m.msg.Key() }
+func (m *message) Payload() []byte               { return m.msg.Payload() }
+func (m *message) Properties() map[string]string { return m.msg.Properties() }
+func (m *message) EventTime() time.Time          { return m.msg.EventTime() }
+func (m *message) PublishTime() time.Time        { return m.msg.PublishTime() }
+func (m *message) RedeliveryCount() uint32       { return m.msg.RedeliveryCount() }
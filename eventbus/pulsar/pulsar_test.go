@@ -0,0 +1,38 @@
+package pulsar
+
+import (
+	"main/eventbus"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+// batchedMessage is a Pulsar message with the given id.
+type batchedMessage struct {
+	pulsar.Message
+	id pulsar.MessageID
+}
+
+func (m *batchedMessage) ID() pulsar.MessageID { return m.id }
+
+func TestBatchedMessageIDs(t *testing.T) {
+	first := &message{msg: &batchedMessage{id: pulsar.NewMessageID(7, 3, 0, 1)}}
+	second := &message{msg: &batchedMessage{id: pulsar.NewMessageID(7, 3, 1, 1)}}
+	require.Equal(t, "7:3:1", first.ID())
+	require.Equal(t, "7:3:1:1", second.ID())
+
+	id, err := parseMessageID(second.ID())
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id.LedgerID())
+	require.Equal(t, int64(3), id.EntryID())
+	require.Equal(t, int32(1), id.BatchIdx())
+	require.Equal(t, int32(1), id.PartitionIdx())
+
+	id, err = parseMessageID(first.ID())
+	require.NoError(t, err)
+	require.Equal(t, int32(-1), id.BatchIdx())
+
+	_, err = parseMessageID("7:3")
+	require.ErrorIs(t, err, eventbus.ErrInvalidMessageID)
+}
@@ -0,0 +1,198 @@
+package eventcodec
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	eventsv1 "main/rpc/events/v1"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// update rewrites the schema snapshot and the encoded fixtures.
+// Only run it for backward compatible schema changes, the snapshot is what guards against breaking ones.
+var update = flag.Bool("update", false, "update the schema snapshot and fixtures")
+
+const schemaSnapshot = "testdata/schema_v1.golden"
+
+// schemaEntries describes the registered event schemas as key to value entries.
+// Any change of an existing entry breaks either the wire or the JSON encoding of already published events.
+func schemaEntries() map[string]string {
+	entries := map[string]string{}
+	seen := map[protoreflect.FullName]bool{}
+
+	var walkEnum func(e protoreflect.EnumDescriptor)
+	walkEnum = func(e protoreflect.EnumDescriptor) {
+		if seen[e.FullName()] {
+			return
+		}
+		seen[e.FullName()] = true
+		values := e.Values()
+		for i := 0; i < values.Len(); i++ {
+			entries[fmt.Sprintf("enum %s %d", e.FullName(), values.Get(i).Number())] = string(values.Get(i).Name())
+		}
+	}
+
+	var walkMessage func(m protoreflect.MessageDescriptor)
+	walkMessage = func(m protoreflect.MessageDescriptor) {
+		if seen[m.FullName()] || strings.HasPrefix(string(m.FullName()), "google.protobuf.") {
+			return
+		}
+		seen[m.FullName()] = true
+		fields := m.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			f := fields.Get(i)
+			kind := f.Kind().String()
+			switch {
+			case f.Message() != nil:
+				kind = string(f.Message().FullName())
+				walkMessage(f.Message())
+			case f.Enum() != nil:
+				kind = string(f.Enum().FullName())
+				walkEnum(f.Enum())
+			}
+			entries[fmt.Sprintf("field %s %d", m.FullName(), f.Number())] = fmt.Sprintf("%s %s %s", f.Name(), f.Cardinality(), kind)
+		}
+	}
+
+	for name, s := range schemasByMessage {
+		entries[fmt.Sprintf("type %s %s", s.eventType, s.version)] = string(name)
+		walkMessage(s.message.ProtoReflect().Descriptor())
+	}
+	return entries
+}
+
+func readSnapshot(t *testing.T) map[string]string {
+	f, err := os.Open(schemaSnapshot)
+	require.NoError(t, err)
+	defer f.Close()
+
+	entries := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " = ")
+		require.True(t, ok, "malformed snapshot line %q", scanner.Text())
+		entries[key] = value
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func writeSnapshot(t *testing.T, entries map[string]string) {
+	var lines []string
+	for key, value := range entries {
+		lines = append(lines, key+" = "+value)
+	}
+	sort.Strings(lines)
+	require.NoError(t, os.WriteFile(schemaSnapshot, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+}
+
+// reserved reports whether a removed field was properly reserved by its message.
+func reserved(key, value string) bool {
+	var message string
+	var number int32
+	if _, err := fmt.Sscanf(key, "field %s %d", &message, &number); err != nil {
+		return false
+	}
+	desc, err := findMessage(protoreflect.FullName(message))
+	if err != nil {
+		return false
+	}
+	name, _, _ := strings.Cut(value, " ")
+	return desc.ReservedRanges().Has(protoreflect.FieldNumber(number)) && desc.ReservedNames().Has(protoreflect.Name(name))
+}
+
+// findMessage returns the descriptor of a registered event message or of a message used by one.
+func findMessage(name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
+	for _, s := range schemasByMessage {
+		d := s.message.ProtoReflect().Descriptor()
+		if d.FullName() == name {
+			return d, nil
+		}
+		fields := d.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			if m := fields.Get(i).Message(); m != nil && m.FullName() == name {
+				return m, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("message %s not found", name)
+}
+
+func TestSchemaCompatibility(t *testing.T) {
+	current := schemaEntries()
+	snapshot := readSnapshot(t)
+
+	for key, want := range snapshot {
+		got, ok := current[key]
+		if !ok {
+			require.True(t, reserved(key, want), "%s (%s) was removed without reserving its number and name", key, want)
+			continue
+		}
+		require.Equal(t, want, got, "%s changed incompatibly, breaking changes require a new schema version", key)
+	}
+
+	var added []string
+	for key, value := range current {
+		if _, ok := snapshot[key]; !ok {
+			added = append(added, key+" = "+value)
+		}
+	}
+	if *update {
+		writeSnapshot(t, current)
+		return
+	}
+	require.Empty(t, added, "schema has new entries, run go test ./eventcodec -update to accept them")
+}
+
+// fixtures are events encoded by earlier releases, which must keep decoding to the same data.
+var fixtures = []struct {
+	file        string
+	contentType string
+	data        proto.Message
+}{
+	{"testdata/rating_event_v1.json", ContentTypeJSON, ratingEventFixture()},
+	{"testdata/rating_event_v1.pb", ContentTypeProtobuf, ratingEventFixture()},
+}
+
+func ratingEventFixture() *eventsv1.RatingEvent {
+	return &eventsv1.RatingEvent{
+		UserId:     "105",
+		RecordId:   "1",
+		RecordType: "movie",
+		Value:      5,
+		EventType:  eventsv1.RatingEventType_RATING_EVENT_TYPE_PUT,
+	}
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	for _, fixture := range fixtures {
+		t.Run(fixture.file, func(t *testing.T) {
+			if *update {
+				payload, err := Encode(fixture.contentType, &Envelope{
+					ID:     "5d9f3c1e-8a4b-4f0e-9c6d-2b7a1e3f4c01",
+					Source: "/producer",
+					Time:   time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+				}, fixture.data)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(fixture.file, payload, 0o644))
+			}
+
+			payload, err := os.ReadFile(fixture.file)
+			require.NoError(t, err)
+
+			env, data, err := Decode(fixture.contentType, payload)
+			require.NoError(t, err)
+			require.Equal(t, "5d9f3c1e-8a4b-4f0e-9c6d-2b7a1e3f4c01", env.ID)
+			require.Equal(t, TypeRatingEvent, env.Type)
+			require.True(t, proto.Equal(fixture.data, data), "decoded %v, want %v", data, fixture.data)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package eventcodec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	cloudeventsv1 "main/rpc/cloudevents/v1"
+	eventsv1 "main/rpc/events/v1"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Content types of encoded events. Publishers set the content type in the PropertyContentType message property.
+const (
+	// ContentTypeJSON is the CloudEvents structured JSON format, with the event data encoded as protobuf JSON.
+	ContentTypeJSON = "application/cloudevents+json"
+	// ContentTypeProtobuf is the CloudEvents protobuf format, with the event data encoded as a protobuf Any.
+	ContentTypeProtobuf = "application/cloudevents+protobuf"
+)
+
+// PropertyContentType is the message property holding the content type of the message payload.
+const PropertyContentType = "content-type"
+
+// SpecVersion is the supported CloudEvents specification version.
+const SpecVersion = "1.0"
+
+// Event types, used as the CloudEvents type attribute.
+const (
+	TypeRatingEvent      = "RatingEvent"
+	TypeRatingChanged    = "RatingChanged"
+	TypeAggregateChanged = "AggregateChanged"
+	TypeMetadataChanged  = "MetadataChanged"
+	TypeAuditRecorded    = "AuditRecorded"
+)
+
+var (
+	// ErrUnsupportedContentType is returned when decoding a payload with an unknown content type.
+	ErrUnsupportedContentType = errors.New("eventcodec: unsupported content type")
+	// ErrUnknownSchema is returned when an event type and schema version pair is not registered.
+	ErrUnknownSchema = errors.New("eventcodec: unknown event type or schema version")
+	// ErrInvalidEnvelope is returned when a decoded envelope misses required attributes.
+	ErrInvalidEnvelope = errors.New("eventcodec: invalid envelope")
+)
+
+// Envelope defines the CloudEvents context attributes of an event.
+type Envelope struct {
+	ID     string
+	Source string
+	// Type and SchemaVersion are derived from the event data when encoding.
+	Type          string
+	SchemaVersion string
+	Subject       string
+	Time          time.Time
+}
+
+type schema struct {
+	eventType string
+	version   string
+	message   proto.Message
+}
+
+var (
+	schemasByMessage = map[protoreflect.FullName]schema{}
+	schemasByType    = map[string]schema{}
+)
+
+func init() {
+	register(TypeRatingEvent, "v1", &eventsv1.RatingEvent{})
+	register(TypeRatingChanged, "v1", &eventsv1.RatingChanged{})
+	register(TypeAggregateChanged, "v1", &eventsv1.AggregateChanged{})
+	register(TypeMetadataChanged, "v1", &eventsv1.MetadataChanged{})
+	register(TypeAuditRecorded, "v1", &eventsv1.AuditRecorded{})
+}
+
+// register binds an event type and schema version to the message its data is encoded with.
+func register(eventType, version string, msg proto.Message) {
+	s := schema{eventType: eventType, version: version, message: msg}
+	schemasByMessage[msg.ProtoReflect().Descriptor().FullName()] = s
+	schemasByType[eventType+"/"+version] = s
+}
+
+// Encode wraps data into a CloudEvents envelope and encodes it in the given content type.
+// The type and schema version of the envelope are set from the registered schema of data.
+func Encode(contentType string, env *Envelope, data proto.Message) ([]byte, error) {
+	s, ok := schemasByMessage[data.ProtoReflect().Descriptor().FullName()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSchema, data.ProtoReflect().Descriptor().FullName())
+	}
+	env.Type = s.eventType
+	env.SchemaVersion = s.version
+
+	switch contentType {
+	case ContentTypeJSON:
+		return encodeJSON(env, data)
+	case ContentTypeProtobuf:
+		return encodeProtobuf(env, data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
+
+// Decode decodes a CloudEvents payload of the given content type.
+// It returns the envelope and the event data, decoded with the message registered for its type and schema version.
+func Decode(contentType string, payload []byte) (*Envelope, proto.Message, error) {
+	switch contentType {
+	case ContentTypeJSON:
+		return decodeJSON(payload)
+	case ContentTypeProtobuf:
+		return decodeProtobuf(payload)
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
+
+// newData returns an empty message for the data of the given envelope.
+func newData(env *Envelope) (proto.Message, error) {
+	if env.ID == "" || env.Source == "" || env.Type == "" {
+		return nil, fmt.Errorf("%w: id, source and type are required", ErrInvalidEnvelope)
+	}
+	s, ok := schemasByType[env.Type+"/"+env.SchemaVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownSchema, env.Type, env.SchemaVersion)
+	}
+	return s.message.ProtoReflect().New().Interface(), nil
+}
+
+// dataSchema returns the dataschema attribute of the given data.
+func dataSchema(data proto.Message) string {
+	return string(data.ProtoReflect().Descriptor().FullName())
+}
+
+type jsonEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	SchemaVersion   string          `json:"schemaversion"`
+	Data            json.RawMessage `json:"data"`
+}
+
+func encodeJSON(env *Envelope, data proto.Message) ([]byte, error) {
+	encoded, err := protojson.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	event := jsonEvent{
+		SpecVersion:     SpecVersion,
+		ID:              env.ID,
+		Source:          env.Source,
+		Type:            env.Type,
+		Subject:         env.Subject,
+		DataContentType: "application/json",
+		DataSchema:      dataSchema(data),
+		SchemaVersion:   env.SchemaVersion,
+		Data:            encoded,
+	}
+	if !env.Time.IsZero() {
+		t := env.Time.UTC()
+		event.Time = &t
+	}
+	return json.Marshal(event)
+}
+
+func decodeJSON(payload []byte) (*Envelope, proto.Message, error) {
+	var event jsonEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, nil, err
+	}
+	if event.SpecVersion != SpecVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported specversion %q", ErrInvalidEnvelope, event.SpecVersion)
+	}
+
+	env := &Envelope{
+		ID:            event.ID,
+		Source:        event.Source,
+		Type:          event.Type,
+		SchemaVersion: event.SchemaVersion,
+		Subject:       event.Subject,
+	}
+	if event.Time != nil {
+		env.Time = *event.Time
+	}
+
+	data, err := newData(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Unknown fields are ignored so that consumers keep working when producers add fields.
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(event.Data, data); err != nil {
+		return nil, nil, err
+	}
+	return env, data, nil
+}
+
+func stringAttribute(v string) *cloudeventsv1.CloudEvent_CloudEventAttributeValue {
+	return &cloudeventsv1.CloudEvent_CloudEventAttributeValue{
+		Attr: &cloudeventsv1.CloudEvent_CloudEventAttributeValue_CeString{CeString: v},
+	}
+}
+
+func encodeProtobuf(env *Envelope, data proto.Message) ([]byte, error) {
+	packed, err := anypb.New(data)
+	if err != nil {
+		return nil, err
+	}
+	event := &cloudeventsv1.CloudEvent{
+		Id:          env.ID,
+		Source:      env.Source,
+		SpecVersion: SpecVersion,
+		Type:        env.Type,
+		Attributes: map[string]*cloudeventsv1.CloudEvent_CloudEventAttributeValue{
+			"datacontenttype": stringAttribute("application/protobuf"),
+			"dataschema": {
+				Attr: &cloudeventsv1.CloudEvent_CloudEventAttributeValue_CeUriRef{CeUriRef: dataSchema(data)},
+			},
+			"schemaversion": stringAttribute(env.SchemaVersion),
+		},
+		Data: &cloudeventsv1.CloudEvent_ProtoData{ProtoData: packed},
+	}
+	if env.Subject != "" {
+		event.Attributes["subject"] = stringAttribute(env.Subject)
+	}
+	if !env.Time.IsZero() {
+		event.Attributes["time"] = &cloudeventsv1.CloudEvent_CloudEventAttributeValue{
+			Attr: &cloudeventsv1.CloudEvent_CloudEventAttributeValue_CeTimestamp{CeTimestamp: timestamppb.New(env.Time)},
+		}
+	}
+	return proto.Marshal(event)
+}
+
+func decodeProtobuf(payload []byte) (*Envelope, proto.Message, error) {
+	var event cloudeventsv1.CloudEvent
+	if err := proto.Unmarshal(payload, &event); err != nil {
+		return nil, nil, err
+	}
+	if event.SpecVersion != SpecVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported specversion %q", ErrInvalidEnvelope, event.SpecVersion)
+	}
+
+	attrs := event.GetAttributes()
+	env := &Envelope{
+		ID:            event.Id,
+		Source:        event.Source,
+		Type:          event.Type,
+		SchemaVersion: attrs["schemaversion"].GetCeString(),
+		Subject:       attrs["subject"].GetCeString(),
+	}
+	if t := attrs["time"].GetCeTimestamp(); t != nil {
+		env.Time = t.AsTime()
+	}
+
+	data, err := newData(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	packed := event.GetProtoData()
+	if packed == nil {
+		return nil, nil, fmt.Errorf("%w: missing proto data", ErrInvalidEnvelope)
+	}
+	if err := proto.Unmarshal(packed.GetValue(), data); err != nil {
+		return nil, nil, err
+	}
+	return env, data, nil
+}
@@ -0,0 +1,62 @@
+package eventcodec
+
+import (
+	eventsv1 "main/rpc/events/v1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProtobuf} {
+		t.Run(contentType, func(t *testing.T) {
+			data := &eventsv1.RatingEvent{
+				UserId:     "user1",
+				RecordId:   "movie1",
+				RecordType: "movie",
+				Value:      5,
+				EventType:  eventsv1.RatingEventType_RATING_EVENT_TYPE_PUT,
+			}
+			env := &Envelope{
+				ID:      "event1",
+				Source:  "/test",
+				Subject: "movie1",
+				Time:    time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+			}
+
+			payload, err := Encode(contentType, env, data)
+			require.NoError(t, err)
+			require.Equal(t, TypeRatingEvent, env.Type)
+			require.Equal(t, "v1", env.SchemaVersion)
+
+			decodedEnv, decoded, err := Decode(contentType, payload)
+			require.NoError(t, err)
+			require.Equal(t, env, decodedEnv)
+			require.True(t, proto.Equal(data, decoded))
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, _, err := Decode("application/xml", []byte("<event/>"))
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, _, err = Decode(ContentTypeJSON, []byte(`{"specversion":"1.0","id":"1","source":"/test","type":"RatingEvent","schemaversion":"v2","data":{}}`))
+	require.ErrorIs(t, err, ErrUnknownSchema)
+
+	_, _, err = Decode(ContentTypeJSON, []byte(`{"specversion":"0.3","id":"1","source":"/test","type":"RatingEvent","schemaversion":"v1","data":{}}`))
+	require.ErrorIs(t, err, ErrInvalidEnvelope)
+
+	_, _, err = Decode(ContentTypeJSON, []byte(`{"specversion":"1.0","source":"/test","type":"RatingEvent","schemaversion":"v1","data":{}}`))
+	require.ErrorIs(t, err, ErrInvalidEnvelope)
+}
+
+func TestDecodeIgnoresUnknownFields(t *testing.T) {
+	payload := []byte(`{"specversion":"1.0","id":"1","source":"/test","type":"RatingEvent","schemaversion":"v1","data":{"userId":"user1","addedLater":true}}`)
+
+	_, data, err := Decode(ContentTypeJSON, payload)
+	require.NoError(t, err)
+	require.Equal(t, "user1", data.(*eventsv1.RatingEvent).UserId)
+}
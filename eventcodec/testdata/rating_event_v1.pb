@@ -0,0 +1,9 @@
+
+$5d9f3c1e-8a4b-4f0e-9c6d-2b7a1e3f4c01	/producer1.0"RatingEvent*)
+datacontenttypeapplication/protobuf*%
+
+dataschema2events.v1.RatingEvent*
+schemaversionv1*
+time:B@
+)type.googleapis.com/events.v1.RatingEvent
+1051movie (
\ No newline at end of file
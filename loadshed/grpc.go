@@ -0,0 +1,140 @@
+package loadshed
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// priorityMetadata is the metadata key carrying the priority of a gRPC request.
+const priorityMetadata = "x-priority"
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx carrying the priority of its request, which the client interceptors use
+// and forward to the downstream services.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority of the request of ctx, if any.
+func PriorityFromContext(ctx context.Context) (Priority, bool) {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	return p, ok
+}
+
+// MethodPriority returns the default priority of a gRPC method: critical for the health service, high for writes,
+// low for listings and normal for the other reads.
+func MethodPriority(fullMethod string) Priority {
+	if strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return PriorityCritical
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range []string{"Put", "Create", "Update", "Delete", "Set"} {
+		if strings.HasPrefix(method, prefix) {
+			return PriorityHigh
+		}
+	}
+	if strings.HasPrefix(method, "List") {
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+// incomingPriority returns the priority of an incoming gRPC request from its method, lowered by its metadata if
+// requested. The metadata is set by any client, so it cannot raise a request above the priority of its method.
+func incomingPriority(ctx context.Context, fullMethod string) Priority {
+	p := MethodPriority(fullMethod)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(priorityMetadata); len(v) > 0 {
+			if requested, ok := ParsePriority(v[0]); ok {
+				return capPriority(requested, p)
+			}
+		}
+	}
+	return p
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor shedding the unary requests over the limit with
+// ResourceExhausted. The priority of a request is the one of its method, which its x-priority metadata may lower.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		p := incomingPriority(ctx, info.FullMethod)
+		release, ok := l.Acquire(p)
+		if !ok {
+			return nil, shedError(info.FullMethod, p)
+		}
+		resp, err := handler(WithPriority(ctx, p), req)
+		release(serverOutcome(err))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor shedding the streams over the limit with ResourceExhausted.
+// Streams count against the limit while they are open, but their duration does not adjust it.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		p := incomingPriority(ss.Context(), info.FullMethod)
+		release, ok := l.Acquire(p)
+		if !ok {
+			return shedError(info.FullMethod, p)
+		}
+		defer release(Ignored)
+		return handler(srv, ss)
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor failing the calls over the limit with ResourceExhausted
+// without sending them. The priority of a call is the one of the request of ctx, or else its method; it is
+// forwarded to the called service in the x-priority metadata.
+func (l *Limiter) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		p, ok := PriorityFromContext(ctx)
+		if !ok {
+			p = MethodPriority(method)
+		}
+		release, ok := l.Acquire(p)
+		if !ok {
+			return shedError(method, p)
+		}
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(priorityMetadata)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, priorityMetadata, p.String())
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		release(clientOutcome(err))
+		return err
+	}
+}
+
+func shedError(fullMethod string, p Priority) error {
+	return status.Errorf(codes.ResourceExhausted, "%s request to %s shed, the service is overloaded", p, fullMethod)
+}
+
+// serverOutcome returns the outcome of a served request. Requests rejected by another limiter are ignored.
+func serverOutcome(err error) Outcome {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return Dropped
+	case codes.Canceled, codes.ResourceExhausted:
+		return Ignored
+	default:
+		return Success
+	}
+}
+
+// clientOutcome returns the outcome of a call. Calls rejected or timed out by the called service are dropped.
+func clientOutcome(err error) Outcome {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Unavailable:
+		return Dropped
+	case codes.Canceled:
+		return Ignored
+	default:
+		return Success
+	}
+}
@@ -0,0 +1,35 @@
+package loadshed
+
+import (
+	"net/http"
+)
+
+// priorityHeader is the header carrying the priority of an HTTP request.
+const priorityHeader = "X-Priority"
+
+// Middleware returns an HTTP handler shedding the requests over the limit with 503 Service Unavailable.
+// The priority of a request is high for writes and normal for reads, which its X-Priority header may lower.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := requestPriority(r)
+		if requested, ok := ParsePriority(r.Header.Get(priorityHeader)); ok {
+			p = capPriority(requested, p)
+		}
+		release, ok := l.Acquire(p)
+		if !ok {
+			http.Error(w, "service overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		defer release(Success)
+		next.ServeHTTP(w, r.WithContext(WithPriority(r.Context(), p)))
+	})
+}
+
+// requestPriority returns the default priority of an HTTP request: high for writes and normal for reads.
+func requestPriority(r *http.Request) Priority {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
@@ -0,0 +1,286 @@
+package loadshed
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Priority is the importance of a request. When the limiter is near its limit, requests of lower priority are
+// shed first.
+type Priority int
+
+// Priorities of requests, from the first to the last to be shed.
+const (
+	// PriorityLow is for bulk reads, such as listings.
+	PriorityLow Priority = iota
+	// PriorityNormal is for the other reads.
+	PriorityNormal
+	// PriorityHigh is for writes.
+	PriorityHigh
+	// PriorityCritical is for health checks, which are never shed.
+	PriorityCritical
+)
+
+// priorityNames are the names of the priorities in headers, metadata and metrics.
+var priorityNames = [...]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+// priorityShares are the shares of the limit the requests of each priority may use.
+var priorityShares = [...]float64{
+	PriorityLow:      0.6,
+	PriorityNormal:   0.8,
+	PriorityHigh:     1,
+	PriorityCritical: math.Inf(1),
+}
+
+func (p Priority) String() string {
+	if p < PriorityLow || p > PriorityCritical {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+// capPriority lowers a priority requested by a client to the default priority of its request. The priority is set
+// by any client, so it may only lower a request: raising it would let bulk reads compete with writes, or bypass the
+// limit.
+func capPriority(requested, def Priority) Priority {
+	return min(requested, def)
+}
+
+// ParsePriority returns the priority of the given name, case insensitive.
+func ParsePriority(name string) (Priority, bool) {
+	for p, n := range priorityNames {
+		if strings.EqualFold(name, n) {
+			return Priority(p), true
+		}
+	}
+	return PriorityNormal, false
+}
+
+// Outcome is the result of a request, telling the limiter how to account for its latency.
+type Outcome int
+
+const (
+	// Success is a request whose latency reflects the load of the server.
+	Success Outcome = iota
+	// Dropped is a request that failed because of the load of the server, such as timed out requests.
+	Dropped
+	// Ignored is a request whose latency says nothing about the load of the server, such as cancelled requests.
+	Ignored
+)
+
+// Results of the admission of a request, as labelled in the limiter metrics.
+const (
+	resultAdmitted = "admitted"
+	resultShed     = "shed"
+)
+
+// Defaults of the limiter settings.
+const (
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 1000
+	defaultTolerance    = 2
+	defaultBackoff      = 0.9
+)
+
+// Window of the latency samples the limit is adjusted on: it ends after as many samples as the limit, and at least
+// minWindowSamples, or after maxWindowDuration if it got any sample.
+const (
+	minWindowSamples  = 10
+	maxWindowDuration = time.Second
+)
+
+// baselineSmoothing is the weight of the latency of a window in the baseline latency.
+const baselineSmoothing = 0.1
+
+// Config holds the settings of an adaptive concurrency limiter. Zero settings take their default.
+type Config struct {
+	// InitialLimit is the number of concurrent requests allowed before the limit adapts to the observed latency.
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit.
+	MinLimit int
+	MaxLimit int
+	// Tolerance is how many times longer than the baseline latency requests may take on average before the limit
+	// decreases.
+	Tolerance float64
+	// Backoff is the factor the limit is multiplied by when it decreases.
+	Backoff float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.InitialLimit == 0 {
+		c.InitialLimit = defaultInitialLimit
+	}
+	if c.MinLimit == 0 {
+		c.MinLimit = defaultMinLimit
+	}
+	if c.MaxLimit == 0 {
+		c.MaxLimit = defaultMaxLimit
+	}
+	if c.Tolerance == 0 {
+		c.Tolerance = defaultTolerance
+	}
+	if c.Backoff == 0 {
+		c.Backoff = defaultBackoff
+	}
+	return c
+}
+
+// window accumulates the latency samples of the requests completed since the limit was last adjusted.
+type window struct {
+	start       time.Time
+	samples     int
+	successes   int
+	latency     float64
+	dropped     bool
+	maxInflight int
+}
+
+// Limiter limits the number of concurrent requests with an AIMD algorithm driven by their latency. The limit grows
+// by one request per window of samples while the average latency of the window stays within the tolerance of the
+// baseline latency and the limit is in use; it is multiplied by the backoff once the latency exceeds the tolerance
+// or requests are dropped. The baseline follows the latency slowly, so the limit recovers from a lasting change of
+// latency.
+// Requests over the share of the limit of their priority are shed, so lower priority requests are shed first.
+type Limiter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	baseline float64
+	window   window
+
+	limitGauge    prometheus.Gauge
+	inflightGauge prometheus.Gauge
+	baselineGauge prometheus.Gauge
+	requests      *prometheus.CounterVec
+}
+
+// New creates an adaptive concurrency limiter, labelled with its name in the limiter metrics.
+func New(name string, cfg Config) *Limiter {
+	cfg = cfg.withDefaults()
+	labels := prometheus.Labels{"limiter": name}
+	l := &Limiter{
+		cfg:    cfg,
+		limit:  float64(cfg.InitialLimit),
+		window: window{start: time.Now()},
+		limitGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "loadshed",
+			Name:        "limit",
+			Help:        "Number of concurrent requests allowed by the adaptive limiter.",
+			ConstLabels: labels,
+		}),
+		inflightGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "loadshed",
+			Name:        "inflight",
+			Help:        "Number of requests in flight through the adaptive limiter.",
+			ConstLabels: labels,
+		}),
+		baselineGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "loadshed",
+			Name:        "baseline_latency_seconds",
+			Help:        "Baseline latency of the requests the adaptive limiter compares their latency to.",
+			ConstLabels: labels,
+		}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "loadshed",
+			Name:        "requests_total",
+			Help:        "Number of requests through the adaptive limiter, by priority and admission result.",
+			ConstLabels: labels,
+		}, []string{"priority", "result"}),
+	}
+	l.limitGauge.Set(l.limit)
+	return l
+}
+
+// Collectors returns the Prometheus collectors of the limiter metrics.
+func (l *Limiter) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{l.limitGauge, l.inflightGauge, l.baselineGauge, l.requests}
+}
+
+// Limit returns the current number of concurrent requests allowed.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire admits a request of the given priority if the requests in flight are within the share of the limit of
+// its priority. The returned function must be called with the outcome of an admitted request once it completes.
+func (l *Limiter) Acquire(p Priority) (func(Outcome), bool) {
+	l.mu.Lock()
+	if p != PriorityCritical && float64(l.inflight) >= l.limit*priorityShares[p] {
+		l.mu.Unlock()
+		l.requests.WithLabelValues(p.String(), resultShed).Inc()
+		return nil, false
+	}
+	l.inflight++
+	l.window.maxInflight = max(l.window.maxInflight, l.inflight)
+	l.inflightGauge.Set(float64(l.inflight))
+	l.mu.Unlock()
+	l.requests.WithLabelValues(p.String(), resultAdmitted).Inc()
+
+	start := time.Now()
+	var once sync.Once
+	return func(outcome Outcome) {
+		once.Do(func() { l.release(time.Since(start), outcome) })
+	}, true
+}
+
+// release accounts for a completed request and adjusts the limit at the end of a window.
+func (l *Limiter) release(latency time.Duration, outcome Outcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.inflightGauge.Set(float64(l.inflight))
+
+	switch outcome {
+	case Ignored:
+		return
+	case Dropped:
+		l.window.dropped = true
+	default:
+		l.window.successes++
+		l.window.latency += latency.Seconds()
+	}
+	l.window.samples++
+
+	if l.window.samples < max(int(l.limit), minWindowSamples) && time.Since(l.window.start) < maxWindowDuration {
+		return
+	}
+	l.adjust()
+	l.window = window{start: time.Now(), maxInflight: l.inflight}
+}
+
+// adjust updates the limit from the samples of the window. The lock must be held.
+func (l *Limiter) adjust() {
+	overloaded := l.window.dropped
+	if l.window.successes > 0 {
+		latency := l.window.latency / float64(l.window.successes)
+		if l.baseline == 0 {
+			l.baseline = latency
+		}
+		overloaded = overloaded || latency > l.baseline*l.cfg.Tolerance
+		l.baseline += (latency - l.baseline) * baselineSmoothing
+		l.baselineGauge.Set(l.baseline)
+	}
+
+	switch {
+	case overloaded:
+		l.limit = max(float64(l.cfg.MinLimit), l.limit*l.cfg.Backoff)
+	case float64(l.window.maxInflight)*2 >= l.limit:
+		// The limit only grows while it is in use, so it does not grow without bounds under a light load.
+		l.limit = min(float64(l.cfg.MaxLimit), l.limit+1)
+	}
+	l.limitGauge.Set(l.limit)
+}
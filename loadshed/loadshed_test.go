@@ -0,0 +1,135 @@
+package loadshed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAcquireShedsByPriority(t *testing.T) {
+	l := New("test", Config{InitialLimit: 10})
+
+	admitted := func(p Priority) int {
+		n := 0
+		for {
+			if _, ok := l.Acquire(p); !ok {
+				return n
+			}
+			n++
+		}
+	}
+	require.Equal(t, 6, admitted(PriorityLow))
+	require.Equal(t, 2, admitted(PriorityNormal))
+	require.Equal(t, 2, admitted(PriorityHigh))
+	for i := 0; i < 5; i++ {
+		_, ok := l.Acquire(PriorityCritical)
+		require.True(t, ok)
+	}
+	require.Equal(t, float64(1), testutil.ToFloat64(l.requests.WithLabelValues("low", resultShed)))
+	require.Equal(t, float64(15), testutil.ToFloat64(l.inflightGauge))
+}
+
+// runWindow completes a full window of concurrent requests with the given latency and outcome.
+func runWindow(t *testing.T, l *Limiter, latency time.Duration, outcome Outcome) {
+	n := max(l.Limit(), minWindowSamples)
+	for i := 0; i < n; i++ {
+		_, ok := l.Acquire(PriorityCritical)
+		require.True(t, ok)
+	}
+	for i := 0; i < n; i++ {
+		l.release(latency, outcome)
+	}
+}
+
+func TestLimitAdaptsToLatency(t *testing.T) {
+	l := New("test", Config{InitialLimit: 20, MinLimit: 5, MaxLimit: 22})
+
+	runWindow(t, l, 10*time.Millisecond, Success)
+	require.Equal(t, 21, l.Limit())
+	runWindow(t, l, 12*time.Millisecond, Success)
+	runWindow(t, l, 12*time.Millisecond, Success)
+	require.Equal(t, 22, l.Limit())
+
+	runWindow(t, l, 50*time.Millisecond, Success)
+	require.Equal(t, 19, l.Limit())
+	runWindow(t, l, time.Millisecond, Dropped)
+	require.Equal(t, 17, l.Limit())
+	for i := 0; i < 20; i++ {
+		runWindow(t, l, time.Millisecond, Dropped)
+	}
+	require.Equal(t, 5, l.Limit())
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l := New("test", Config{InitialLimit: 1})
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/RatingService/ListTopRated"}
+
+	var priority Priority
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(priorityMetadata, "high"))
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		priority, _ = PriorityFromContext(ctx)
+
+		// The only slot is taken: listings are shed, health checks are not.
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) { return nil, nil })
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"},
+			func(context.Context, any) (any, error) { return nil, nil })
+		require.NoError(t, err)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	// A listing asking for a high priority stays low.
+	require.Equal(t, PriorityLow, priority)
+	require.Equal(t, float64(0), testutil.ToFloat64(l.inflightGauge))
+}
+
+func TestUnaryServerInterceptorCapsRequestedPriority(t *testing.T) {
+	l := New("test", Config{InitialLimit: 1})
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/RatingService/PutRating"}
+	critical := metadata.NewIncomingContext(context.Background(), metadata.Pairs(priorityMetadata, "critical"))
+
+	var priority Priority
+	_, err := interceptor(critical, nil, info, func(ctx context.Context, req any) (any, error) {
+		priority, _ = PriorityFromContext(ctx)
+
+		// A client asking for a critical priority does not bypass the limit.
+		_, err := interceptor(critical, nil, info, func(context.Context, any) (any, error) { return nil, nil })
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, PriorityHigh, priority)
+
+	// A client may lower the priority of its request.
+	low := metadata.NewIncomingContext(context.Background(), metadata.Pairs(priorityMetadata, "low"))
+	_, err = interceptor(low, nil, info, func(ctx context.Context, req any) (any, error) {
+		priority, _ = PriorityFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, PriorityLow, priority)
+}
+
+func TestUnaryClientInterceptorForwardsPriority(t *testing.T) {
+	l := New("test", Config{})
+	interceptor := l.UnaryClientInterceptor()
+
+	var forwarded []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		forwarded = append(forwarded, md.Get(priorityMetadata)...)
+		return nil
+	}
+	require.NoError(t, interceptor(WithPriority(context.Background(), PriorityHigh), "/MetadataService/GetMetadata", nil, nil, nil, invoker))
+	require.NoError(t, interceptor(context.Background(), "/RatingService/ListTrending", nil, nil, nil, invoker))
+	require.Equal(t, []string{"high", "low"}, forwarded)
+}
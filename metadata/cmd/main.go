@@ -5,177 +5,130 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"main/app"
+	"main/audit"
+	auditpg "main/audit/postgres"
+	"main/auth"
+	"main/config"
 	"main/database/db"
-	"main/discovery"
-	"main/discovery/consul"
+	"main/database/dbtrace"
+	"main/eventbus/pulsar"
 	grpchandler "main/metadata/handler/grpc"
+	"main/metadata/repository"
 	"main/metadata/repository/postgres"
 	"main/metadata/service"
+	"main/outbox"
+	outboxpg "main/outbox/postgres"
+	"main/ratelimit"
 	"main/rpc"
-	"main/tracing"
 	"main/util"
-	"net"
-	"net/http"
 	"os"
-	"os/signal"
-	"runtime/pprof"
-	"sync"
-	"syscall"
-	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 )
 
-const serviceName = "metadata"
+const (
+	serviceName = "metadata"
+	// limit and burst are the default quota of every caller. The unauthenticated requests relayed by the movie
+	// gateway share its address, so the quota is higher than the one of the gateway.
+	limit = 1000
+	burst = 1000
+)
+
+// policy restricts the edits of the metadata to editors and the admin methods to operators. Reads are public.
+var policy = auth.Policy{
+	"Put*":            {auth.RoleEditor},
+	"Create*":         {auth.RoleEditor},
+	"Update*":         {auth.RoleEditor},
+	"Delete*":         {auth.RoleEditor},
+	"Import*":         {auth.RoleEditor},
+	auth.AdminMethods: {auth.RoleOperator},
+}
 
 func main() {
 	// configurations
-	var config string
 	var simulateCPUload bool
-	flag.StringVar(&config, "config", ".env", "Configuration path")
 	flag.BoolVar(&simulateCPUload, "simulatecpuload", false, "Simulate CPU load for profiling")
-	flag.Parse()
-	cfg := util.LoadConfig(config)
+	var cfg config.Metadata
+	config.MustLoad(&cfg)
 
-	// environments
-	if cfg.Environment == "dev" {
-		var logger = slog.New(slog.NewTextHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
-	} else {
-		var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
+	if err := run(&cfg, simulateCPUload); err != nil {
+		slog.Error("metadata service failed:", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
+}
 
-	// profiling
-	cpuFile, err := os.Create("cpu.pprof")
+func run(cfg *config.Metadata, simulateCPUload bool) error {
+	source, err := cfg.DynamicSource(serviceName)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create dynamic configuration source: %w", err)
 	}
-	memFile, err := os.Create("mem.pprof")
+
+	a, err := app.New(serviceName,
+		app.WithEnvironment(cfg.Environment),
+		app.WithProfiling("cpu.pprof", "mem.pprof"),
+		app.WithMetrics(cfg.MetadataMetricsPort),
+		app.WithTracing(cfg.JaegerURL),
+		app.WithDiscovery(cfg.ConsulURL),
+		app.WithDynamicConfig(source),
+		app.WithConcurrencyLimit(cfg.ConcurrencyConfig()),
+		app.WithAuth(cfg.AuthConfig()),
+		app.WithTLS(cfg.TLSConfig("movie")),
+		app.WithRateLimit(ratelimit.Quota{Limit: limit, Burst: burst}),
+		app.WithAuthorization(policy),
+		app.WithGRPC(fmt.Sprintf("%s:%d", cfg.Host, cfg.MetadataPort)),
+		app.WithShutdownTimeout(cfg.ShutdownTimeout),
+	)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	pprof.StartCPUProfile(cpuFile)
-	pprof.WriteHeapProfile(memFile)
-	defer pprof.StopCPUProfile()
-	defer memFile.Close()
-
 	if simulateCPUload {
 		go util.HeavyOperation()
 	}
 
-	// prometheus metrics
-	reg := prometheus.NewRegistry()
-	counter := promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: serviceName,
-		Name:      "service_started",
-	})
-
-	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
-	go func() {
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.MetadataMetricsPort), nil); err != nil {
-			slog.Error("failed to start metrics handler:", slog.String("error", err.Error()))
-			return
-		}
-	}()
-
-	reg.MustRegister(counter)
-	defer reg.Unregister(counter)
-	counter.Inc()
-
-	// jaeger tracing
-	slog.Info("Starting the movie metadata service on port", slog.Int("port", cfg.MetadataPort))
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := tracing.NewJaegerProvider(cfg.JaegerURL, serviceName)
+	// services
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
 	if err != nil {
-		slog.Error("failed to initialize Jaeger provider:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("invalid database url: %w", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			slog.Error("failed to shutdown tracing provider:", slog.String("error", err.Error()))
-			return
-		}
-	}()
-
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	// service discovery
-	registry, err := consul.NewRegistry(cfg.ConsulURL)
+	dbTracer := dbtrace.New(serviceName)
+	poolConfig.ConnConfig.Tracer = dbTracer
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		slog.Error("failed to connect consul registry:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("cannot connect to db: %w", err)
 	}
+	a.Append(app.Hook{Name: "database", OnStop: func(context.Context) error { conn.Close(); return nil }})
+	a.Check("database", conn.Ping)
+	a.Metrics().MustRegister(dbTracer.Collectors()...)
+	a.Metrics().MustRegister(dbtrace.NewPoolCollector(conn))
 
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	hostPort := fmt.Sprintf("%s:%d", cfg.Host, cfg.MetadataPort)
-	fmt.Println(hostPort)
-	if err := registry.Register(ctx, instanceID, serviceName, hostPort); err != nil {
-		slog.Error("failed to register metadata service:", slog.String("error", err.Error()))
-		return
+	bus, err := pulsar.New(cfg.Pulsar)
+	if err != nil {
+		return fmt.Errorf("failed to connect to pulsar: %w", err)
 	}
+	a.Append(app.Hook{Name: "pulsar", OnStop: func(context.Context) error { bus.Close(); return nil }})
 
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				slog.Info("Failed to report healthy state:", slog.String("error", err.Error()))
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-
-	// services
-	conn, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	publisher, err := bus.Publisher(cfg.MetadataEventsTopic)
 	if err != nil {
-		slog.Error("cannot connect to db:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to create metadata events publisher: %w", err)
 	}
+	a.Append(app.Hook{Name: "metadata events publisher", OnStop: func(context.Context) error { publisher.Close(); return nil }})
 
 	store := db.NewStore(conn)
-	repo := postgres.New(store)
-	svc := service.New(repo)
+	auditStore := auditpg.New(store, cfg.AuditEventSource(repository.EventSource))
+	repo := postgres.New(store, auditStore)
+	auditor := audit.New(serviceName, auditStore)
+	a.Metrics().MustRegister(auditor.Collectors()...)
+	a.Authorizer().OnDenied(auditor.Denied)
+	svc := service.New(repo, auditor)
 	h := grpchandler.New(svc)
 
-	listener, err := net.Listen("tcp", hostPort)
-	if err != nil {
-		slog.Error("failed to listen on", slog.String("host", hostPort), slog.String("error", err.Error()))
-		return
-	}
-
-	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	relay := outbox.NewRelay(outboxpg.New(store), publisher, cfg.OutboxRelayConfig(repository.EventSource, cfg.MetadataEventsTopic))
+	a.Metrics().MustRegister(relay.Collectors()...)
+	a.Go("outbox relay", relay.Run)
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		slog.Info("Received signal", s)
-		slog.Info("attempting graceful shutdown")
-		server.GracefulStop()
-		slog.Info("Gracefully stopped the gRPC server")
-	}()
-
-	reflection.Register(server)
-	rpc.RegisterMetadataServiceServer(server, h)
-	if err := server.Serve(listener); err != nil {
-		slog.Error("Failed to start gRPC server:", slog.String("error", err.Error()))
-		return
-	}
+	rpc.RegisterMetadataServiceServer(a.GRPCServer(), h)
+	rpc.RegisterAuditAdminServer(a.GRPCServer(), audit.NewHandler(serviceName, auditStore))
+	return a.Run(context.Background())
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"main/auth"
 	"main/metadata/model"
 	"main/metadata/repository"
 	"main/metadata/service"
@@ -12,13 +13,16 @@ import (
 
 // Handler defines a movie metadata HTTP handler.
 type Handler struct {
-	ctrl *service.MetadataService
+	ctrl       *service.MetadataService
+	authorizer *auth.Authorizer
 }
 
-// New creates a new movie metadata HTTP handler.
-func New(ctrl *service.MetadataService) *Handler {
+// New creates a new movie metadata HTTP handler. The authorizer, if not nil, restricts the edits of the metadata
+// to the roles of its policy for PutMetadata.
+func New(ctrl *service.MetadataService, authorizer *auth.Authorizer) *Handler {
 	return &Handler{
-		ctrl: ctrl,
+		ctrl:       ctrl,
+		authorizer: authorizer,
 	}
 }
 
@@ -45,6 +49,10 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Response encode error: %v\n", err)
 		}
 	case http.MethodPut:
+		if err := h.authorizer.Authorize(r.Context(), "PutMetadata"); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		title := r.FormValue("title")
 		if title == "" {
 			w.WriteHeader(http.StatusBadRequest)
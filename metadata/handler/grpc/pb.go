@@ -42,6 +42,29 @@ func (h *Handler) GetMetadata(ctx context.Context, req *rpc.GetMetadataRequest)
 	}, nil
 }
 
+// maxBatchSize is the maximum number of movies of a GetMetadataBatch request.
+const maxBatchSize = 100
+
+// GetMetadataBatch returns the metadata of the given movies. Movies without metadata are left out of the response.
+func (h *Handler) GetMetadataBatch(ctx context.Context, req *rpc.GetMetadataBatchRequest) (*rpc.GetMetadataBatchResponse, error) {
+	if req == nil || len(req.MovieIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request or more than %d movie ids", maxBatchSize)
+	}
+
+	ms, err := h.svc.GetMetadataBatch(ctx, req.MovieIds)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	res := &rpc.GetMetadataBatchResponse{
+		Metadata: make([]*rpc.Metadata, 0, len(ms)),
+	}
+	for _, m := range ms {
+		res.Metadata = append(res.Metadata, model.MetadataToProto(m))
+	}
+	return res, nil
+}
+
 // PutMetadata insert a movie metadata.
 func (h *Handler) PutMetadata(ctx context.Context, req *rpc.PutMetadataRequest) (*rpc.PutMetadataResponse, error) {
 	if req == nil || req.Metadata.MovieId == "" {
@@ -7,3 +7,6 @@ type Metadata struct {
 	Description string `json:"description"`
 	Director    string `json:"director"`
 }
+
+// MetadataAggregateType is the outbox aggregate type of movie metadata events.
+const MetadataAggregateType = "movie"
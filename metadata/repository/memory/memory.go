@@ -2,24 +2,41 @@ package memory
 
 import (
 	"context"
+	"main/audit"
+	auditmemory "main/audit/memory"
 	"main/metadata/model"
 	"main/metadata/repository"
+	outboxmemory "main/outbox/memory"
 	"sync"
 )
 
 // Repository defines a memory movie metadata repository.
 type Repository struct {
 	sync.RWMutex
-	data map[string]*model.Metadata
+	data   map[string]*model.Metadata
+	outbox *outboxmemory.Store
+	audit  *auditmemory.Store
 }
 
 // New creates a new memory repository.
 func New() *Repository {
 	return &Repository{
-		data: map[string]*model.Metadata{},
+		data:   map[string]*model.Metadata{},
+		outbox: outboxmemory.New(),
+		audit:  auditmemory.New(),
 	}
 }
 
+// Outbox returns the outbox the change events of written metadata are recorded in.
+func (r *Repository) Outbox() *outboxmemory.Store {
+	return r.outbox
+}
+
+// Audit returns the store the audit records of written metadata are appended to.
+func (r *Repository) Audit() *auditmemory.Store {
+	return r.audit
+}
+
 // Get retrieves movie metadata for by movie id.
 func (r *Repository) Get(_ context.Context, id string) (*model.Metadata, error) {
 	r.RLock()
@@ -32,11 +49,34 @@ func (r *Repository) Get(_ context.Context, id string) (*model.Metadata, error)
 	return m, nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(_ context.Context, id string, metadata *model.Metadata) error {
+// GetBatch retrieves the movie metadata of the given movie ids, skipping the movies without metadata.
+func (r *Repository) GetBatch(_ context.Context, ids []string) ([]*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	res := make([]*model.Metadata, 0, len(ids))
+	for _, id := range ids {
+		if m, ok := r.data[id]; ok {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// Put adds movie metadata for a given movie id, records its change events in the outbox and appends its audit record
+// if not nil.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata, record *audit.Record) error {
 	r.Lock()
 	defer r.Unlock()
 
+	events, err := repository.ChangeEvents(id, metadata)
+	if err != nil {
+		return err
+	}
 	r.data[id] = metadata
+	r.outbox.Add(events...)
+	if record != nil {
+		return r.audit.Append(ctx, record)
+	}
 	return nil
 }
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"main/metadata/model"
+	"main/outbox"
+	eventsv1 "main/rpc/events/v1"
+)
+
+// EventSource is the CloudEvents source of the change events published by the metadata service.
+const EventSource = "/metadata"
+
+// ChangeEvents builds the outbox events recorded when movie metadata is written.
+func ChangeEvents(id string, metadata *model.Metadata) ([]outbox.Event, error) {
+	event, err := outbox.NewEvent(EventSource, model.MetadataAggregateType, id, &eventsv1.MetadataChanged{
+		MovieId:     id,
+		Title:       metadata.Title,
+		Description: metadata.Description,
+		Director:    metadata.Director,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []outbox.Event{event}, nil
+}
@@ -2,9 +2,12 @@ package postgres
 
 import (
 	"context"
+	"main/audit"
+	auditpg "main/audit/postgres"
 	"main/database/db"
 	"main/metadata/model"
 	"main/metadata/repository"
+	outboxpg "main/outbox/postgres"
 
 	"github.com/jackc/pgx/v5"
 	"go.opentelemetry.io/otel"
@@ -14,19 +17,22 @@ const tracerID = "metadata-repository-postgres"
 
 // Repository defines a PostgreSQL-based movie metadata repository.
 type Repository struct {
-	db db.Store
+	db    db.Store
+	audit *auditpg.Store
 }
 
-// New creates a new PostgreSQL-based repository.
-func New(store db.Store) *Repository {
+// New creates a new PostgreSQL-based repository, appending the audit records of the writes to the audit store.
+// The audit store may be nil if the writes are not audited.
+func New(store db.Store, auditStore *auditpg.Store) *Repository {
 	return &Repository{
-		db: store,
+		db:    store,
+		audit: auditStore,
 	}
 }
 
 // Get retrieves movie metadata for by movie id.
 func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	_, span := otel.Tracer(tracerID).Start(ctx, "Repository/GET")
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/GET")
 	defer span.End()
 
 	movie, err := r.db.GetMovie(ctx, id)
@@ -40,20 +46,67 @@ func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error
 		ID:          movie.ID,
 		Title:       movie.Title,
 		Description: movie.Description,
-		Director:    movie.Description,
+		Director:    movie.Director,
 	}, nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
-	_, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT")
+// GetBatch retrieves the movie metadata of the given movie ids, skipping the movies without metadata.
+func (r *Repository) GetBatch(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/GET_BATCH")
 	defer span.End()
 
-	_, err := r.db.CreateMovie(ctx, &db.CreateMovieParams{
-		ID:          id,
-		Title:       metadata.Title,
-		Description: metadata.Description,
-		Director:    metadata.Director,
+	movies, err := r.db.ListMoviesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.Metadata, 0, len(movies))
+	for _, movie := range movies {
+		res = append(res, &model.Metadata{
+			ID:          movie.ID,
+			Title:       movie.Title,
+			Description: movie.Description,
+			Director:    movie.Director,
+		})
+	}
+	return res, nil
+}
+
+// Put adds or replaces movie metadata for a given movie id, records its change events in the outbox and appends its audit record,
+// if not nil, in the same transaction. The id of the record is set once committed.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata, record *audit.Record) error {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT")
+	defer span.End()
+
+	auditParams, err := r.audit.TxParams(record)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.PutMovieTx(ctx, db.PutMovieTxParams{
+		Movie: db.UpsertMovieParams{
+			ID:          id,
+			Title:       metadata.Title,
+			Description: metadata.Description,
+			Director:    metadata.Director,
+		},
+		AfterPut: func(movie *db.Movie) ([]*db.CreateOutboxEventParams, error) {
+			events, err := repository.ChangeEvents(movie.ID, &model.Metadata{
+				ID:          movie.ID,
+				Title:       movie.Title,
+				Description: movie.Description,
+				Director:    movie.Director,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return outboxpg.Params(events), nil
+		},
+		Audit: auditParams,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		record.ID = result.AuditRecord.ID
+	}
+	return nil
 }
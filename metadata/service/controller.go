@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"main/audit"
 	"main/metadata/model"
 	"main/metadata/repository"
 )
@@ -12,18 +13,22 @@ var ErrNotFound = errors.New("not found")
 
 type metadataRepository interface {
 	Get(ctx context.Context, id string) (*model.Metadata, error)
-	Put(ctx context.Context, id string, metadata *model.Metadata) error
+	GetBatch(ctx context.Context, ids []string) ([]*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata, record *audit.Record) error
 }
 
 // MetadataService defines a metadata service controller.
 type MetadataService struct {
-	repo metadataRepository
+	repo    metadataRepository
+	auditor *audit.Logger
 }
 
-// New creates a metadata service controller.
-func New(repo metadataRepository) *MetadataService {
+// New creates a metadata service controller. The metadata writes are audited with the auditor, if not nil, their
+// records being stored with them.
+func New(repo metadataRepository, auditor *audit.Logger) *MetadataService {
 	return &MetadataService{
-		repo: repo,
+		repo:    repo,
+		auditor: auditor,
 	}
 }
 
@@ -35,6 +40,27 @@ func (c *MetadataService) GetMetadata(ctx context.Context, id string) (*model.Me
 	return res, err
 }
 
+// GetMetadataBatch returns the metadata of the given movies, skipping the movies without metadata.
+func (c *MetadataService) GetMetadataBatch(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	return c.repo.GetBatch(ctx, ids)
+}
+
+// PutMetadata writes the metadata of a movie and audits the change from its previous metadata.
 func (c *MetadataService) PutMetadata(ctx context.Context, id string, metadata *model.Metadata) error {
-	return c.repo.Put(ctx, id, metadata)
+	var before *model.Metadata
+	if c.auditor != nil {
+		var err error
+		before, err = c.repo.Get(ctx, id)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+
+	record := c.auditor.NewRecord(ctx, "PutMetadata", model.MetadataAggregateType, id, before, metadata)
+	if err := c.repo.Put(ctx, id, metadata, record); err != nil {
+		c.auditor.Failed(ctx, record, err)
+		return err
+	}
+	c.auditor.Committed(record)
+	return nil
 }
@@ -1,15 +1,32 @@
 package testutil
 
 import (
+	"context"
+	"log/slog"
+	"main/config"
+	"main/eventbus"
 	grpchandler "main/metadata/handler/grpc"
+	"main/metadata/repository"
 	"main/metadata/repository/memory"
 	"main/metadata/service"
+	"main/outbox"
 	"main/rpc"
 )
 
 // NewTestMetadataGRPCServer creates a new metadata gRPC server to be used in tests.
-func NewTestMetadataGRPCServer() rpc.MetadataServiceServer {
+// It publishes metadata change events to the given bus until the context is cancelled.
+func NewTestMetadataGRPCServer(ctx context.Context, cfg *config.Metadata, bus eventbus.Bus) rpc.MetadataServiceServer {
 	r := memory.New()
-	svc := service.New(r)
+	svc := service.New(r, nil)
+	publisher, err := bus.Publisher(cfg.MetadataEventsTopic)
+	if err != nil {
+		slog.Error("failed to create metadata events publisher:", slog.String("error", err.Error()))
+	} else {
+		relay := outbox.NewRelay(r.Outbox(), publisher, cfg.OutboxRelayConfig(repository.EventSource, cfg.MetadataEventsTopic))
+		go func() {
+			defer publisher.Close()
+			relay.Run(ctx)
+		}()
+	}
 	return grpchandler.New(svc)
 }
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Types of gRPC methods, as labelled in the request metrics.
+const (
+	grpcTypeUnary        = "unary"
+	grpcTypeClientStream = "client_stream"
+	grpcTypeServerStream = "server_stream"
+	grpcTypeBidiStream   = "bidi_stream"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor recording the metrics of unary requests.
+func (m *ServerMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGRPC(ctx, info.FullMethod, grpcTypeUnary, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor recording the metrics of streaming requests.
+func (m *ServerMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeGRPC(ss.Context(), info.FullMethod, streamType(info), err, time.Since(start))
+		return err
+	}
+}
+
+func (m *ServerMetrics) observeGRPC(ctx context.Context, fullMethod, grpcType string, err error, d time.Duration) {
+	service, method := splitMethod(fullMethod)
+	code := status.Code(err).String()
+	observe(ctx,
+		m.grpcHandled.WithLabelValues(service, method, grpcType, code),
+		m.grpcDuration.WithLabelValues(service, method, grpcType, code),
+		d.Seconds(),
+	)
+}
+
+// splitMethod splits a full gRPC method name in the /package.Service/Method form into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return grpcTypeBidiStream
+	case info.IsClientStream:
+		return grpcTypeClientStream
+	default:
+		return grpcTypeServerStream
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServerMetrics records the rate, errors and duration (RED) of the requests served by the gRPC and HTTP endpoints
+// of a service. Observations carry the trace id of their request as an exemplar when the request is sampled.
+type ServerMetrics struct {
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+	httpHandled  *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+// NewServerMetrics creates the request metrics of a service.
+func NewServerMetrics() *ServerMetrics {
+	return &ServerMetrics{
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "handled_total",
+			Help:      "Number of gRPC requests completed, by method and status code.",
+		}, []string{"grpc_service", "grpc_method", "grpc_type", "grpc_code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "handling_seconds",
+			Help:      "Time taken to handle gRPC requests, by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"grpc_service", "grpc_method", "grpc_type", "grpc_code"}),
+		httpHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "http",
+			Subsystem: "server",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests completed, by handler, method and status code.",
+		}, []string{"handler", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "http",
+			Subsystem: "server",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by handler, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler", "method", "code"}),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the request metrics.
+func (m *ServerMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.grpcHandled, m.grpcDuration, m.httpHandled, m.httpDuration}
+}
+
+// observe counts a request and records its duration, with the trace id of ctx as exemplar if it is sampled.
+func observe(ctx context.Context, counter prometheus.Counter, histogram prometheus.Observer, seconds float64) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsSampled() {
+		counter.Inc()
+		histogram.Observe(seconds)
+		return
+	}
+
+	exemplar := prometheus.Labels{"trace_id": spanCtx.TraceID().String()}
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok {
+		adder.AddWithExemplar(1, exemplar)
+	} else {
+		counter.Inc()
+	}
+	if observer, ok := histogram.(prometheus.ExemplarObserver); ok {
+		observer.ObserveWithExemplar(seconds, exemplar)
+	} else {
+		histogram.Observe(seconds)
+	}
+}
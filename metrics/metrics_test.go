@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := NewServerMetrics()
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/MetadataService/GetMetadata"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	require.Error(t, err)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(m.grpcHandled.WithLabelValues("MetadataService", "GetMetadata", "unary", "OK")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.grpcHandled.WithLabelValues("MetadataService", "GetMetadata", "unary", "NotFound")))
+	require.Equal(t, 2, testutil.CollectAndCount(m.grpcDuration))
+}
+
+func TestMiddleware(t *testing.T) {
+	m := NewServerMetrics()
+	handler := m.Middleware("/rating", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/rating", nil))
+
+	require.Equal(t, float64(1), testutil.ToFloat64(m.httpHandled.WithLabelValues("/rating", http.MethodGet, "404")))
+}
@@ -2,154 +2,91 @@ package main
 
 import (
 	"context"
-	"flag"
 	"fmt"
 	"log/slog"
-	"main/discovery"
-	"main/discovery/consul"
+	"main/app"
+	"main/auth"
+	"main/config"
+	"main/loadshed"
+	"main/movie/gateway"
 	metadatagateway "main/movie/gateway/metadata/grpc"
 	ratinggateway "main/movie/gateway/rating/grpc"
 	grpchandler "main/movie/handler/grpc"
 	"main/movie/service"
+	"main/ratelimit"
 	"main/rpc"
-	"main/tracing"
-	"main/util"
-	"net"
-	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"time"
 
-	// "github.com/grpc-ecosystem/go-grpc-middleware/ratelimit"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 )
 
 const (
-	serviceName = "movie"
-	limit       = 100
-	burst       = 100
+	serviceName    = "movie"
+	limit          = 100
+	burst          = 100
+	gatewayTimeout = 5 * time.Second
 )
 
 func main() {
-	var config string
-	flag.StringVar(&config, "config", ".env", "Configuration path")
-	flag.Parse()
-	cfg := util.LoadConfig(config)
+	var cfg config.Movie
+	config.MustLoad(&cfg)
 
-	if cfg.Environment == "dev" {
-		var logger = slog.New(slog.NewTextHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
-	} else {
-		var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
+	if err := run(&cfg); err != nil {
+		slog.Error("movie service failed:", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
+}
 
-	// prometheus
-	reg := prometheus.NewRegistry()
-	counter := promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: serviceName,
-		Name:      "service_started",
-	})
-
-	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
-	go func() {
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.MovieMetricsPort), nil); err != nil {
-			slog.Error("failed to start metrics handler:", slog.String("error", err.Error()))
-			return
-		}
-	}()
-
-	reg.MustRegister(counter)
-	defer reg.Unregister(counter)
-	counter.Inc()
-
-	slog.Info("Starting the movie service on port", slog.Int("port", cfg.MoviePort))
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := tracing.NewJaegerProvider(cfg.JaegerURL, serviceName)
+func run(cfg *config.Movie) error {
+	source, err := cfg.DynamicSource(serviceName)
 	if err != nil {
-		slog.Error("failed to initialize Jaeger provider:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to create dynamic configuration source: %w", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			slog.Error("failed to shutdown tracing provider:", slog.String("error", err.Error()))
-			return
-		}
-	}()
 
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	registry, err := consul.NewRegistry(cfg.ConsulURL)
+	a, err := app.New(serviceName,
+		app.WithEnvironment(cfg.Environment),
+		app.WithMetrics(cfg.MovieMetricsPort),
+		app.WithTracing(cfg.JaegerURL),
+		app.WithDiscovery(cfg.ConsulURL),
+		app.WithDynamicConfig(source),
+		app.WithConcurrencyLimit(cfg.ConcurrencyConfig()),
+		app.WithAuth(cfg.AuthConfig()),
+		app.WithTLS(cfg.TLSConfig()),
+		app.WithRateLimit(ratelimit.Quota{Limit: limit, Burst: burst}),
+		app.WithAuthorization(auth.Policy{auth.AdminMethods: {auth.RoleOperator}}),
+		app.WithGRPC(fmt.Sprintf("%s:%d", cfg.Host, cfg.MoviePort)),
+		app.WithShutdownTimeout(cfg.ShutdownTimeout),
+	)
 	if err != nil {
-		slog.Error("failed to connect consul registry:", slog.String("error", err.Error()))
-		return
+		return err
 	}
 
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	hostPort := fmt.Sprintf("%s:%d", cfg.Host, cfg.MoviePort)
-	if err := registry.Register(ctx, instanceID, serviceName, hostPort); err != nil {
-		slog.Error("failed to register movie service:", slog.String("error", err.Error()))
-		return
-	}
-
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				slog.Info("Failed to report healthy state:", slog.String("error", err.Error()))
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
-	svc := service.New(ratingGateway, metadataGateway)
+	dynamic := a.Dynamic()
+	timeout := dynamic.Duration("GATEWAY_TIMEOUT", gatewayTimeout)
+	dynamic.Feature(service.FeatureRatings, true)
+
+	metadataLimiter := loadshed.New("metadata_gateway", cfg.ConcurrencyConfig())
+	ratingLimiter := loadshed.New("rating_gateway", cfg.ConcurrencyConfig())
+	a.Metrics().MustRegister(metadataLimiter.Collectors()...)
+	a.Metrics().MustRegister(ratingLimiter.Collectors()...)
+	metadataGateway := metadatagateway.New(a.Discovery(), gateway.Timeout(timeout.Load),
+		a.TLS().DialOption("metadata"),
+		grpc.WithChainUnaryInterceptor(metadataLimiter.UnaryClientInterceptor(), auth.UnaryClientInterceptor()))
+	ratingGateway := ratinggateway.New(a.Discovery(), gateway.Timeout(timeout.Load),
+		a.TLS().DialOption("rating"),
+		grpc.WithChainUnaryInterceptor(ratingLimiter.UnaryClientInterceptor(), auth.UnaryClientInterceptor()))
+	svc := service.New(ratingGateway, metadataGateway, dynamic)
 	h := grpchandler.New(svc)
 
-	listener, err := net.Listen("tcp", hostPort)
-	if err != nil {
-		slog.Error("failed to listen on:", slog.String("host", hostPort), slog.String("error", err.Error()))
-		return
+	for _, downstream := range []string{"metadata", "rating"} {
+		downstream := downstream
+		a.CheckDegraded(downstream+" registry", func(ctx context.Context) error {
+			_, err := a.Discovery().ServiceAddresses(ctx, downstream)
+			return err
+		})
 	}
 
-	// l := util.NewLimiter(limit, burst)
-	server := grpc.NewServer(
-		// grpc.UnaryInterceptor(ratelimit.UnaryServerInterceptor(l)),
-		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-	)
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		slog.Info("Received signal", s)
-		slog.Info("attempting graceful stutdown")
-		server.GracefulStop()
-		slog.Info("Gracefully stopped the gRPC server")
-	}()
-
-	reflection.Register(server)
-	rpc.RegisterMovieServiceServer(server, h)
-	if err := server.Serve(listener); err != nil {
-		slog.Error("Failed to start gRPC server:", slog.String("error", err.Error()))
-		return
-	}
+	rpc.RegisterMovieServiceServer(a.GRPCServer(), h)
+	return a.Run(context.Background())
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"main/discovery"
 	"main/metadata/model"
+	"main/movie/gateway"
 	"main/rpc"
 	"main/util"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -14,18 +16,26 @@ import (
 // Gateway defines a movie metadata gRPC gateway.
 type Gateway struct {
 	registry discovery.Registry
+	timeout  gateway.Timeout
+	opts     []grpc.DialOption
 }
 
-// New creates a new gRPC gateway for a movie metadata service.
-func New(registry discovery.Registry) *Gateway {
+// New creates a new gRPC gateway for a movie metadata service. Every call is bounded by the current timeout, if any,
+// and uses the dial options, such as client interceptors.
+func New(registry discovery.Registry, timeout gateway.Timeout, opts ...grpc.DialOption) *Gateway {
 	return &Gateway{
 		registry: registry,
+		timeout:  timeout,
+		opts:     opts,
 	}
 }
 
 // Get returns movie metadata by a movie id.
 func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	conn, err := util.ServiceConnection(ctx, "metadata", g.registry)
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "metadata", g.registry, g.opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +53,9 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 			if shouldRetry(err) {
 				continue
 			}
+			if status.Code(err) == codes.NotFound {
+				return nil, gateway.ErrNotFound
+			}
 			return nil, err
 		}
 		return model.MetadataFromProto(resp.Metadata), nil
@@ -51,6 +64,41 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	return nil, err
 }
 
+// GetBatch returns the metadata of the given movies by movie id, leaving out the movies without metadata.
+func (g *Gateway) GetBatch(ctx context.Context, ids []string) (map[string]*model.Metadata, error) {
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "metadata", g.registry, g.opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := rpc.NewMetadataServiceClient(conn)
+
+	var resp *rpc.GetMetadataBatchResponse
+	const maxRetries = 5
+	for i := 0; i < maxRetries; i++ {
+		resp, err = client.GetMetadataBatch(ctx, &rpc.GetMetadataBatchRequest{
+			MovieIds: ids,
+		})
+		if err != nil {
+			if shouldRetry(err) {
+				continue
+			}
+			return nil, err
+		}
+		res := make(map[string]*model.Metadata, len(resp.Metadata))
+		for _, m := range resp.Metadata {
+			res[m.MovieId] = model.MetadataFromProto(m)
+		}
+		return res, nil
+	}
+
+	return nil, err
+}
+
 func shouldRetry(err error) bool {
 	e, ok := status.FromError(err)
 	if !ok {
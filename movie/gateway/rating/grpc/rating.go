@@ -3,26 +3,37 @@ package grpc
 import (
 	"context"
 	"main/discovery"
+	"main/movie/gateway"
 	"main/rating/model"
 	"main/rpc"
 	"main/util"
+
+	"google.golang.org/grpc"
 )
 
 // Gateway defines an gRPC gateway for a rating service.
 type Gateway struct {
 	registry discovery.Registry
+	timeout  gateway.Timeout
+	opts     []grpc.DialOption
 }
 
-// New creates a new gRPC gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
+// New creates a new gRPC gateway for a rating service. Every call is bounded by the current timeout, if any,
+// and uses the dial options, such as client interceptors.
+func New(registry discovery.Registry, timeout gateway.Timeout, opts ...grpc.DialOption) *Gateway {
 	return &Gateway{
 		registry: registry,
+		timeout:  timeout,
+		opts:     opts,
 	}
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are not ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	conn, err := util.ServiceConnection(ctx, "rating", g.registry)
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "rating", g.registry, g.opts...)
 	if err != nil {
 		return 0, err
 	}
@@ -42,7 +53,10 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 
 // PutRating writes a rating.
 func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	conn, err := util.ServiceConnection(ctx, "rating", g.registry)
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "rating", g.registry, g.opts...)
 	if err != nil {
 		return err
 	}
@@ -58,3 +72,66 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 
 	return err
 }
+
+// ListTopRated returns a page of records ranked by their average rating, along with the next page token.
+func (g *Gateway) ListTopRated(ctx context.Context, recordType model.RecordType, minCount int64, pageSize int32, pageToken string) ([]model.RecordAggregate, string, error) {
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "rating", g.registry, g.opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+
+	client := rpc.NewRatingServiceClient(conn)
+	resp, err := client.ListTopRated(ctx, &rpc.ListTopRatedRequest{
+		RecordType:     string(recordType),
+		MinRatingCount: minCount,
+		PageSize:       pageSize,
+		PageToken:      pageToken,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rankingsFromProto(resp.Rankings), resp.NextPageToken, nil
+}
+
+// ListTrending returns a page of records ranked by their time-decayed rating velocity, along with the next page token.
+func (g *Gateway) ListTrending(ctx context.Context, recordType model.RecordType, pageSize int32, pageToken string) ([]model.RecordAggregate, string, error) {
+	ctx, cancel := g.timeout.WithTimeout(ctx)
+	defer cancel()
+
+	conn, err := util.ServiceConnection(ctx, "rating", g.registry, g.opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+
+	client := rpc.NewRatingServiceClient(conn)
+	resp, err := client.ListTrending(ctx, &rpc.ListTrendingRequest{
+		RecordType: string(recordType),
+		PageSize:   pageSize,
+		PageToken:  pageToken,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rankingsFromProto(resp.Rankings), resp.NextPageToken, nil
+}
+
+func rankingsFromProto(rankings []*rpc.RecordRanking) []model.RecordAggregate {
+	res := make([]model.RecordAggregate, 0, len(rankings))
+	for _, r := range rankings {
+		res = append(res, model.RecordAggregate{
+			RecordID:      model.RecordID(r.RecordId),
+			RecordType:    model.RecordType(r.RecordType),
+			RatingCount:   r.RatingCount,
+			AverageValue:  r.RatingValue,
+			TrendingScore: r.TrendingScore,
+		})
+	}
+	return res
+}
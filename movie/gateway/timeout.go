@@ -0,0 +1,23 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout returns the current timeout of the calls of a gateway. It may change between calls,
+// such as when it is a dynamic setting.
+type Timeout func() time.Duration
+
+// WithTimeout returns a copy of ctx cancelled once the timeout elapses.
+// A nil timeout, or one that is not positive, leaves the deadline of ctx as it is.
+func (t Timeout) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t == nil {
+		return context.WithCancel(ctx)
+	}
+	d := t()
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
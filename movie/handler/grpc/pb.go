@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"main/metadata/model"
+	moviemodel "main/movie/model"
 	"main/movie/service"
 	"main/rpc"
 
@@ -46,3 +47,52 @@ func (h *Handler) GetMovieDetails(ctx context.Context, req *rpc.GetMovieDetailsR
 		},
 	}, nil
 }
+
+// ListTopRatedMovies returns the highest rated movies with their details.
+func (h *Handler) ListTopRatedMovies(ctx context.Context, req *rpc.ListTopRatedMoviesRequest) (*rpc.ListTopRatedMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request")
+	}
+
+	movies, next, err := h.svc.ListTopRated(ctx, req.MinRatingCount, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, status.Errorf(status.Code(err), err.Error())
+	}
+
+	return &rpc.ListTopRatedMoviesResponse{
+		Movies:        rankedMoviesToProto(movies),
+		NextPageToken: next,
+	}, nil
+}
+
+// ListTrendingMovies returns the currently trending movies with their details.
+func (h *Handler) ListTrendingMovies(ctx context.Context, req *rpc.ListTrendingMoviesRequest) (*rpc.ListTrendingMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request")
+	}
+
+	movies, next, err := h.svc.ListTrending(ctx, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, status.Errorf(status.Code(err), err.Error())
+	}
+
+	return &rpc.ListTrendingMoviesResponse{
+		Movies:        rankedMoviesToProto(movies),
+		NextPageToken: next,
+	}, nil
+}
+
+func rankedMoviesToProto(movies []*moviemodel.RankedMovie) []*rpc.RankedMovie {
+	res := make([]*rpc.RankedMovie, 0, len(movies))
+	for _, m := range movies {
+		res = append(res, &rpc.RankedMovie{
+			MovieDetails: &rpc.MovieDetails{
+				Rating:   m.Rating,
+				Metadata: model.MetadataToProto(&m.Metadata),
+			},
+			RatingCount:   m.RatingCount,
+			TrendingScore: m.TrendingScore,
+		})
+	}
+	return res
+}
@@ -7,3 +7,10 @@ type MovieDetails struct {
 	Rating   float64        `json:"rating,omitempty"`
 	Metadata model.Metadata `json:"metadata"`
 }
+
+// RankedMovie includes movie details along with the statistics it was ranked by.
+type RankedMovie struct {
+	MovieDetails
+	RatingCount   int64   `json:"ratingCount"`
+	TrendingScore float64 `json:"trendingScore,omitempty"`
+}
@@ -15,26 +15,44 @@ var ErrNotFound = errors.New("movie metadata not found")
 type ratingGateway interface {
 	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
 	PutRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, rating *ratingmodel.Rating) error
+	ListTopRated(ctx context.Context, recordType ratingmodel.RecordType, minCount int64, pageSize int32, pageToken string) ([]ratingmodel.RecordAggregate, string, error)
+	ListTrending(ctx context.Context, recordType ratingmodel.RecordType, pageSize int32, pageToken string) ([]ratingmodel.RecordAggregate, string, error)
 }
 
 type metadataGateway interface {
 	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	GetBatch(ctx context.Context, ids []string) (map[string]*metadatamodel.Metadata, error)
+}
+
+// FeatureRatings is the feature adding the aggregated rating to the movie details.
+const FeatureRatings = "MOVIE_RATINGS"
+
+// Features reports which features of the service are enabled.
+type Features interface {
+	Enabled(feature string) bool
 }
 
 // MovieService defines a movie service controller.
 type MovieService struct {
 	ratingGateway   ratingGateway
 	metadataGateway metadataGateway
+	features        Features
 }
 
-// New creates a new movie service controller.
-func New(ratingGateway ratingGateway, metadataGateway metadataGateway) *MovieService {
+// New creates a new movie service controller. Without features, all of them are enabled.
+func New(ratingGateway ratingGateway, metadataGateway metadataGateway, features Features) *MovieService {
 	return &MovieService{
 		ratingGateway:   ratingGateway,
 		metadataGateway: metadataGateway,
+		features:        features,
 	}
 }
 
+// enabled reports whether a feature is enabled.
+func (c *MovieService) enabled(feature string) bool {
+	return c.features == nil || c.features.Enabled(feature)
+}
+
 // Get returns the movie details including the aggregated rating and movie metadata.
 func (c *MovieService) Get(ctx context.Context, id string) (*model.MovieDetails, error) {
 	metadata, err := c.metadataGateway.Get(ctx, id)
@@ -47,6 +65,9 @@ func (c *MovieService) Get(ctx context.Context, id string) (*model.MovieDetails,
 	details := &model.MovieDetails{
 		Metadata: *metadata,
 	}
+	if !c.enabled(FeatureRatings) {
+		return details, nil
+	}
 
 	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie)
 	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
@@ -59,3 +80,65 @@ func (c *MovieService) Get(ctx context.Context, id string) (*model.MovieDetails,
 
 	return details, nil
 }
+
+// ListTopRated returns a page of the highest rated movies with at least minCount ratings, joined with their metadata.
+func (c *MovieService) ListTopRated(ctx context.Context, minCount int64, pageSize int32, pageToken string) ([]*model.RankedMovie, string, error) {
+	aggs, next, err := c.ratingGateway.ListTopRated(ctx, ratingmodel.RecordTypeMovie, minCount, pageSize, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	movies, err := c.rankedMovies(ctx, aggs)
+	if err != nil {
+		return nil, "", err
+	}
+	return movies, next, nil
+}
+
+// ListTrending returns a page of the currently trending movies, joined with their metadata.
+func (c *MovieService) ListTrending(ctx context.Context, pageSize int32, pageToken string) ([]*model.RankedMovie, string, error) {
+	aggs, next, err := c.ratingGateway.ListTrending(ctx, ratingmodel.RecordTypeMovie, pageSize, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	movies, err := c.rankedMovies(ctx, aggs)
+	if err != nil {
+		return nil, "", err
+	}
+	return movies, next, nil
+}
+
+// rankedMovies joins the ranked records with their movie metadata, fetched in a single call. Movies without
+// metadata keep their rank with only their id, so pages are not shorter than the rankings.
+func (c *MovieService) rankedMovies(ctx context.Context, aggs []ratingmodel.RecordAggregate) ([]*model.RankedMovie, error) {
+	if len(aggs) == 0 {
+		return []*model.RankedMovie{}, nil
+	}
+
+	ids := make([]string, 0, len(aggs))
+	for _, agg := range aggs {
+		ids = append(ids, string(agg.RecordID))
+	}
+	metadata, err := c.metadataGateway.GetBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.RankedMovie, 0, len(aggs))
+	for _, agg := range aggs {
+		m, ok := metadata[string(agg.RecordID)]
+		if !ok {
+			m = &metadatamodel.Metadata{ID: string(agg.RecordID)}
+		}
+		res = append(res, &model.RankedMovie{
+			MovieDetails: model.MovieDetails{
+				Rating:   agg.AverageValue,
+				Metadata: *m,
+			},
+			RatingCount:   agg.RatingCount,
+			TrendingScore: agg.TrendingScore,
+		})
+	}
+	return res, nil
+}
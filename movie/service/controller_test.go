@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	metadatamodel "main/metadata/model"
+	ratingmodel "main/rating/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRatingGateway struct {
+	ratingGateway
+	aggs []ratingmodel.RecordAggregate
+	next string
+}
+
+func (g *fakeRatingGateway) ListTopRated(_ context.Context, _ ratingmodel.RecordType, _ int64, _ int32, _ string) ([]ratingmodel.RecordAggregate, string, error) {
+	return g.aggs, g.next, nil
+}
+
+type fakeMetadataGateway struct {
+	metadataGateway
+	data    map[string]*metadatamodel.Metadata
+	batches [][]string
+}
+
+func (g *fakeMetadataGateway) GetBatch(_ context.Context, ids []string) (map[string]*metadatamodel.Metadata, error) {
+	g.batches = append(g.batches, ids)
+	res := map[string]*metadatamodel.Metadata{}
+	for _, id := range ids {
+		if m, ok := g.data[id]; ok {
+			res[id] = m
+		}
+	}
+	return res, nil
+}
+
+func TestListTopRated(t *testing.T) {
+	ratings := &fakeRatingGateway{
+		aggs: []ratingmodel.RecordAggregate{
+			{RecordID: "alien", AverageValue: 5, RatingCount: 2},
+			{RecordID: "unknown", AverageValue: 4.5, RatingCount: 2},
+			{RecordID: "heat", AverageValue: 4, RatingCount: 3},
+		},
+		next: "3",
+	}
+	metadata := &fakeMetadataGateway{data: map[string]*metadatamodel.Metadata{
+		"alien": {ID: "alien", Title: "Alien"},
+		"heat":  {ID: "heat", Title: "Heat"},
+	}}
+	svc := New(ratings, metadata, nil)
+
+	movies, next, err := svc.ListTopRated(context.Background(), 2, 3, "")
+	require.NoError(t, err)
+	require.Equal(t, "3", next)
+	require.Equal(t, [][]string{{"alien", "unknown", "heat"}}, metadata.batches)
+
+	// Movies without metadata keep their rank, so the page is full.
+	require.Len(t, movies, 3)
+	require.Equal(t, "Alien", movies[0].Metadata.Title)
+	require.Equal(t, metadatamodel.Metadata{ID: "unknown"}, movies[1].Metadata)
+	require.Equal(t, 4.5, movies[1].Rating)
+	require.Equal(t, "Heat", movies[2].Metadata.Title)
+	require.Equal(t, int64(3), movies[2].RatingCount)
+}
+
+func TestListTopRatedEmptyPage(t *testing.T) {
+	metadata := &fakeMetadataGateway{}
+	svc := New(&fakeRatingGateway{}, metadata, nil)
+
+	movies, next, err := svc.ListTopRated(context.Background(), 0, 10, "")
+	require.NoError(t, err)
+	require.Empty(t, movies)
+	require.Empty(t, next)
+	require.Empty(t, metadata.batches)
+}
@@ -11,8 +11,8 @@ import (
 
 // NewTestMovieGRPCServer creates a new movie gRPC server to be used in tests.
 func NewTestMovieGRPCServer(registry discovery.Registry) rpc.MovieServiceServer {
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
-	ctrl := service.New(ratingGateway, metadataGateway)
+	metadataGateway := metadatagateway.New(registry, nil)
+	ratingGateway := ratinggateway.New(registry, nil)
+	ctrl := service.New(ratingGateway, metadataGateway, nil)
 	return grpchandler.New(ctrl)
 }
@@ -0,0 +1,25 @@
+package mtls
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerOption returns the gRPC server option serving connections with mutual TLS, or without TLS for a nil
+// reloader.
+func (r *Reloader) ServerOption() grpc.ServerOption {
+	if r == nil {
+		return grpc.Creds(insecure.NewCredentials())
+	}
+	return grpc.Creds(credentials.NewTLS(r.ServerConfig()))
+}
+
+// DialOption returns the gRPC dial option connecting to the named service with mutual TLS, or without TLS for a nil
+// reloader.
+func (r *Reloader) DialOption(serviceName string) grpc.DialOption {
+	if r == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(r.ClientConfig(serviceName)))
+}
@@ -0,0 +1,245 @@
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Results of a reload of the certificates, as labelled in the reload metrics.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Config locates the PEM files of the certificates of a service.
+type Config struct {
+	// CAPath is the path of the certificates of the authorities trusted to sign the certificates of the peers.
+	CAPath string
+	// CertPath and KeyPath are the paths of the certificate of the service and of its private key. The certificate
+	// identifies the service to its clients and to the servers it calls, so it must be valid for both uses and
+	// carry the name of the service in its DNS subject alternative names.
+	CertPath string
+	KeyPath  string
+	// AllowedClients are the names of the services allowed to call the server, one of which must be in the DNS
+	// subject alternative names of the certificate of a client. Empty allows every client whose certificate is
+	// signed by a trusted authority.
+	AllowedClients []string
+	// ReloadInterval is how often the files are read for changes.
+	ReloadInterval time.Duration
+}
+
+// certificates are the certificates loaded from the files, along with the content they were parsed from.
+type certificates struct {
+	cert     *tls.Certificate
+	leaf     *x509.Certificate
+	roots    *x509.CertPool
+	contents [][]byte
+}
+
+// Reloader serves the certificates of a service, reloading them from disk when their files change so they can be
+// rotated without a restart. A nil reloader serves connections without TLS.
+type Reloader struct {
+	cfg     Config
+	current atomic.Pointer[certificates]
+
+	reloads *prometheus.CounterVec
+	expiry  prometheus.GaugeFunc
+}
+
+// New creates a reloader of the certificates of cfg, loading them once.
+func New(cfg Config) (*Reloader, error) {
+	certs, err := load(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		cfg: cfg,
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mtls",
+			Name:      "reloads_total",
+			Help:      "Number of reloads of changed certificate files, by result.",
+		}, []string{"result"}),
+	}
+	r.expiry = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "mtls",
+		Name:      "certificate_expiry_timestamp_seconds",
+		Help:      "Unix time the certificate of the service expires at.",
+	}, func() float64 {
+		return float64(r.current.Load().leaf.NotAfter.Unix())
+	})
+	r.current.Store(certs)
+	return r, nil
+}
+
+// Collectors returns the Prometheus collectors of the certificate metrics.
+func (r *Reloader) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{r.reloads, r.expiry}
+}
+
+// Run reloads the certificates every time the content of their files changes, until ctx is cancelled.
+// Files that fail to load are skipped, the previous certificates being kept until the files change again.
+func (r *Reloader) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		contents, err := readFiles(r.cfg)
+		if err != nil {
+			r.reloads.WithLabelValues(resultFailure).Inc()
+			slog.Error("failed to read certificates:", slog.String("error", err.Error()))
+			continue
+		}
+		if equalContents(contents, r.current.Load().contents) {
+			continue
+		}
+		certs, err := parse(contents)
+		if err != nil {
+			r.reloads.WithLabelValues(resultFailure).Inc()
+			slog.Error("failed to reload certificates:", slog.String("error", err.Error()))
+			continue
+		}
+		r.current.Store(certs)
+		r.reloads.WithLabelValues(resultSuccess).Inc()
+		slog.Info("Reloaded certificates", slog.Time("expiry", certs.leaf.NotAfter))
+	}
+}
+
+// ServerConfig returns the TLS configuration of a server requiring the clients to present a certificate signed by
+// a trusted authority and issued to one of the allowed clients. Every handshake uses the latest certificates.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certs := r.current.Load()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certs.cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    certs.roots,
+				VerifyConnection: func(cs tls.ConnectionState) error {
+					return r.verifyClient(cs)
+				},
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS configuration of a client of the named service. The server must present
+// a certificate signed by a trusted authority whose subject alternative names include the service name, since
+// the addresses of the registry do not identify the services. Every handshake uses the latest certificates.
+func (r *Reloader) ClientConfig(serviceName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.current.Load().cert, nil
+		},
+		// The default verification checks the address dialed, it is replaced by the one of the service name.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return r.verifyServer(cs, serviceName)
+		},
+	}
+}
+
+// verifyServer checks that the certificate of a server is signed by a trusted authority and issued to the service.
+func (r *Reloader) verifyServer(cs tls.ConnectionState, serviceName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         r.current.Load().roots,
+		Intermediates: intermediates,
+		DNSName:       serviceName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("invalid certificate of service %s: %w", serviceName, err)
+	}
+	return nil
+}
+
+// verifyClient checks that the verified certificate of a client is issued to one of the allowed clients.
+func (r *Reloader) verifyClient(cs tls.ConnectionState) error {
+	if len(r.cfg.AllowedClients) == 0 {
+		return nil
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("client presented no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	for _, name := range r.cfg.AllowedClients {
+		if leaf.VerifyHostname(name) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("client certificate issued to %v is not one of the allowed clients %v", leaf.DNSNames, r.cfg.AllowedClients)
+}
+
+// load reads and parses the certificates of cfg.
+func load(cfg Config) (*certificates, error) {
+	contents, err := readFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return parse(contents)
+}
+
+// readFiles returns the contents of the CA, certificate and key files.
+func readFiles(cfg Config) ([][]byte, error) {
+	contents := make([][]byte, 0, 3)
+	for _, path := range []string{cfg.CAPath, cfg.CertPath, cfg.KeyPath} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+// parse parses the contents of the CA, certificate and key files.
+func parse(contents [][]byte) (*certificates, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(contents[0]) {
+		return nil, errors.New("no CA certificate found")
+	}
+	cert, err := tls.X509KeyPair(contents[1], contents[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate or key: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	return &certificates{cert: &cert, leaf: leaf, roots: roots, contents: contents}, nil
+}
+
+func equalContents(a, b [][]byte) bool {
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
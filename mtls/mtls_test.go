@@ -0,0 +1,158 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes the CA, and a certificate of the service with its key, to dir.
+func (ca *testCA) issue(t *testing.T, dir, service string, notAfter time.Time) Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: service},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{service},
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	cfg := Config{
+		CAPath:         filepath.Join(dir, "ca.pem"),
+		CertPath:       filepath.Join(dir, service+".pem"),
+		KeyPath:        filepath.Join(dir, service+"-key.pem"),
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	require.NoError(t, os.WriteFile(cfg.CAPath, ca.pem, 0o644))
+	require.NoError(t, os.WriteFile(cfg.KeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.WriteFile(cfg.CertPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	return cfg
+}
+
+// handshake connects a client to a server and returns the error of the client handshake.
+func handshake(server *tls.Config, client *tls.Config) error {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	go tls.Server(serverConn, server).Handshake()
+	return tls.Client(clientConn, client).Handshake()
+}
+
+// serverHandshake connects a client to a server over TCP and returns the error of the server handshake. Unlike a
+// pipe, the connection buffers the alert of a server rejecting the certificate of a client.
+func serverHandshake(t *testing.T, server *tls.Config, client *tls.Config) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := tls.Dial("tcp", l.Addr().String(), client)
+		if err == nil {
+			conn.Read(make([]byte, 1))
+			conn.Close()
+		}
+	}()
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	return tls.Server(conn, server).Handshake()
+}
+
+func TestHandshake(t *testing.T) {
+	ca := newTestCA(t)
+	server, err := New(ca.issue(t, t.TempDir(), "rating", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	client, err := New(ca.issue(t, t.TempDir(), "movie", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+
+	require.NoError(t, handshake(server.ServerConfig(), client.ClientConfig("rating")))
+	require.ErrorContains(t, handshake(server.ServerConfig(), client.ClientConfig("metadata")), "invalid certificate of service metadata")
+
+	other, err := New(newTestCA(t).issue(t, t.TempDir(), "movie", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	require.Error(t, handshake(server.ServerConfig(), other.ClientConfig("rating")))
+}
+
+func TestHandshakeAllowedClients(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := ca.issue(t, t.TempDir(), "rating", time.Now().Add(time.Hour))
+	cfg.AllowedClients = []string{"movie"}
+	server, err := New(cfg)
+	require.NoError(t, err)
+	movie, err := New(ca.issue(t, t.TempDir(), "movie", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	metadata, err := New(ca.issue(t, t.TempDir(), "metadata", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+
+	require.NoError(t, serverHandshake(t, server.ServerConfig(), movie.ClientConfig("rating")))
+	require.ErrorContains(t, serverHandshake(t, server.ServerConfig(), metadata.ClientConfig("rating")), "not one of the allowed clients")
+}
+
+func TestReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	r, err := New(ca.issue(t, dir, "rating", expiry))
+	require.NoError(t, err)
+	require.Equal(t, expiry.Unix(), r.current.Load().leaf.NotAfter.Unix())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	// An invalid file keeps the previous certificates.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rating.pem"), []byte("garbage"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, expiry.Unix(), r.current.Load().leaf.NotAfter.Unix())
+
+	rotated := expiry.Add(time.Hour)
+	ca.issue(t, dir, "rating", rotated)
+	require.Eventually(t, func() bool {
+		return r.current.Load().leaf.NotAfter.Unix() == rotated.Unix()
+	}, time.Second, 10*time.Millisecond)
+}
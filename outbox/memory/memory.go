@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"main/outbox"
+	"sync"
+	"time"
+)
+
+// Store defines an in-memory outbox store.
+type Store struct {
+	sync.Mutex
+	nextID int64
+	events []*entry
+}
+
+type entry struct {
+	event         outbox.Event
+	published     bool
+	claimed       bool
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+// New creates a new in-memory outbox store.
+func New() *Store {
+	return &Store{}
+}
+
+// Add records events in the outbox, assigning their ids.
+func (s *Store) Add(events ...outbox.Event) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, event := range events {
+		s.nextID++
+		event.ID = s.nextID
+		s.events = append(s.events, &entry{event: event, nextAttemptAt: event.CreatedAt})
+	}
+}
+
+// Claim calls fn with up to limit unpublished events of the source due at now, in publishing order. The store is
+// the marker of the events, which are skipped by concurrent claims until fn returns.
+func (s *Store) Claim(_ context.Context, source string, now time.Time, limit int, fn func(events []outbox.Event, marker outbox.Marker) error) error {
+	claimed := s.claim(source, now, limit)
+	defer s.release(claimed)
+
+	events := make([]outbox.Event, 0, len(claimed))
+	for _, e := range claimed {
+		events = append(events, e.event)
+	}
+	return fn(events, s)
+}
+
+func (s *Store) claim(source string, now time.Time, limit int) []*entry {
+	s.Lock()
+	defer s.Unlock()
+
+	var res []*entry
+	blocked := map[string]bool{}
+	for _, e := range s.events {
+		if len(res) == limit {
+			break
+		}
+		if e.published || e.event.Source != source {
+			continue
+		}
+		key := e.event.AggregateType + "/" + e.event.AggregateID
+		if e.claimed || e.nextAttemptAt.After(now) {
+			blocked[key] = true
+			continue
+		}
+		if blocked[key] {
+			continue
+		}
+		e.claimed = true
+		res = append(res, e)
+	}
+	return res
+}
+
+func (s *Store) release(claimed []*entry) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, e := range claimed {
+		e.claimed = false
+	}
+}
+
+// OldestPending returns the creation time of the oldest unpublished event of the source, or false if there is none.
+func (s *Store) OldestPending(_ context.Context, source string) (time.Time, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, e := range s.events {
+		if !e.published && e.event.Source == source {
+			return e.event.CreatedAt, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// MarkPublished marks an event as published.
+func (s *Store) MarkPublished(_ context.Context, id int64, _ time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if e := s.find(id); e != nil {
+		e.published = true
+	}
+	s.compact()
+	return nil
+}
+
+// MarkFailed records a failed publishing attempt and schedules the next one.
+func (s *Store) MarkFailed(_ context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if e := s.find(id); e != nil {
+		e.event.Attempts++
+		e.lastError = lastError
+		e.nextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
+
+func (s *Store) find(id int64) *entry {
+	for _, e := range s.events {
+		if e.event.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// compact drops the published events at the head of the outbox.
+func (s *Store) compact() {
+	i := 0
+	for i < len(s.events) && s.events[i].published {
+		i++
+	}
+	s.events = s.events[i:]
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"main/eventcodec"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the content type of outbox event payloads.
+const ContentType = eventcodec.ContentTypeJSON
+
+// Message properties set on every published outbox event, besides the content type.
+const (
+	PropertyEventID       = "event_id"
+	PropertyEventType     = "event_type"
+	PropertyAggregateType = "aggregate_type"
+)
+
+// Event defines a change event recorded in the outbox together with the change it describes.
+type Event struct {
+	// ID is the store-assigned sequence number, which defines the publishing order.
+	ID        int64
+	EventID   string
+	EventType string
+	// Source is the CloudEvents source of the event, which names the service whose relay publishes it.
+	Source        string
+	AggregateType string
+	AggregateID   string
+	Payload       []byte
+	CreatedAt     time.Time
+	// Attempts is the number of failed publishing attempts.
+	Attempts int32
+}
+
+// NewEvent creates an outbox event for the given data, created now with a new unique event id.
+// The payload is a CloudEvents structured JSON envelope, so the stored events stay readable.
+func NewEvent(source, aggregateType, aggregateID string, data proto.Message) (Event, error) {
+	env := eventcodec.Envelope{
+		ID:      uuid.NewString(),
+		Source:  source,
+		Subject: aggregateID,
+		Time:    time.Now(),
+	}
+	payload, err := eventcodec.Encode(ContentType, &env, data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		EventID:       env.ID,
+		EventType:     env.Type,
+		Source:        source,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       payload,
+		CreatedAt:     env.Time,
+	}, nil
+}
+
+// Store defines the outbox storage read by the relays. Several services may share a store, each relay publishing
+// the events of its source.
+type Store interface {
+	// Claim calls fn with up to limit unpublished events of the source due at now, in publishing order, and the
+	// marker recording their publishing outcomes. Events queued behind an event of the same aggregate that is
+	// waiting for a retry are not returned. The events are claimed until fn returns: concurrent relays skip them and
+	// the later events of their aggregates.
+	Claim(ctx context.Context, source string, now time.Time, limit int, fn func(events []Event, marker Marker) error) error
+	// OldestPending returns the creation time of the oldest unpublished event of the source, or false if there is none.
+	OldestPending(ctx context.Context, source string) (time.Time, bool, error)
+}
+
+// Marker records the publishing outcomes of claimed events.
+type Marker interface {
+	// MarkPublished marks an event as published.
+	MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error
+	// MarkFailed records a failed publishing attempt and schedules the next one.
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+}
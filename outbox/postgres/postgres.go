@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"main/database/db"
+	"main/outbox"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Store defines a PostgreSQL-based outbox store.
+type Store struct {
+	db db.Store
+}
+
+// New creates a new PostgreSQL-based outbox store.
+func New(store db.Store) *Store {
+	return &Store{
+		db: store,
+	}
+}
+
+// Params converts outbox events to the parameters recording them in the database.
+func Params(events []outbox.Event) []*db.CreateOutboxEventParams {
+	params := make([]*db.CreateOutboxEventParams, 0, len(events))
+	for _, event := range events {
+		params = append(params, &db.CreateOutboxEventParams{
+			EventID:       event.EventID,
+			EventType:     event.EventType,
+			Source:        event.Source,
+			AggregateType: event.AggregateType,
+			AggregateID:   event.AggregateID,
+			Payload:       event.Payload,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+	return params
+}
+
+// Claim calls fn with up to limit unpublished events of the source due at now, in publishing order, in a
+// transaction holding row locks on the events. The outcomes recorded by the marker are committed if fn returns nil.
+func (s *Store) Claim(ctx context.Context, source string, now time.Time, limit int, fn func(events []outbox.Event, marker outbox.Marker) error) error {
+	return s.db.ClaimOutboxEventsTx(ctx, db.ClaimOutboxEventsTxParams{
+		Pending: db.ClaimPendingOutboxEventsParams{
+			Source:    source,
+			Now:       now,
+			BatchSize: int32(limit),
+		},
+		Publish: func(rows []*db.OutboxEvent, q db.Querier) error {
+			events := make([]outbox.Event, 0, len(rows))
+			for _, row := range rows {
+				events = append(events, outbox.Event{
+					ID:            row.ID,
+					EventID:       row.EventID,
+					EventType:     row.EventType,
+					Source:        row.Source,
+					AggregateType: row.AggregateType,
+					AggregateID:   row.AggregateID,
+					Payload:       row.Payload,
+					CreatedAt:     row.CreatedAt,
+					Attempts:      row.Attempts,
+				})
+			}
+			return fn(events, &marker{q: q})
+		},
+	})
+}
+
+// OldestPending returns the creation time of the oldest unpublished event of the source, or false if there is none.
+func (s *Store) OldestPending(ctx context.Context, source string) (time.Time, bool, error) {
+	createdAt, err := s.db.GetOldestPendingOutboxEvent(ctx, source)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return createdAt, true, nil
+}
+
+// marker records the publishing outcomes of claimed events within the claiming transaction.
+type marker struct {
+	q db.Querier
+}
+
+// MarkPublished marks an event as published.
+func (m *marker) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	return m.q.MarkOutboxEventPublished(ctx, &db.MarkOutboxEventPublishedParams{
+		PublishedAt: &publishedAt,
+		ID:          id,
+	})
+}
+
+// MarkFailed records a failed publishing attempt and schedules the next one.
+func (m *marker) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	return m.q.MarkOutboxEventFailed(ctx, &db.MarkOutboxEventFailedParams{
+		LastError:     &lastError,
+		NextAttemptAt: nextAttemptAt,
+		ID:            id,
+	})
+}
@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"main/eventbus"
+	"main/eventcodec"
+	"main/tracing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const tracerID = "outbox-relay"
+
+// RelayConfig defines the relay settings.
+type RelayConfig struct {
+	// Source is the event source whose events the relay publishes, leaving the events of other sources sharing the
+	// store to their own relays.
+	Source string
+	// Topic is the topic the publisher sends to, named in the producer spans.
+	Topic string
+	// BatchSize is the maximum number of events published per poll.
+	BatchSize int
+	// PollInterval is the delay between polls once the outbox is drained.
+	PollInterval time.Duration
+	// Retry computes the delay before retrying an event that failed to publish.
+	Retry eventbus.BackoffPolicy
+}
+
+// Relay publishes outbox events to the event bus with at-least-once delivery.
+// Events of the same aggregate are published in order, keyed by the aggregate id.
+type Relay struct {
+	store     Store
+	publisher eventbus.Publisher
+	cfg       RelayConfig
+	metrics   *relayMetrics
+}
+
+type relayMetrics struct {
+	published *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	lag       prometheus.Gauge
+}
+
+// NewRelay creates a relay publishing the events of the given store.
+func NewRelay(store Store, publisher eventbus.Publisher, cfg RelayConfig) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		metrics: &relayMetrics{
+			published: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "outbox",
+				Name:      "events_published_total",
+				Help:      "Number of outbox events published to the event bus.",
+			}, []string{"event_type"}),
+			failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "outbox",
+				Name:      "publish_failures_total",
+				Help:      "Number of failed attempts to publish an outbox event.",
+			}, []string{"event_type"}),
+			lag: prometheus.NewGauge(prometheus.GaugeOpts{
+				Namespace: "outbox",
+				Name:      "lag_seconds",
+				Help:      "Age of the oldest unpublished outbox event.",
+			}),
+		},
+	}
+}
+
+// publish sends an outbox event within a producer span.
+func (r *Relay) publish(ctx context.Context, event *Event) error {
+	msg := &eventbus.OutgoingMessage{
+		Key:     event.AggregateID,
+		Payload: event.Payload,
+		Properties: map[string]string{
+			eventcodec.PropertyContentType: ContentType,
+			PropertyEventID:                event.EventID,
+			PropertyEventType:              event.EventType,
+			PropertyAggregateType:          event.AggregateType,
+		},
+		EventTime: event.CreatedAt,
+	}
+	ctx, span := tracing.StartProducerSpan(ctx, tracerID, r.cfg.Topic, msg)
+	defer span.End()
+
+	_, err := r.publisher.Publish(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Collectors returns the relay metrics collectors.
+func (r *Relay) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{r.metrics.published, r.metrics.failed, r.metrics.lag}
+}
+
+// Run publishes outbox events until the context is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Error("failed to relay outbox events:", slog.String("error", err.Error()))
+		}
+		if err == nil && n == r.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes a batch of pending events and returns how many events were fetched.
+// Events failing to publish are retried later; the events queued behind them for the same aggregate are held back.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var n int
+	err := r.store.Claim(ctx, r.cfg.Source, time.Now(), r.cfg.BatchSize, func(events []Event, marker Marker) error {
+		n = len(events)
+		return r.publishAll(ctx, events, marker)
+	})
+	if err != nil {
+		return n, err
+	}
+
+	oldest, ok, err := r.store.OldestPending(ctx, r.cfg.Source)
+	if err != nil {
+		return n, err
+	}
+	if ok {
+		r.metrics.lag.Set(time.Since(oldest).Seconds())
+	} else {
+		r.metrics.lag.Set(0)
+	}
+
+	return n, nil
+}
+
+func (r *Relay) publishAll(ctx context.Context, events []Event, marker Marker) error {
+	blocked := map[string]bool{}
+	for _, event := range events {
+		key := event.AggregateType + "/" + event.AggregateID
+		if blocked[key] {
+			continue
+		}
+
+		err := r.publish(ctx, &event)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			blocked[key] = true
+			r.metrics.failed.WithLabelValues(event.EventType).Inc()
+			slog.Warn("failed to publish outbox event, will retry:", slog.String("event_id", event.EventID), slog.String("error", err.Error()))
+			next := time.Now().Add(r.cfg.Retry.Next(uint32(event.Attempts)))
+			if err := marker.MarkFailed(ctx, event.ID, err.Error(), next); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A failure here republishes the event on the next poll, which consumers must tolerate anyway.
+		if err := marker.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+			return err
+		}
+		r.metrics.published.WithLabelValues(event.EventType).Inc()
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"main/eventbus"
+	"main/eventcodec"
+	"main/outbox"
+	"main/outbox/memory"
+	eventsv1 "main/rpc/events/v1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// flakyPublisher fails the first failures publish calls and records the published messages.
+type flakyPublisher struct {
+	failures  int
+	published []*eventbus.OutgoingMessage
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, msg *eventbus.OutgoingMessage) (string, error) {
+	if p.failures > 0 {
+		p.failures--
+		return "", errors.New("broker unavailable")
+	}
+	p.published = append(p.published, msg)
+	return "", nil
+}
+
+func (p *flakyPublisher) PublishAsync(ctx context.Context, msg *eventbus.OutgoingMessage, callback func(id string, err error)) {
+	callback(p.Publish(ctx, msg))
+}
+
+func (p *flakyPublisher) Flush() error { return nil }
+
+func (p *flakyPublisher) Close() {}
+
+func newEvent(t *testing.T, eventType, aggregateID string) outbox.Event {
+	return newSourceEvent(t, "/test", eventType, aggregateID)
+}
+
+func newSourceEvent(t *testing.T, source, eventType, aggregateID string) outbox.Event {
+	var data proto.Message
+	switch eventType {
+	case eventcodec.TypeRatingChanged:
+		data = &eventsv1.RatingChanged{RecordId: aggregateID, RecordType: "movie"}
+	case eventcodec.TypeAggregateChanged:
+		data = &eventsv1.AggregateChanged{RecordId: aggregateID, RecordType: "movie"}
+	}
+	event, err := outbox.NewEvent(source, "movie", aggregateID, data)
+	require.NoError(t, err)
+	return event
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	store := memory.New()
+	store.Add(newEvent(t, eventcodec.TypeRatingChanged, "1"), newEvent(t, eventcodec.TypeAggregateChanged, "1"), newEvent(t, eventcodec.TypeRatingChanged, "2"))
+
+	publisher := &flakyPublisher{}
+	relay := outbox.NewRelay(store, publisher, outbox.RelayConfig{Source: "/test", BatchSize: 10, Retry: &eventbus.ExponentialBackoff{Min: time.Minute, Max: time.Minute}})
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, publisher.published, 3)
+	require.Equal(t, eventcodec.TypeRatingChanged, publisher.published[0].Properties[outbox.PropertyEventType])
+	require.Equal(t, eventcodec.TypeAggregateChanged, publisher.published[1].Properties[outbox.PropertyEventType])
+	require.Equal(t, "1", publisher.published[1].Key)
+	require.Equal(t, "2", publisher.published[2].Key)
+
+	_, ok, err := store.OldestPending(context.Background(), "/test")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRelayRetriesFailedEventsInOrder(t *testing.T) {
+	store := memory.New()
+	store.Add(newEvent(t, eventcodec.TypeRatingChanged, "1"), newEvent(t, eventcodec.TypeAggregateChanged, "1"), newEvent(t, eventcodec.TypeRatingChanged, "2"))
+
+	publisher := &flakyPublisher{failures: 1}
+	relay := outbox.NewRelay(store, publisher, outbox.RelayConfig{Source: "/test", BatchSize: 10, Retry: &eventbus.ExponentialBackoff{Min: time.Millisecond, Max: time.Millisecond}})
+
+	// The first event of aggregate 1 fails, so the second one is held back while aggregate 2 goes through.
+	_, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, publisher.published, 1)
+	require.Equal(t, "2", publisher.published[0].Key)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, publisher.published, 3)
+	require.Equal(t, eventcodec.TypeRatingChanged, publisher.published[1].Properties[outbox.PropertyEventType])
+	require.Equal(t, eventcodec.TypeAggregateChanged, publisher.published[2].Properties[outbox.PropertyEventType])
+}
+
+func TestRelaysPublishTheirSource(t *testing.T) {
+	store := memory.New()
+	store.Add(
+		newSourceEvent(t, "/metadata", eventcodec.TypeRatingChanged, "1"),
+		newSourceEvent(t, "/rating", eventcodec.TypeRatingChanged, "1"),
+		newSourceEvent(t, "/rating", eventcodec.TypeAggregateChanged, "2"),
+	)
+
+	metadataPublisher, ratingPublisher := &flakyPublisher{}, &flakyPublisher{}
+	retry := &eventbus.ExponentialBackoff{Min: time.Minute, Max: time.Minute}
+	metadataRelay := outbox.NewRelay(store, metadataPublisher, outbox.RelayConfig{Source: "/metadata", BatchSize: 10, Retry: retry})
+	ratingRelay := outbox.NewRelay(store, ratingPublisher, outbox.RelayConfig{Source: "/rating", BatchSize: 10, Retry: retry})
+
+	n, err := metadataRelay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, metadataPublisher.published, 1)
+
+	_, ok, err := store.OldestPending(context.Background(), "/metadata")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = store.OldestPending(context.Background(), "/rating")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	n, err = ratingRelay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Len(t, ratingPublisher.published, 2)
+	require.Len(t, metadataPublisher.published, 1)
+}
+
+// relayingPublisher runs another relay while publishing its first message, as a concurrent relay instance would.
+type relayingPublisher struct {
+	flakyPublisher
+	relay    *outbox.Relay
+	relayed  int
+	relaying bool
+}
+
+func (p *relayingPublisher) Publish(ctx context.Context, msg *eventbus.OutgoingMessage) (string, error) {
+	if p.relay != nil && !p.relaying {
+		p.relaying = true
+		n, err := p.relay.RelayOnce(ctx)
+		if err != nil {
+			return "", err
+		}
+		p.relayed = n
+	}
+	return p.flakyPublisher.Publish(ctx, msg)
+}
+
+func TestRelaysSkipClaimedEvents(t *testing.T) {
+	store := memory.New()
+	store.Add(newEvent(t, eventcodec.TypeRatingChanged, "1"), newEvent(t, eventcodec.TypeAggregateChanged, "1"), newEvent(t, eventcodec.TypeRatingChanged, "2"))
+
+	cfg := outbox.RelayConfig{Source: "/test", BatchSize: 1, Retry: &eventbus.ExponentialBackoff{Min: time.Minute, Max: time.Minute}}
+	other := &flakyPublisher{}
+	publisher := &relayingPublisher{relay: outbox.NewRelay(store, other, cfg)}
+	relay := outbox.NewRelay(store, publisher, cfg)
+
+	// The other relay skips the claimed event and the later event of its aggregate.
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, publisher.relayed)
+	require.Len(t, publisher.published, 1)
+	require.Equal(t, "1", publisher.published[0].Key)
+	require.Len(t, other.published, 1)
+	require.Equal(t, "2", other.published[0].Key)
+}
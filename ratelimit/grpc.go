@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"main/auth"
+	"net"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// retryAfterMetadata is the header telling rejected callers how many seconds to wait before retrying.
+const retryAfterMetadata = "retry-after"
+
+// UnaryServerInterceptor returns a gRPC interceptor rejecting the unary requests over the quota of their caller
+// with ResourceExhausted.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.check(ctx, info.FullMethod, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) }); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor rejecting the streams over the quota of their caller
+// with ResourceExhausted.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(ss.Context(), info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// check returns a ResourceExhausted error if the caller of ctx is over its quota of the method. The error carries
+// the retry delay as RetryInfo details, which is also sent in the retry-after header.
+func (l *Limiter) check(ctx context.Context, fullMethod string, setHeader func(metadata.MD) error) error {
+	callerType, caller := grpcCaller(ctx)
+	allowed, retryAfter := l.allow(fullMethod, callerType, caller)
+	if allowed {
+		return nil
+	}
+
+	st := status.Newf(codes.ResourceExhausted, "%s is rate limited, please retry later", fullMethod)
+	if retryAfter > 0 {
+		_ = setHeader(metadata.Pairs(retryAfterMetadata, retryAfterSeconds(retryAfter)))
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+// grpcCaller identifies the caller of a gRPC request by the principal its credentials were verified as, or else
+// its IP address. Unverified identities such as raw API keys or user ids sent in metadata are ignored: any caller
+// could pick a new one for every request to get a fresh quota.
+func grpcCaller(ctx context.Context) (string, string) {
+	if p, ok := auth.FromContext(ctx); ok {
+		return callerPrincipal, p.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return callerPeer, host
+	}
+	return callerUnknown, ""
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"main/auth"
+	"net"
+	"net/http"
+)
+
+// Middleware returns an HTTP handler rejecting the requests over the quota of their caller for the handler with
+// 429 Too Many Requests and a Retry-After header. It must run after the authentication to limit the callers by
+// their principal.
+func (l *Limiter) Middleware(handler string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callerType, caller := httpCaller(r)
+		allowed, retryAfter := l.allow(handler, callerType, caller)
+		if !allowed {
+			if retryAfter > 0 {
+				w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+			}
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// httpCaller identifies the caller of an HTTP request by the principal its credentials were verified as, or else
+// its IP address. Like for gRPC requests, unverified identities sent in headers are ignored.
+func httpCaller(r *http.Request) (string, string) {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return callerPrincipal, p.Subject
+	}
+	if r.RemoteAddr == "" {
+		return callerUnknown, ""
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return callerPeer, host
+}
@@ -0,0 +1,253 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long the bucket of a caller is kept without requests.
+const idleTimeout = 10 * time.Minute
+
+// maxBuckets is the default maximum number of token buckets kept by a limiter. Once reached, the callers without a
+// bucket share one per method until idle buckets are swept, which happens at most once per fullSweepInterval.
+const (
+	maxBuckets        = 100_000
+	fullSweepInterval = time.Second
+)
+
+// overflowCaller is the caller of the bucket shared by the callers without a bucket of their own.
+const overflowCaller = "\x00overflow"
+
+// Types of callers, as labelled in the rate limiting metrics.
+const (
+	callerPrincipal = "principal"
+	callerPeer      = "peer"
+	callerUnknown   = "unknown"
+)
+
+// Results of the rate limiting of a request, as labelled in the rate limiting metrics.
+const (
+	resultAllowed  = "allowed"
+	resultRejected = "rejected"
+)
+
+// Quota is the number of requests per second a caller may send to a method, with bursts of up to Burst requests.
+// A zero Limit allows all requests.
+type Quota struct {
+	Limit float64
+	Burst int
+}
+
+// Validate checks that the quota is a finite rate that is not negative, with a burst of at least one request
+// if it limits requests.
+func (q Quota) Validate() error {
+	if q.Limit < 0 || math.IsInf(q.Limit, 0) || math.IsNaN(q.Limit) {
+		return fmt.Errorf("limit must be a finite number that is not negative, got %v", q.Limit)
+	}
+	if q.Limit > 0 && q.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1, got %d", q.Burst)
+	}
+	return nil
+}
+
+// ParseQuotas parses the quotas of methods in the method=limit:burst,... form, such as
+// GetMovieDetails=10:20,ListTopRated=5. The burst defaults to the limit rounded up.
+// A method is either a full gRPC method name or a gRPC method name without its service.
+func ParseQuotas(s string) (map[string]Quota, error) {
+	quotas := map[string]Quota{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		method = strings.TrimSpace(method)
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid quota %q, want method=limit:burst", entry)
+		}
+
+		limit, burst, hasBurst := strings.Cut(value, ":")
+		var q Quota
+		var err error
+		if q.Limit, err = strconv.ParseFloat(strings.TrimSpace(limit), 64); err != nil {
+			return nil, fmt.Errorf("invalid limit of %s: %w", method, err)
+		}
+		if hasBurst {
+			if q.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil {
+				return nil, fmt.Errorf("invalid burst of %s: %w", method, err)
+			}
+		} else if q.Limit > 0 && !math.IsInf(q.Limit, 0) {
+			q.Burst = int(math.Ceil(q.Limit))
+		}
+		if err := q.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid quota of %s: %w", method, err)
+		}
+		if _, ok := quotas[method]; ok {
+			return nil, fmt.Errorf("duplicate quota of %s", method)
+		}
+		quotas[method] = q
+	}
+	return quotas, nil
+}
+
+type bucketKey struct {
+	method string
+	caller string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter limits the rate of the requests of every caller to every method with a token bucket per caller and
+// method. Callers are identified by their authenticated principal, or else their IP address. The number of buckets
+// is bounded: once it is reached, new callers share a bucket per method until idle buckets are swept.
+type Limiter struct {
+	mu         sync.Mutex
+	quota      Quota
+	methods    map[string]Quota
+	buckets    map[bucketKey]*bucket
+	maxBuckets int
+	lastSweep  time.Time
+
+	requests *prometheus.CounterVec
+	active   prometheus.GaugeFunc
+}
+
+// New creates a rate limiter applying the default quota to every method.
+func New(quota Quota) *Limiter {
+	l := &Limiter{
+		quota:      quota,
+		methods:    map[string]Quota{},
+		buckets:    map[bucketKey]*bucket{},
+		maxBuckets: maxBuckets,
+		lastSweep:  time.Now(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ratelimit",
+			Name:      "requests_total",
+			Help:      "Number of requests checked by the rate limiter, by method, type of caller and result.",
+		}, []string{"method", "caller_type", "result"}),
+	}
+	l.active = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "ratelimit",
+		Name:      "buckets",
+		Help:      "Number of token buckets of the callers that sent requests recently.",
+	}, func() float64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return float64(len(l.buckets))
+	})
+	return l
+}
+
+// Collectors returns the Prometheus collectors of the rate limiter metrics.
+func (l *Limiter) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{l.requests, l.active}
+}
+
+// SetQuotas changes the default quota and the quotas of specific methods while the limiter is in use.
+// The callers keep their tokens.
+func (l *Limiter) SetQuotas(quota Quota, methods map[string]Quota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quota = quota
+	l.methods = make(map[string]Quota, len(methods))
+	for method, q := range methods {
+		l.methods[method] = q
+	}
+	for key, b := range l.buckets {
+		q := l.quotaOf(key.method)
+		b.limiter.SetLimit(rate.Limit(q.Limit))
+		b.limiter.SetBurst(q.Burst)
+	}
+}
+
+// quotaOf returns the quota of a method, by its full name first and then by its name without its service.
+// The lock must be held.
+func (l *Limiter) quotaOf(method string) Quota {
+	if q, ok := l.methods[method]; ok {
+		return q
+	}
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		if q, ok := l.methods[method[i+1:]]; ok {
+			return q
+		}
+	}
+	return l.quota
+}
+
+// allow reports whether the caller may send a request to the method now, along with the time to wait before
+// retrying otherwise. A zero delay means the request can never be allowed.
+func (l *Limiter) allow(method, callerType, caller string) (bool, time.Duration) {
+	allowed, retryAfter := l.take(method, caller)
+	result := resultAllowed
+	if !allowed {
+		result = resultRejected
+	}
+	l.requests.WithLabelValues(method, callerType, result).Inc()
+	return allowed, retryAfter
+}
+
+func (l *Limiter) take(method, caller string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	q := l.quotaOf(method)
+	if q.Limit == 0 {
+		return true, 0
+	}
+	l.sweep(now, idleTimeout)
+
+	key := bucketKey{method: method, caller: caller}
+	b, ok := l.buckets[key]
+	if !ok && len(l.buckets) >= l.maxBuckets {
+		l.sweep(now, fullSweepInterval)
+		if len(l.buckets) >= l.maxBuckets {
+			key.caller = overflowCaller
+			b, ok = l.buckets[key]
+		}
+	}
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(q.Limit), q.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, 0
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep removes the buckets of the callers idle for longer than idleTimeout, at most once per interval.
+// The lock must be held.
+func (l *Limiter) sweep(now time.Time, interval time.Duration) {
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// retryAfterSeconds rounds a retry delay up to whole seconds, as in Retry-After headers.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
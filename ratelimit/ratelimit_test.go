@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"main/auth"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseQuotas(t *testing.T) {
+	quotas, err := ParseQuotas("GetMovieDetails=10:20, /MovieService/ListTopRated=2.5,Health=0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Quota{
+		"GetMovieDetails":            {Limit: 10, Burst: 20},
+		"/MovieService/ListTopRated": {Limit: 2.5, Burst: 3},
+		"Health":                     {Limit: 0, Burst: 0},
+	}, quotas)
+
+	for _, invalid := range []string{"GetMovieDetails", "=1", "GetMovieDetails=a", "GetMovieDetails=1:0", "GetMovieDetails=-1", "A=1,A=2"} {
+		_, err := ParseQuotas(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 4242}})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l := New(Quota{Limit: 1, Burst: 2})
+	l.SetQuotas(Quota{Limit: 1, Burst: 2}, map[string]Quota{"ListTopRated": {}})
+	interceptor := l.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	call := func(ctx context.Context, method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	alice := auth.NewContext(context.Background(), &auth.Principal{Subject: "alice", Kind: auth.KindAPIKey})
+	bob := peerContext("10.0.0.2")
+
+	require.NoError(t, call(alice, "/MovieService/GetMovieDetails"))
+	require.NoError(t, call(alice, "/MovieService/GetMovieDetails"))
+	err := call(alice, "/MovieService/GetMovieDetails")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	require.Positive(t, details[0].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+
+	// Quotas are per caller and per method.
+	require.NoError(t, call(bob, "/MovieService/GetMovieDetails"))
+	require.NoError(t, call(alice, "/MovieService/GetMetadata"))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, call(alice, "/MovieService/ListTopRated"))
+	}
+
+	require.Equal(t, float64(1), testutil.ToFloat64(l.requests.WithLabelValues("/MovieService/GetMovieDetails", callerPrincipal, resultRejected)))
+	require.Equal(t, float64(1), testutil.ToFloat64(l.requests.WithLabelValues("/MovieService/GetMovieDetails", callerPeer, resultAllowed)))
+	require.Equal(t, float64(3), testutil.ToFloat64(l.active))
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(Quota{Limit: 0.5, Burst: 1})
+	handler := l.Middleware("/movie", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(remoteAddr string, p *auth.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/movie", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-User-Id", "spoofed")
+		if p != nil {
+			r = r.WithContext(auth.NewContext(r.Context(), p))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1234", nil).Code)
+	rejected := serve("10.0.0.1:5678", nil)
+	require.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	require.Equal(t, "2", rejected.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, serve("10.0.0.2:1234", nil).Code)
+
+	// Verified principals have their own quota, wherever they call from.
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1234", &auth.Principal{Subject: "alice"}).Code)
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.3:1234", &auth.Principal{Subject: "alice"}).Code)
+}
+
+func TestUnverifiedCallersLimitedByPeer(t *testing.T) {
+	l := New(Quota{Limit: 1, Burst: 1})
+	interceptor := l.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	// Unverified API keys and user ids do not earn a quota of their own.
+	for i, md := range []metadata.MD{metadata.Pairs("x-api-key", "a"), metadata.Pairs("x-user-id", "b")} {
+		ctx := metadata.NewIncomingContext(peerContext("10.0.0.2"), md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/MovieService/GetMovieDetails"}, handler)
+		if i == 0 {
+			require.NoError(t, err)
+		} else {
+			require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		}
+	}
+	require.Equal(t, float64(1), testutil.ToFloat64(l.active))
+}
+
+func TestBucketsBounded(t *testing.T) {
+	l := New(Quota{Limit: 1, Burst: 1})
+	l.maxBuckets = 2
+
+	allowed, _ := l.take("GetMovieDetails", "10.0.0.1")
+	require.True(t, allowed)
+	allowed, _ = l.take("GetMovieDetails", "10.0.0.2")
+	require.True(t, allowed)
+
+	// New callers share the overflow bucket once the limiter is full.
+	allowed, _ = l.take("GetMovieDetails", "10.0.0.3")
+	require.True(t, allowed)
+	allowed, _ = l.take("GetMovieDetails", "10.0.0.4")
+	require.False(t, allowed)
+	require.Len(t, l.buckets, 3)
+}
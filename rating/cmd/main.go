@@ -2,159 +2,145 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
 	"log/slog"
+	"main/app"
+	"main/audit"
+	auditpg "main/audit/postgres"
+	"main/auth"
+	"main/config"
 	"main/database/db"
-	"main/discovery"
-	"main/discovery/consul"
+	"main/database/dbtrace"
+	"main/eventbus/pulsar"
+	"main/outbox"
+	outboxpg "main/outbox/postgres"
+	"main/ratelimit"
 	grpchandler "main/rating/handler/grpc"
+	"main/rating/repository"
 	"main/rating/repository/postgres"
 	"main/rating/service"
 	"main/rpc"
-	"main/tracing"
-	"main/util"
-	"net"
-	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 )
 
-const serviceName = "rating"
+const (
+	serviceName = "rating"
+	// limit and burst are the default quota of every caller. The unauthenticated requests relayed by the movie
+	// gateway share its address, so the quota is higher than the one of the gateway.
+	limit = 1000
+	burst = 1000
+)
 
 func main() {
-	var config string
-	flag.StringVar(&config, "config", ".env", "Configuration path")
-	flag.Parse()
-	cfg := util.LoadConfig(config)
-
-	if cfg.Environment == "dev" {
-		var logger = slog.New(slog.NewTextHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
-	} else {
-		var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("service_name", serviceName)
-		slog.SetDefault(logger)
-	}
+	var cfg config.Rating
+	config.MustLoad(&cfg)
 
-	// prometheus
-	reg := prometheus.NewRegistry()
-	counter := promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: serviceName,
-		Name:      "service_started",
-	})
-
-	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
-	go func() {
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.RatingMetricsPort), nil); err != nil {
-			slog.Error("failed to start metrics handler:", slog.String("error", err.Error()))
-			return
-		}
-	}()
-
-	reg.MustRegister(counter)
-	defer reg.Unregister(counter)
-	counter.Inc()
-
-	slog.Info("Starting the rating service on port", slog.Int("port", cfg.RatingPort))
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if err := run(&cfg); err != nil {
+		slog.Error("rating service failed:", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
 
-	tp, err := tracing.NewJaegerProvider(cfg.JaegerURL, serviceName)
+func run(cfg *config.Rating) error {
+	source, err := cfg.DynamicSource(serviceName)
 	if err != nil {
-		slog.Error("failed to initialize Jaeger provider:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to create dynamic configuration source: %w", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			slog.Error("failed to shutdown tracing provider:", slog.String("error", err.Error()))
-			return
-		}
-	}()
 
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	registry, err := consul.NewRegistry(cfg.ConsulURL)
+	a, err := app.New(serviceName,
+		app.WithEnvironment(cfg.Environment),
+		app.WithMetrics(cfg.RatingMetricsPort),
+		app.WithTracing(cfg.JaegerURL),
+		app.WithDiscovery(cfg.ConsulURL),
+		app.WithDynamicConfig(source),
+		app.WithConcurrencyLimit(cfg.ConcurrencyConfig()),
+		app.WithAuth(cfg.AuthConfig()),
+		app.WithTLS(cfg.TLSConfig("movie")),
+		app.WithRateLimit(ratelimit.Quota{Limit: limit, Burst: burst}),
+		app.WithAuthorization(auth.Policy{auth.AdminMethods: {auth.RoleOperator}}),
+		app.WithGRPC(fmt.Sprintf("%s:%d", cfg.Host, cfg.RatingPort)),
+		app.WithShutdownTimeout(cfg.ShutdownTimeout),
+	)
 	if err != nil {
-		slog.Error("faild to connect to consul registry:", slog.String("error", err.Error()))
-		return
+		return err
 	}
 
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	hostPort := fmt.Sprintf("%s:%d", cfg.Host, cfg.RatingPort)
-	if err := registry.Register(ctx, instanceID, serviceName, hostPort); err != nil {
-		slog.Error("failed to register rating service:", slog.String("error", err.Error()))
-		return
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid database url: %w", err)
 	}
+	dbTracer := dbtrace.New(serviceName)
+	poolConfig.ConnConfig.Tracer = dbTracer
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	a.Append(app.Hook{Name: "database", OnStop: func(context.Context) error { conn.Close(); return nil }})
+	a.Metrics().MustRegister(dbTracer.Collectors()...)
+	a.Metrics().MustRegister(dbtrace.NewPoolCollector(conn))
 
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				slog.Info("Failed to report healthy state:", slog.String("error", err.Error()))
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-
-	conn, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	bus, err := pulsar.New(cfg.Pulsar)
 	if err != nil {
-		slog.Error("cannot connect to db:", slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to connect to pulsar: %w", err)
 	}
+	a.Append(app.Hook{Name: "pulsar", OnStop: func(context.Context) error { bus.Close(); return nil }})
 
 	store := db.NewStore(conn)
-	repo := postgres.New(store)
-	svc := service.New(repo, cfg)
+	auditStore := auditpg.New(store, cfg.AuditEventSource(repository.EventSource))
+	repo := postgres.New(store, auditStore)
+	auditor := audit.New(serviceName, auditStore)
+	a.Metrics().MustRegister(auditor.Collectors()...)
+	a.Authorizer().OnDenied(auditor.Denied)
+	svc := service.New(repo, bus, cfg, auditor)
 	h := grpchandler.New(svc)
-
-	go func() {
-		if err := svc.StartConsume(ctx); err != nil {
-			slog.Error("failed to consume events:", slog.String("error", err.Error()))
-			return
+	a.Metrics().MustRegister(svc.Collectors()...)
+	a.Check("database", conn.Ping)
+	a.CheckDegraded("rating event consumer", func(context.Context) error {
+		if !svc.ConsumerHealthy() {
+			return errors.New("no consumer is receiving rating events")
 		}
-	}()
+		return nil
+	})
+
+	a.Go("leaderboard refresh", func(ctx context.Context) error {
+		svc.StartLeaderboardRefresh(ctx)
+		return nil
+	})
+
+	a.Go("processed events prune", func(ctx context.Context) error {
+		svc.StartProcessedEventsPrune(ctx)
+		return nil
+	})
 
-	listener, err := net.Listen("tcp", hostPort)
+	publisher, err := bus.Publisher(cfg.RatingEventsTopic)
 	if err != nil {
-		slog.Error("failed to listen on:", slog.String("host", hostPort), slog.String("error", err.Error()))
-		return
+		return fmt.Errorf("failed to create rating events publisher: %w", err)
 	}
+	a.Append(app.Hook{Name: "rating events publisher", OnStop: func(context.Context) error { publisher.Close(); return nil }})
 
-	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		slog.Info("Received signal", s)
-		slog.Info("attempting graceful shutdown")
-		server.GracefulStop()
-		slog.Info("Gracefully stopped the gRPC server")
-	}()
-
-	reflection.Register(server)
-	rpc.RegisterRatingServiceServer(server, h)
-	if err := server.Serve(listener); err != nil {
-		slog.Error("Failed to start gRPC server:", slog.String("error", err.Error()))
-		return
-	}
+	relay := outbox.NewRelay(outboxpg.New(store), publisher, cfg.OutboxRelayConfig(repository.EventSource, cfg.RatingEventsTopic))
+	a.Metrics().MustRegister(relay.Collectors()...)
+	a.Go("outbox relay", relay.Run)
+
+	a.Go("rating event consumer", func(ctx context.Context) error {
+		for {
+			if err := svc.StartConsume(ctx); err != nil {
+				slog.Error("failed to consume events:", slog.String("error", err.Error()))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+				slog.Info("Restarting the rating event consumer")
+			}
+		}
+	})
+
+	rpc.RegisterRatingServiceServer(a.GRPCServer(), h)
+	rpc.RegisterAuditAdminServer(a.GRPCServer(), audit.NewHandler(serviceName, auditStore))
+	return a.Run(context.Background())
 }
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"main/auth"
 	"main/rating/model"
 	"main/rating/service"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // Handler defines a rating service controller.
@@ -37,7 +39,16 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		v, err := h.ctrl.GetAggregatedRating(r.Context(), recordID, recordType)
+		var window time.Duration
+		if v := r.FormValue("window"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			window = d
+		}
+		v, err := h.ctrl.GetAggregatedRating(r.Context(), recordID, recordType, window)
 		if err != nil && errors.Is(err, service.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 			return
@@ -46,17 +57,19 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Response encode error: %v\n", err)
 		}
 	case http.MethodPut:
-		userID := model.UserID(r.FormValue("userId"))
-		if userID == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
 		v, err := strconv.ParseFloat(r.FormValue("value"), 64)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		if err := h.ctrl.PutRating(r.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)}); err != nil {
+		// The rating is from the principal of the request: a userId, if set, must be its subject.
+		userID := model.UserID(r.FormValue("userId"))
+		err = h.ctrl.PutRating(r.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)})
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else if errors.Is(err, auth.ErrPermissionDenied) {
+			w.WriteHeader(http.StatusForbidden)
+		} else if err != nil {
 			log.Printf("Repository put error: %v\n", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
@@ -3,9 +3,12 @@ package grpc
 import (
 	"context"
 	"errors"
+	"main/auth"
 	"main/rating/model"
 	"main/rating/service"
 	"main/rpc"
+	"strconv"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -30,7 +33,15 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *rpc.GetAggregate
 		return nil, status.Errorf(codes.InvalidArgument, "nil request or emtpy movie id")
 	}
 
-	rating, err := h.svc.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	var window time.Duration
+	if req.Window != nil {
+		if err := req.Window.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid window: %v", err)
+		}
+		window = req.Window.AsDuration()
+	}
+
+	rating, err := h.svc.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), window)
 	if err != nil && errors.Is(err, service.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
 	} else if err != nil {
@@ -42,20 +53,115 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *rpc.GetAggregate
 	}, nil
 }
 
-// PutRating writes a rating for a given record.
+// PutRating writes a rating for a given record. The rating is from the authenticated principal, which the user id
+// of the request must match if set.
 func (h *Handler) PutRating(ctx context.Context, req *rpc.PutRatingRequest) (*rpc.PutRatingResponse, error) {
-	if req == nil || req.RecordId == "" || req.RecordType == "" || req.UserId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "nil request or empty user id or record id")
+	if req == nil || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request or empty record id")
 	}
 
-	if err := h.svc.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{
+	err := h.svc.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{
 		RecordID:   req.RecordId,
 		RecordType: req.RecordType,
 		UserID:     model.UserID(req.UserId),
 		Value:      model.RatingValue(req.RatingValue),
-	}); err != nil {
+	})
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, status.Errorf(codes.Unauthenticated, err.Error())
+	} else if errors.Is(err, auth.ErrPermissionDenied) {
+		return nil, status.Errorf(codes.PermissionDenied, err.Error())
+	} else if err != nil {
 		return nil, err
 	}
 
 	return &rpc.PutRatingResponse{}, nil
 }
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// ListTopRated returns records ranked by their average rating.
+func (h *Handler) ListTopRated(ctx context.Context, req *rpc.ListTopRatedRequest) (*rpc.ListTopRatedResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request or empty record type")
+	}
+
+	offset, limit, err := pageBounds(req.PageToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	aggs, next, err := h.svc.ListTopRated(ctx, model.RecordType(req.RecordType), req.MinRatingCount, offset, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	return &rpc.ListTopRatedResponse{
+		Rankings:      rankingsToProto(aggs),
+		NextPageToken: pageToken(next),
+	}, nil
+}
+
+// ListTrending returns records ranked by their time-decayed rating velocity.
+func (h *Handler) ListTrending(ctx context.Context, req *rpc.ListTrendingRequest) (*rpc.ListTrendingResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil request or empty record type")
+	}
+
+	offset, limit, err := pageBounds(req.PageToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	aggs, next, err := h.svc.ListTrending(ctx, model.RecordType(req.RecordType), offset, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	return &rpc.ListTrendingResponse{
+		Rankings:      rankingsToProto(aggs),
+		NextPageToken: pageToken(next),
+	}, nil
+}
+
+func pageBounds(token string, size int32) (int, int, error) {
+	offset := 0
+	if token != "" {
+		v, err := strconv.Atoi(token)
+		if err != nil || v < 0 {
+			return 0, 0, status.Errorf(codes.InvalidArgument, "invalid page token")
+		}
+		offset = v
+	}
+
+	limit := int(size)
+	if limit <= 0 {
+		limit = defaultPageSize
+	} else if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return offset, limit, nil
+}
+
+func pageToken(next int) string {
+	if next == 0 {
+		return ""
+	}
+	return strconv.Itoa(next)
+}
+
+func rankingsToProto(aggs []model.RecordAggregate) []*rpc.RecordRanking {
+	res := make([]*rpc.RecordRanking, 0, len(aggs))
+	for _, agg := range aggs {
+		res = append(res, &rpc.RecordRanking{
+			RecordId:      string(agg.RecordID),
+			RecordType:    string(agg.RecordType),
+			RatingValue:   agg.AverageValue,
+			RatingCount:   agg.RatingCount,
+			TrendingScore: agg.TrendingScore,
+		})
+	}
+	return res
+}
@@ -0,0 +1,40 @@
+package model
+
+import eventsv1 "main/rpc/events/v1"
+
+// RatingEventToProto converts a RatingEvent struct into a generated proto counterpart.
+// The event id and occurrence time are carried by the event envelope instead.
+func RatingEventToProto(e *RatingEvent) *eventsv1.RatingEvent {
+	eventType := eventsv1.RatingEventType_RATING_EVENT_TYPE_UNSPECIFIED
+	switch e.EventType {
+	case RatingEventTypePut:
+		eventType = eventsv1.RatingEventType_RATING_EVENT_TYPE_PUT
+	case RatingEventTypeDelete:
+		eventType = eventsv1.RatingEventType_RATING_EVENT_TYPE_DELETE
+	}
+	return &eventsv1.RatingEvent{
+		UserId:     string(e.UserID),
+		RecordId:   string(e.RecordID),
+		RecordType: string(e.RecordType),
+		Value:      int64(e.Value),
+		EventType:  eventType,
+	}
+}
+
+// RatingEventFromProto converts generated proto counterpart into a RatingEvent struct.
+func RatingEventFromProto(e *eventsv1.RatingEvent) *RatingEvent {
+	var eventType RatingEventType
+	switch e.EventType {
+	case eventsv1.RatingEventType_RATING_EVENT_TYPE_PUT:
+		eventType = RatingEventTypePut
+	case eventsv1.RatingEventType_RATING_EVENT_TYPE_DELETE:
+		eventType = RatingEventTypeDelete
+	}
+	return &RatingEvent{
+		UserID:     UserID(e.UserId),
+		RecordID:   RecordID(e.RecordId),
+		RecordType: RecordType(e.RecordType),
+		Value:      RatingValue(e.Value),
+		EventType:  eventType,
+	}
+}
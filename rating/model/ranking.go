@@ -0,0 +1,10 @@
+package model
+
+// RecordAggregate defines the aggregated rating statistics of a single record.
+type RecordAggregate struct {
+	RecordID      RecordID   `json:"recordId"`
+	RecordType    RecordType `json:"recordType"`
+	RatingCount   int64      `json:"ratingCount"`
+	AverageValue  float64    `json:"averageValue"`
+	TrendingScore float64    `json:"trendingScore"`
+}
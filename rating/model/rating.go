@@ -1,5 +1,10 @@
 package model
 
+import (
+	"main/audit"
+	"time"
+)
+
 // RecordID defines a record id. Together with RecordType identifies unique records across all types.
 type RecordID string
 
@@ -23,10 +28,24 @@ type Rating struct {
 	RecordType string      `json:"recordType"`
 	UserID     UserID      `json:"userId"`
 	Value      RatingValue `json:"value"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+// RatingWrite defines a rating to be written once for the event with the given id.
+type RatingWrite struct {
+	EventID    string
+	RecordID   RecordID
+	RecordType RecordType
+	Rating     *Rating
+	// Audit is the audit record of the write, stored with it if not nil.
+	Audit *audit.Record
 }
 
 // RatingEvent defines an event containing rating information.
 type RatingEvent struct {
+	ID         string          `json:"id"`
+	OccurredAt time.Time       `json:"occurredAt"`
 	UserID     UserID          `json:"userId"`
 	RecordID   RecordID        `json:"recordId"`
 	RecordType RecordType      `json:"recordType"`
@@ -2,5 +2,9 @@ package repository
 
 import "errors"
 
-// ErrorNotFound is retured when a requested record is not found.
-var ErrNotFound = errors.New("not found")
+var (
+	// ErrorNotFound is retured when a requested record is not found.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateEvent is returned when an event was already applied.
+	ErrDuplicateEvent = errors.New("event already processed")
+)
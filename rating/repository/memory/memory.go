@@ -2,35 +2,177 @@ package memory
 
 import (
 	"context"
+	"main/audit"
+	auditmemory "main/audit/memory"
+	outboxmemory "main/outbox/memory"
 	"main/rating/model"
 	"main/rating/repository"
+	"math"
+	"sync"
+	"time"
 )
 
 // Repository defines a rating repository.
 type Repository struct {
-	data map[model.RecordType]map[model.RecordID][]model.Rating
+	sync.RWMutex
+	data      map[model.RecordType]map[model.RecordID][]model.Rating
+	processed map[string]time.Time
+	outbox    *outboxmemory.Store
+	audit     *auditmemory.Store
 }
 
 // New creates a new memory repository.
 func New() *Repository {
 	return &Repository{
-		data: map[model.RecordType]map[model.RecordID][]model.Rating{},
+		data:      map[model.RecordType]map[model.RecordID][]model.Rating{},
+		processed: map[string]time.Time{},
+		outbox:    outboxmemory.New(),
+		audit:     auditmemory.New(),
 	}
 }
 
-// Get retrieves all ratings for a given record.
-func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+// Outbox returns the outbox the change events of written ratings are recorded in.
+func (r *Repository) Outbox() *outboxmemory.Store {
+	return r.outbox
+}
+
+// Audit returns the store the audit records of written ratings are appended to.
+func (r *Repository) Audit() *auditmemory.Store {
+	return r.audit
+}
+
+// Get retrieves all ratings for a given record created at or after since. A zero since returns all ratings.
+func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType, since time.Time) ([]model.Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+
 	if _, ok := r.data[recordType]; !ok {
 		return nil, repository.ErrNotFound
 	}
-	return r.data[recordType][recordID], nil
+
+	var res []model.Rating
+	for _, rating := range r.data[recordType][recordID] {
+		if rating.CreatedAt.Before(since) {
+			continue
+		}
+		res = append(res, rating)
+	}
+
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	return res, nil
+}
+
+// Put adds a rating for a given record, records its change events in the outbox and appends its audit record if not nil.
+func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.put(ctx, recordID, recordType, rating, record)
+}
+
+// PutOnce adds a rating for a given record unless the event with the given id was already applied,
+// in which case it returns ErrDuplicateEvent. The audit record, if not nil, is appended unless the event is a duplicate.
+func (r *Repository) PutOnce(ctx context.Context, eventID string, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.processed[eventID]; ok {
+		return repository.ErrDuplicateEvent
+	}
+	if err := r.put(ctx, recordID, recordType, rating, record); err != nil {
+		return err
+	}
+	r.processed[eventID] = time.Now()
+	return nil
+}
+
+// PutOnceBatch adds the ratings of a batch of events, skipping the events already applied.
+// It returns whether each event was a duplicate, in the order of the writes.
+func (r *Repository) PutOnceBatch(ctx context.Context, writes []model.RatingWrite) ([]bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	duplicates := make([]bool, len(writes))
+	for i, w := range writes {
+		if _, ok := r.processed[w.EventID]; ok {
+			duplicates[i] = true
+			continue
+		}
+		if err := r.put(ctx, w.RecordID, w.RecordType, w.Rating, w.Audit); err != nil {
+			return nil, err
+		}
+		r.processed[w.EventID] = time.Now()
+	}
+	return duplicates, nil
+}
+
+// PruneProcessedEvents forgets the events applied before the given time, returning how many were forgotten.
+func (r *Repository) PruneProcessedEvents(_ context.Context, before time.Time) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var pruned int64
+	for eventID, processedAt := range r.processed {
+		if processedAt.Before(before) {
+			delete(r.processed, eventID)
+			pruned++
+		}
+	}
+	return pruned, nil
 }
 
-// Put adds a rating for a given record.
-func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+// put adds a rating, records its change events in the outbox and appends its audit record if not nil.
+// The caller must hold the write lock.
+func (r *Repository) put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	ratings := append(r.data[recordType][recordID], *rating)
+	var sum float64
+	for _, existing := range ratings {
+		sum += float64(existing.Value)
+	}
+	events, err := repository.ChangeEvents(recordID, recordType, rating, int64(len(ratings)), sum/float64(len(ratings)))
+	if err != nil {
+		return err
+	}
+
 	if _, ok := r.data[recordType]; !ok {
 		r.data[recordType] = map[model.RecordID][]model.Rating{}
 	}
-	r.data[recordType][recordID] = append(r.data[recordType][recordID], *rating)
+	r.data[recordType][recordID] = ratings
+	r.outbox.Add(events...)
+	if record != nil {
+		return r.audit.Append(ctx, record)
+	}
 	return nil
 }
+
+// Aggregates returns the rating statistics of every record, with trending scores decayed by halfLife as of now.
+func (r *Repository) Aggregates(ctx context.Context, now time.Time, halfLife time.Duration) ([]model.RecordAggregate, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var res []model.RecordAggregate
+	for recordType, records := range r.data {
+		for recordID, ratings := range records {
+			if len(ratings) == 0 {
+				continue
+			}
+			agg := model.RecordAggregate{
+				RecordID:    recordID,
+				RecordType:  recordType,
+				RatingCount: int64(len(ratings)),
+			}
+			var sum float64
+			for _, rating := range ratings {
+				sum += float64(rating.Value)
+				age := now.Sub(rating.CreatedAt).Seconds()
+				agg.TrendingScore += float64(rating.Value) * math.Exp(math.Max(math.Ln2*-age/halfLife.Seconds(), -700))
+			}
+			agg.AverageValue = sum / float64(len(ratings))
+			res = append(res, agg)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"main/outbox"
+	"main/rating/model"
+	eventsv1 "main/rpc/events/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EventSource is the CloudEvents source of the change events published by the rating service.
+const EventSource = "/rating"
+
+// ChangeEvents builds the outbox events recorded when a rating is written,
+// given the rating count and average of its record after the write.
+func ChangeEvents(recordID model.RecordID, recordType model.RecordType, rating *model.Rating, ratingCount int64, averageValue float64) ([]outbox.Event, error) {
+	ratingChanged, err := outbox.NewEvent(EventSource, string(recordType), string(recordID), &eventsv1.RatingChanged{
+		RecordId:   string(recordID),
+		RecordType: string(recordType),
+		UserId:     string(rating.UserID),
+		Value:      int64(rating.Value),
+		CreatedAt:  timestamppb.New(rating.CreatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregateChanged, err := outbox.NewEvent(EventSource, string(recordType), string(recordID), &eventsv1.AggregateChanged{
+		RecordId:     string(recordID),
+		RecordType:   string(recordType),
+		RatingCount:  ratingCount,
+		AverageValue: averageValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []outbox.Event{ratingChanged, aggregateChanged}, nil
+}
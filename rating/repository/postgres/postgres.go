@@ -2,35 +2,53 @@ package postgres
 
 import (
 	"context"
-	"go.opentelemetry.io/otel"
+	"main/audit"
+	auditpg "main/audit/postgres"
 	"main/database/db"
+	outboxpg "main/outbox/postgres"
 	"main/rating/model"
 	"main/rating/repository"
+	"time"
+
+	"go.opentelemetry.io/otel"
 )
 
 const tracerID = "rating-repository-postgres"
 
 // Repository defines a PostgreSQL-based movie metadata repository.
 type Repository struct {
-	db db.Store
+	db    db.Store
+	audit *auditpg.Store
 }
 
-// New creates a new PostgreSQL-based repository.
-func New(store db.Store) *Repository {
+// New creates a new PostgreSQL-based repository, appending the audit records of the writes to the audit store.
+// The audit store may be nil if the writes are not audited.
+func New(store db.Store, auditStore *auditpg.Store) *Repository {
 	return &Repository{
-		db: store,
+		db:    store,
+		audit: auditStore,
 	}
 }
 
-// Get retrieves all ratings for a given record.
-func (r *Repository) Get(ctx context.Context, movieId model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
-	_, span := otel.Tracer(tracerID).Start(ctx, "Repository/GET")
+// Get retrieves all ratings for a given record created at or after since. A zero since returns all ratings.
+func (r *Repository) Get(ctx context.Context, movieId model.RecordID, recordType model.RecordType, since time.Time) ([]model.Rating, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/GET")
 	defer span.End()
 
-	ratings, err := r.db.ListRatings(ctx, &db.ListRatingsParams{
-		MovieID:    string(movieId),
-		RecordType: string(recordType),
-	})
+	var ratings []*db.Rating
+	var err error
+	if since.IsZero() {
+		ratings, err = r.db.ListRatings(ctx, &db.ListRatingsParams{
+			MovieID:    string(movieId),
+			RecordType: string(recordType),
+		})
+	} else {
+		ratings, err = r.db.ListRatingsSince(ctx, &db.ListRatingsSinceParams{
+			MovieID:    string(movieId),
+			RecordType: string(recordType),
+			Since:      since,
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +56,12 @@ func (r *Repository) Get(ctx context.Context, movieId model.RecordID, recordType
 	var res []model.Rating
 	for _, rating := range ratings {
 		res = append(res, model.Rating{
-			UserID: model.UserID(rating.UserID),
-			Value:  model.RatingValue(rating.Value),
+			RecordID:   rating.MovieID,
+			RecordType: rating.RecordType,
+			UserID:     model.UserID(rating.UserID),
+			Value:      model.RatingValue(rating.Value),
+			CreatedAt:  rating.CreatedAt,
+			UpdatedAt:  rating.UpdatedAt,
 		})
 	}
 
@@ -50,17 +72,159 @@ func (r *Repository) Get(ctx context.Context, movieId model.RecordID, recordType
 	return res, nil
 }
 
-// Put adds a rating for a given record.
-func (r *Repository) Put(ctx context.Context, movieId model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	_, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT")
+// Put adds a rating for a given record, records its change events in the outbox and appends its audit record, if not
+// nil, in the same transaction. The id of the record is set once committed.
+func (r *Repository) Put(ctx context.Context, movieId model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT")
+	defer span.End()
+
+	auditParams, err := r.audit.TxParams(record)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.CreateRatingTx(ctx, db.CreateRatingTxParams{
+		Rating: db.CreateRatingParams{
+			MovieID:    string(movieId),
+			RecordType: string(recordType),
+			UserID:     string(rating.UserID),
+			Value:      int32(rating.Value),
+			CreatedAt:  rating.CreatedAt,
+		},
+		AfterCreate: changeEvents,
+		Audit:       auditParams,
+	})
+	if err != nil {
+		return err
+	}
+	setAuditID(record, result.AuditRecord)
+	return nil
+}
+
+// PutOnce adds a rating for a given record unless the event with the given id was already applied,
+// in which case it returns ErrDuplicateEvent. The event id, the rating, its change events and its audit record, if not
+// nil, are written in one transaction.
+func (r *Repository) PutOnce(ctx context.Context, eventID string, movieId model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT_ONCE")
 	defer span.End()
 
-	_, err := r.db.CreateRating(ctx, &db.CreateRatingParams{
-		MovieID:    string(movieId),
-		RecordType: string(recordType),
-		UserID:     string(rating.UserID),
-		Value:      int32(rating.Value),
+	auditParams, err := r.audit.TxParams(record)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.CreateRatingOnceTx(ctx, db.CreateRatingOnceTxParams{
+		EventID: eventID,
+		Rating: db.CreateRatingParams{
+			MovieID:    string(movieId),
+			RecordType: string(recordType),
+			UserID:     string(rating.UserID),
+			Value:      int32(rating.Value),
+			CreatedAt:  rating.CreatedAt,
+		},
+		AfterCreate: changeEvents,
+		Audit:       auditParams,
 	})
+	if err != nil {
+		return err
+	}
+	if result.Duplicate {
+		return repository.ErrDuplicateEvent
+	}
+	setAuditID(record, result.AuditRecord)
+	return nil
+}
+
+// PutOnceBatch adds the ratings of a batch of events in one transaction, skipping the events already applied.
+// It returns whether each event was a duplicate, in the order of the writes. If any write fails, none is applied.
+// The audit records of the writes, if any, are appended in the same transaction.
+func (r *Repository) PutOnceBatch(ctx context.Context, writes []model.RatingWrite) ([]bool, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/PUT_ONCE_BATCH")
+	defer span.End()
 
-	return err
+	arg := db.CreateRatingsOnceTxParams{Ratings: make([]db.CreateRatingOnceTxParams, 0, len(writes))}
+	for _, w := range writes {
+		auditParams, err := r.audit.TxParams(w.Audit)
+		if err != nil {
+			return nil, err
+		}
+		arg.Ratings = append(arg.Ratings, db.CreateRatingOnceTxParams{
+			EventID: w.EventID,
+			Rating: db.CreateRatingParams{
+				MovieID:    string(w.RecordID),
+				RecordType: string(w.RecordType),
+				UserID:     string(w.Rating.UserID),
+				Value:      int32(w.Rating.Value),
+				CreatedAt:  w.Rating.CreatedAt,
+			},
+			AfterCreate: changeEvents,
+			Audit:       auditParams,
+		})
+	}
+
+	results, err := r.db.CreateRatingsOnceTx(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := make([]bool, len(results))
+	for i, result := range results {
+		duplicates[i] = result.Duplicate
+		setAuditID(writes[i].Audit, result.AuditRecord)
+	}
+	return duplicates, nil
+}
+
+// PruneProcessedEvents forgets the events applied before the given time, returning how many were forgotten.
+func (r *Repository) PruneProcessedEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/PRUNE_PROCESSED_EVENTS")
+	defer span.End()
+
+	return r.db.DeleteProcessedEventsBefore(ctx, before)
+}
+
+// setAuditID sets the id of an audit record stored with a write.
+func setAuditID(record *audit.Record, row *db.AuditLog) {
+	if record != nil && row != nil {
+		record.ID = row.ID
+	}
+}
+
+// changeEvents builds the outbox events recorded with a created rating.
+func changeEvents(rating *db.Rating, aggregate *db.GetRatingAggregateRow) ([]*db.CreateOutboxEventParams, error) {
+	events, err := repository.ChangeEvents(model.RecordID(rating.MovieID), model.RecordType(rating.RecordType), &model.Rating{
+		RecordID:   rating.MovieID,
+		RecordType: rating.RecordType,
+		UserID:     model.UserID(rating.UserID),
+		Value:      model.RatingValue(rating.Value),
+		CreatedAt:  rating.CreatedAt,
+		UpdatedAt:  rating.UpdatedAt,
+	}, aggregate.RatingCount, aggregate.AverageValue)
+	if err != nil {
+		return nil, err
+	}
+	return outboxpg.Params(events), nil
+}
+
+// Aggregates returns the rating statistics of every record, with trending scores decayed by halfLife as of now.
+func (r *Repository) Aggregates(ctx context.Context, now time.Time, halfLife time.Duration) ([]model.RecordAggregate, error) {
+	ctx, span := otel.Tracer(tracerID).Start(ctx, "Repository/AGGREGATES")
+	defer span.End()
+
+	rows, err := r.db.ListRatingAggregates(ctx, &db.ListRatingAggregatesParams{
+		Now:             now,
+		HalfLifeSeconds: halfLife.Seconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.RecordAggregate, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, model.RecordAggregate{
+			RecordID:      model.RecordID(row.MovieID),
+			RecordType:    model.RecordType(row.RecordType),
+			RatingCount:   row.RatingCount,
+			AverageValue:  row.AverageValue,
+			TrendingScore: row.TrendingScore,
+		})
+	}
+	return res, nil
 }
@@ -0,0 +1,500 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"main/eventbus"
+	"main/eventcodec"
+	"main/rating/model"
+	"main/rating/repository"
+	eventsv1 "main/rpc/events/v1"
+	"main/tracing"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+const tracerID = "rating-service-consumer"
+
+// ConsumerHealthy reports whether at least one rating event consumer is subscribed and receiving messages.
+func (s *RatingService) ConsumerHealthy() bool {
+	return s.consuming.Load() > 0
+}
+
+// StartConsume starts ConsumerCount subscribers consuming the rating events until the context is cancelled.
+// Each subscriber dispatches its messages to ConsumerConcurrency workers by message key, so events of the same
+// record are applied in order while different records are processed in parallel.
+// Workers write their messages in batches of up to ConsumerBatchSize events in one transaction; a batch that fails
+// is retried event by event, so a poison message does not hold back the rest of its batch.
+// Messages that fail to be processed are negatively acknowledged and redelivered with an exponential backoff,
+// and moved to the dead letter topic once they were delivered MaxDeliveries times.
+func (s *RatingService) StartConsume(ctx context.Context) error {
+	count := s.cfg.ConsumerCount
+	if count < 1 {
+		count = 1
+	}
+
+	subs := make([]eventbus.Subscriber, 0, count)
+	defer func() {
+		for _, sub := range subs {
+			sub.Close()
+		}
+	}()
+	for i := 0; i < count; i++ {
+		sub, err := s.bus.Subscribe(eventbus.SubscriptionOptions{
+			Topic:             s.cfg.TopicName,
+			Subscription:      s.cfg.SubscriberName,
+			Type:              eventbus.SubscriptionType(s.cfg.SubscriptionType),
+			ReceiverQueueSize: s.cfg.ReceiverQueueSize,
+			MaxDeliveries:     s.cfg.MaxDeliveries,
+			DeadLetterTopic:   s.cfg.DeadLetterTopic,
+			NackBackoff:       &eventbus.ExponentialBackoff{Min: s.cfg.NackBackoffMin, Max: s.cfg.NackBackoffMax},
+		})
+		if err != nil {
+			return err
+		}
+		subs = append(subs, sub)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i, sub := range subs {
+		sub := sub
+		logger := slog.With(
+			slog.String("topic", s.cfg.TopicName),
+			slog.String("subscription", s.cfg.SubscriberName),
+			slog.Int("consumer", i),
+		)
+		g.Go(func() error {
+			return s.consume(ctx, sub, logger)
+		})
+	}
+
+	return g.Wait()
+}
+
+// consume receives messages from a subscriber and dispatches them to the workers until the context is cancelled.
+func (s *RatingService) consume(ctx context.Context, sub eventbus.Subscriber, logger *slog.Logger) error {
+	concurrency := s.cfg.ConsumerConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// A single worker of an exclusive subscription sees every message in order, so its batches can be
+	// acknowledged cumulatively.
+	subType := eventbus.SubscriptionType(s.cfg.SubscriptionType)
+	cumulative := concurrency == 1 && subType != eventbus.Shared && subType != eventbus.KeyShared
+
+	var wg sync.WaitGroup
+	workers := make([]chan *delivery, concurrency)
+	for i := range workers {
+		workers[i] = make(chan *delivery)
+		wg.Add(1)
+		go func(deliveries <-chan *delivery) {
+			defer wg.Done()
+			s.work(ctx, deliveries, newBatchAcker(sub, cumulative, s.metrics.inFlight), logger)
+		}(workers[i])
+	}
+	defer func() {
+		for _, w := range workers {
+			close(w)
+		}
+		wg.Wait()
+	}()
+
+	logger.Info("Started consuming rating events", slog.Int("concurrency", concurrency))
+
+	s.consuming.Add(1)
+	defer s.consuming.Add(-1)
+
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Info("Stopped consuming rating events")
+				return nil
+			}
+			return err
+		}
+		s.metrics.inFlight.Inc()
+		d := &delivery{msg: msg, dispatchedAt: time.Now()}
+		d.ctx, d.span = tracing.StartConsumerSpan(ctx, tracerID, s.cfg.TopicName, msg)
+
+		select {
+		case workers[workerIndex(msg, concurrency)] <- d:
+		case <-ctx.Done():
+			s.metrics.inFlight.Dec()
+			d.end(ctx.Err())
+			logger.Info("Stopped consuming rating events")
+			return nil
+		}
+	}
+}
+
+// delivery is a message handed to a worker, along with its rating event once decoded.
+// Its context carries the consumer span continuing the trace of the producer of the message.
+type delivery struct {
+	msg          eventbus.Message
+	dispatchedAt time.Time
+	ctx          context.Context
+	span         trace.Span
+	event        *model.RatingEvent
+	eventType    string
+}
+
+// end ends the consumer span of the delivery, recording the error it failed with if any.
+func (d *delivery) end(err error) {
+	if err != nil {
+		d.span.RecordError(err)
+		d.span.SetStatus(codes.Error, err.Error())
+	}
+	d.span.End()
+}
+
+// work applies the messages of a worker in batches of up to ConsumerBatchSize messages. A partial batch is
+// written once its first message waited ConsumerBatchMaxDelay.
+func (s *RatingService) work(ctx context.Context, deliveries <-chan *delivery, acker *batchAcker, logger *slog.Logger) {
+	size := s.cfg.ConsumerBatchSize
+	if size <= 1 {
+		for d := range deliveries {
+			if s.decode(acker, d, logger) {
+				s.process(ctx, acker, d, logger)
+			}
+		}
+		return
+	}
+
+	batch := make([]*delivery, 0, size)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	flush := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		s.processBatch(ctx, acker, batch, logger)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				// The messages of a partial batch are unacknowledged and get redelivered once the subscriber closes.
+				s.metrics.inFlight.Sub(float64(len(batch)))
+				for _, d := range batch {
+					d.end(ctx.Err())
+				}
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) == 1 {
+				timer.Reset(s.cfg.ConsumerBatchMaxDelay)
+			}
+			if len(batch) >= size {
+				flush()
+			}
+		case <-timer.C:
+			if len(batch) > 0 {
+				flush()
+			}
+		}
+	}
+}
+
+// processBatch writes the messages of a batch in one transaction and acknowledges them together.
+// If the batch fails, every message is processed on its own so only the failing ones are negatively acknowledged.
+func (s *RatingService) processBatch(ctx context.Context, acker *batchAcker, batch []*delivery, logger *slog.Logger) {
+	writes := make([]model.RatingWrite, 0, len(batch))
+	decoded := make([]*delivery, 0, len(batch))
+	spans := make([]trace.Span, 0, len(batch))
+	for _, d := range batch {
+		if !s.decode(acker, d, logger) {
+			continue
+		}
+		writes = append(writes, *s.ratingWrite(d.ctx, d.msg, d.event))
+		decoded = append(decoded, d)
+		spans = append(spans, d.span)
+	}
+	if len(writes) == 0 {
+		return
+	}
+
+	batchCtx, span := tracing.StartBatchSpan(ctx, tracerID, s.cfg.TopicName, spans)
+	duplicates, err := s.repo.PutOnceBatch(batchCtx, writes)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	if err != nil {
+		s.metrics.batchRetries.Inc()
+		logger.Warn("failed to write rating event batch, retrying event by event:", slog.Int("size", len(decoded)), slog.String("error", err.Error()))
+		for _, d := range decoded {
+			s.process(ctx, acker, d, logger)
+		}
+		return
+	}
+	now := time.Now()
+	s.metrics.batchSize.Observe(float64(len(decoded)))
+
+	msgs := make([]eventbus.Message, 0, len(decoded))
+	for i, d := range decoded {
+		msgs = append(msgs, d.msg)
+		if !duplicates[i] {
+			s.auditor.Committed(writes[i].Audit)
+		}
+		s.metrics.processedMessage(d.msg, d.eventType, duplicates[i], d.dispatchedAt, now)
+		d.end(nil)
+	}
+	if err := acker.ackBatch(msgs); err != nil {
+		logger.Warn("failed to acknowledge rating event batch:", slog.String("error", err.Error()))
+	}
+	logger.Debug("Processed rating event batch", slog.Int("size", len(msgs)))
+}
+
+// decode decodes the rating event of a delivery. Messages that cannot be decoded are negatively acknowledged.
+func (s *RatingService) decode(acker *batchAcker, d *delivery, logger *slog.Logger) bool {
+	logger.Debug("Received rating event",
+		slog.String("msg_id", d.msg.ID()),
+		slog.Uint64("redelivery_count", uint64(d.msg.RedeliveryCount())),
+		slog.String("payload", string(d.msg.Payload())),
+	)
+
+	event, err := decodeRatingEvent(d.msg)
+	d.event = event
+	d.eventType = eventTypeLabel(event)
+	s.metrics.receivedMessage(d.msg, d.eventType)
+	if err != nil {
+		s.fail(acker, d, err, logger)
+		return false
+	}
+	return true
+}
+
+// process applies a single decoded message and acknowledges it, or negatively acknowledges it on failure.
+func (s *RatingService) process(ctx context.Context, acker *batchAcker, d *delivery, logger *slog.Logger) {
+	msgLogger := logger.With(
+		slog.String("msg_id", d.msg.ID()),
+		slog.Uint64("redelivery_count", uint64(d.msg.RedeliveryCount())),
+	)
+
+	write := s.ratingWrite(d.ctx, d.msg, d.event)
+	err := s.apply(d.ctx, write)
+	duplicate := errors.Is(err, ErrDuplicateEvent)
+	if duplicate {
+		msgLogger.Debug("Skipping already processed rating event")
+		err = nil
+	}
+	if err != nil {
+		s.fail(acker, d, err, logger)
+		return
+	}
+	if !duplicate {
+		s.auditor.Committed(write.Audit)
+	}
+	s.metrics.processedMessage(d.msg, d.eventType, duplicate, d.dispatchedAt, time.Now())
+	d.span.SetAttributes(attribute.Bool("rating.duplicate", duplicate))
+	d.end(nil)
+
+	if err := acker.ack(d.msg); err != nil {
+		msgLogger.Warn("failed to acknowledge rating event:", slog.String("error", err.Error()))
+	}
+}
+
+// fail negatively acknowledges a message that failed to be processed.
+func (s *RatingService) fail(acker *batchAcker, d *delivery, err error, logger *slog.Logger) {
+	msgLogger := logger.With(
+		slog.String("msg_id", d.msg.ID()),
+		slog.Uint64("redelivery_count", uint64(d.msg.RedeliveryCount())),
+	)
+	s.metrics.failed.WithLabelValues(d.eventType).Inc()
+	deadLettered := s.cfg.MaxDeliveries > 0 && d.msg.RedeliveryCount()+1 >= s.cfg.MaxDeliveries
+	if deadLettered {
+		s.metrics.deadLettered.WithLabelValues(d.eventType).Inc()
+		msgLogger.Error("failed to process rating event, moving it to the dead letter topic:", slog.String("error", err.Error()))
+	} else {
+		msgLogger.Warn("failed to process rating event, scheduling redelivery:", slog.String("error", err.Error()))
+	}
+	acker.nack(d.msg, !deadLettered)
+	d.end(err)
+}
+
+// batchAcker acknowledges the messages of a worker. When cumulative, batches are acknowledged at once with their
+// last message, unless earlier messages are still awaiting redelivery: a cumulative acknowledgement would drop them.
+type batchAcker struct {
+	sub          eventbus.Subscriber
+	cumulative   bool
+	redelivering map[string]struct{}
+	inFlight     prometheus.Gauge
+}
+
+func newBatchAcker(sub eventbus.Subscriber, cumulative bool, inFlight prometheus.Gauge) *batchAcker {
+	return &batchAcker{sub: sub, cumulative: cumulative, redelivering: map[string]struct{}{}, inFlight: inFlight}
+}
+
+// ack acknowledges a single message.
+func (a *batchAcker) ack(msg eventbus.Message) error {
+	a.inFlight.Dec()
+	delete(a.redelivering, msg.ID())
+	return a.sub.Ack(msg)
+}
+
+// ackBatch acknowledges the messages of a batch, in receive order.
+func (a *batchAcker) ackBatch(msgs []eventbus.Message) error {
+	a.inFlight.Sub(float64(len(msgs)))
+	for _, msg := range msgs {
+		delete(a.redelivering, msg.ID())
+	}
+	if a.cumulative && len(a.redelivering) == 0 {
+		return a.sub.AckCumulative(msgs[len(msgs)-1])
+	}
+
+	var errs []error
+	for _, msg := range msgs {
+		if err := a.sub.Ack(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nack negatively acknowledges a message, tracking it until it is redelivered and acknowledged if redeliver is set.
+func (a *batchAcker) nack(msg eventbus.Message, redeliver bool) {
+	a.inFlight.Dec()
+	if redeliver {
+		a.redelivering[msg.ID()] = struct{}{}
+	} else {
+		delete(a.redelivering, msg.ID())
+	}
+	a.sub.Nack(msg)
+}
+
+// workerIndex picks the worker of a message by hashing its key, so messages with the same key share a worker.
+func workerIndex(msg eventbus.Message, workers int) int {
+	key := msg.Key()
+	if key == "" {
+		key = msg.ID()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// HandleMessage decodes a rating event and applies it exactly once, returning ErrDuplicateEvent if it was already applied.
+// Events without an id are deduplicated by their broker message id, which is stable across redeliveries.
+func (s *RatingService) HandleMessage(ctx context.Context, msg eventbus.Message) error {
+	event, err := decodeRatingEvent(msg)
+	if err != nil {
+		return err
+	}
+	return s.apply(ctx, s.ratingWrite(ctx, msg, event))
+}
+
+// apply writes the rating of an event unless it was already applied, in which case it returns ErrDuplicateEvent.
+func (s *RatingService) apply(ctx context.Context, write *model.RatingWrite) error {
+	err := s.repo.PutOnce(ctx, write.EventID, write.RecordID, write.RecordType, write.Rating, write.Audit)
+	if errors.Is(err, repository.ErrDuplicateEvent) {
+		return ErrDuplicateEvent
+	}
+	return err
+}
+
+// StartProcessedEventsPrune periodically forgets the events applied longer than ProcessedEventsRetention ago,
+// until the context is cancelled. Their redeliveries and replays are no longer skipped once forgotten.
+func (s *RatingService) StartProcessedEventsPrune(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.ProcessedEventsPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		pruned, err := s.repo.PruneProcessedEvents(ctx, time.Now().Add(-s.cfg.ProcessedEventsRetention))
+		if err != nil {
+			slog.Error("failed to prune processed events:", slog.String("error", err.Error()))
+		} else if pruned > 0 {
+			slog.Info("Pruned processed events", slog.Int64("count", pruned))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ratingWrite returns the rating a decoded event writes once, with its audit record. Events without an id are
+// deduplicated by the id of their message, which is unique even among the messages of a broker batch.
+func (s *RatingService) ratingWrite(ctx context.Context, msg eventbus.Message, event *model.RatingEvent) *model.RatingWrite {
+	eventID := event.ID
+	if eventID == "" {
+		eventID = msg.ID()
+	}
+	occurredAt := event.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = eventTime(msg)
+	}
+
+	write := &model.RatingWrite{
+		EventID:    eventID,
+		RecordID:   event.RecordID,
+		RecordType: event.RecordType,
+		Rating: &model.Rating{
+			RecordID:   string(event.RecordID),
+			RecordType: string(event.RecordType),
+			UserID:     event.UserID,
+			Value:      event.Value,
+			CreatedAt:  occurredAt,
+			UpdatedAt:  occurredAt,
+		},
+	}
+	write.Audit = s.auditor.NewRecord(ctx, "ConsumeRatingEvent", auditResourceType, auditResourceID(write.RecordID, write.RecordType, write.Rating), nil, write.Rating)
+	return write
+}
+
+// decodeRatingEvent decodes a rating event according to the content type property of the message.
+// Messages without a content type are legacy JSON encoded RatingEvent values.
+func decodeRatingEvent(msg eventbus.Message) (*model.RatingEvent, error) {
+	contentType := msg.Properties()[eventcodec.PropertyContentType]
+	if contentType == "" {
+		var event model.RatingEvent
+		if err := json.Unmarshal(msg.Payload(), &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	env, data, err := eventcodec.Decode(contentType, msg.Payload())
+	if err != nil {
+		return nil, err
+	}
+	e, ok := data.(*eventsv1.RatingEvent)
+	if !ok {
+		return nil, fmt.Errorf("unexpected event type %q", env.Type)
+	}
+	event := model.RatingEventFromProto(e)
+	event.ID = env.ID
+	event.OccurredAt = env.Time
+	return event, nil
+}
+
+// eventTime returns the time a rating event happened, falling back to the publish time when the producer did not set one.
+func eventTime(msg eventbus.Message) time.Time {
+	if t := msg.EventTime(); !t.IsZero() {
+		return t
+	}
+	return msg.PublishTime()
+}
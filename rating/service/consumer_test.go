@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"main/audit"
+	"main/config"
+	"main/eventbus"
+	"main/eventbus/memory"
+	"main/rating/model"
+	ratingmemory "main/rating/repository/memory"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ackLog records the keys of the messages acknowledged and negatively acknowledged by the consumer.
+type ackLog struct {
+	sync.Mutex
+	acked  []string
+	nacked []string
+}
+
+func (l *ackLog) keys() (acked, nacked []string) {
+	l.Lock()
+	defer l.Unlock()
+	return append([]string(nil), l.acked...), append([]string(nil), l.nacked...)
+}
+
+// recordingBus records the acknowledgements of its subscribers in its log.
+type recordingBus struct {
+	*memory.Bus
+	log *ackLog
+}
+
+func (b *recordingBus) Subscribe(opts eventbus.SubscriptionOptions) (eventbus.Subscriber, error) {
+	sub, err := b.Bus.Subscribe(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingSubscriber{Subscriber: sub, log: b.log}, nil
+}
+
+type recordingSubscriber struct {
+	eventbus.Subscriber
+	log *ackLog
+}
+
+func (s *recordingSubscriber) Ack(msg eventbus.Message) error {
+	s.log.Lock()
+	s.log.acked = append(s.log.acked, msg.Key())
+	s.log.Unlock()
+	return s.Subscriber.Ack(msg)
+}
+
+func (s *recordingSubscriber) Nack(msg eventbus.Message) {
+	s.log.Lock()
+	s.log.nacked = append(s.log.nacked, msg.Key())
+	s.log.Unlock()
+	s.Subscriber.Nack(msg)
+}
+
+// failingRepository fails the writes of the ratings of a record, and records the sizes of the batches written.
+type failingRepository struct {
+	*ratingmemory.Repository
+	recordID model.RecordID
+
+	sync.Mutex
+	batches []int
+}
+
+func (r *failingRepository) PutOnce(ctx context.Context, eventID string, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error {
+	if recordID == r.recordID {
+		return errors.New("constraint violation")
+	}
+	return r.Repository.PutOnce(ctx, eventID, recordID, recordType, rating, record)
+}
+
+func (r *failingRepository) PutOnceBatch(ctx context.Context, writes []model.RatingWrite) ([]bool, error) {
+	r.Lock()
+	r.batches = append(r.batches, len(writes))
+	r.Unlock()
+	for _, w := range writes {
+		if w.RecordID == r.recordID {
+			return nil, errors.New("constraint violation")
+		}
+	}
+	return r.Repository.PutOnceBatch(ctx, writes)
+}
+
+func newTestConsumer(t *testing.T, repo ratingRepository, batchSize int, auditor *audit.Logger) (*memory.Bus, *ackLog) {
+	t.Helper()
+	bus := memory.New()
+	t.Cleanup(bus.Close)
+	acks := &ackLog{}
+	cfg := &config.Rating{RatingConsumer: config.RatingConsumer{
+		TopicName:             "ratings",
+		SubscriberName:        "test",
+		SubscriptionType:      string(eventbus.KeyShared),
+		ConsumerCount:         1,
+		ConsumerConcurrency:   1,
+		ConsumerBatchSize:     batchSize,
+		ConsumerBatchMaxDelay: 20 * time.Millisecond,
+		MaxDeliveries:         2,
+		DeadLetterTopic:       "ratings-dlq",
+		NackBackoffMin:        time.Millisecond,
+		NackBackoffMax:        time.Millisecond,
+	}}
+	svc := New(repo, &recordingBus{Bus: bus, log: acks}, cfg, auditor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.StartConsume(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	require.Eventually(t, svc.ConsumerHealthy, time.Second, time.Millisecond)
+	return bus, acks
+}
+
+// publishEvents publishes rating events of the movie with the given id, or raw payloads for strings.
+func publishEvents(t *testing.T, bus *memory.Bus, events ...any) {
+	t.Helper()
+	publisher, err := bus.Publisher("ratings")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	for _, e := range events {
+		msg := &eventbus.OutgoingMessage{}
+		switch e := e.(type) {
+		case string:
+			msg.Key, msg.Payload = e, []byte(e)
+		case *model.RatingEvent:
+			payload, err := json.Marshal(e)
+			require.NoError(t, err)
+			msg.Key, msg.Payload = string(e.RecordID), payload
+		}
+		_, err := publisher.Publish(context.Background(), msg)
+		require.NoError(t, err)
+	}
+}
+
+func ratingEvent(id string, recordID model.RecordID, value model.RatingValue) *model.RatingEvent {
+	return &model.RatingEvent{
+		ID:         id,
+		UserID:     "alice",
+		RecordID:   recordID,
+		RecordType: model.RecordTypeMovie,
+		Value:      value,
+		EventType:  model.RatingEventTypePut,
+	}
+}
+
+func receiveDeadLetter(t *testing.T, bus *memory.Bus) eventbus.Message {
+	t.Helper()
+	dlq, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings-dlq", Subscription: "test"})
+	require.NoError(t, err)
+	defer dlq.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := dlq.Receive(ctx)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestConsumerDeadLettersPoisonEvent(t *testing.T) {
+	repo := ratingmemory.New()
+	bus, _ := newTestConsumer(t, repo, 1, nil)
+	publishEvents(t, bus, "not a rating event", ratingEvent("e1", "alien", 5))
+
+	dead := receiveDeadLetter(t, bus)
+	require.Equal(t, "not a rating event", string(dead.Payload()))
+
+	require.Eventually(t, func() bool {
+		ratings, err := repo.Get(context.Background(), "alien", model.RecordTypeMovie, time.Time{})
+		return err == nil && len(ratings) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestConsumerDeadLettersFailingEventOfBatch(t *testing.T) {
+	repo := &failingRepository{Repository: ratingmemory.New(), recordID: "poison"}
+	bus, acks := newTestConsumer(t, repo, 3, nil)
+	publishEvents(t, bus, ratingEvent("e1", "alien", 5), ratingEvent("e2", "poison", 1), ratingEvent("e3", "heat", 4))
+
+	dead := receiveDeadLetter(t, bus)
+	var event model.RatingEvent
+	require.NoError(t, json.Unmarshal(dead.Payload(), &event))
+	require.Equal(t, "e2", event.ID)
+
+	// The failed batch is retried event by event: the other events are acknowledged, the failing one is
+	// negatively acknowledged on each of its MaxDeliveries deliveries, then dead lettered.
+	require.Eventually(t, func() bool {
+		_, nacked := acks.keys()
+		return len(nacked) == 2
+	}, time.Second, 5*time.Millisecond)
+	acked, nacked := acks.keys()
+	require.ElementsMatch(t, []string{"alien", "heat"}, acked)
+	require.Equal(t, []string{"poison", "poison"}, nacked)
+
+	repo.Lock()
+	require.Equal(t, 3, repo.batches[0])
+	repo.Unlock()
+	for _, recordID := range []model.RecordID{"alien", "heat"} {
+		ratings, err := repo.Get(context.Background(), recordID, model.RecordTypeMovie, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, ratings, 1)
+	}
+}
+
+func TestConsumerAuditsAppliedRatings(t *testing.T) {
+	for _, batchSize := range []int{1, 3} {
+		repo := ratingmemory.New()
+		bus, acks := newTestConsumer(t, repo, batchSize, audit.New("rating", repo.Audit()))
+		publishEvents(t, bus, ratingEvent("e1", "alien", 5), ratingEvent("e1", "alien", 5), ratingEvent("e2", "heat", 4))
+
+		require.Eventually(t, func() bool {
+			acked, _ := acks.keys()
+			return len(acked) == 3
+		}, time.Second, 5*time.Millisecond)
+
+		// The duplicate event is not audited again.
+		records, err := repo.Audit().Query(context.Background(), audit.Filter{})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.ElementsMatch(t, []string{"movie/alien/alice", "movie/heat/alice"}, []string{records[0].ResourceID, records[1].ResourceID})
+		for _, record := range records {
+			require.Equal(t, "ConsumeRatingEvent", record.Method)
+			require.Equal(t, audit.OutcomeSuccess, record.Outcome)
+			require.NotEmpty(t, record.Changes)
+		}
+	}
+}
+
+// stubMessage is a message with the given id.
+type stubMessage struct {
+	eventbus.Message
+	id string
+}
+
+func (m *stubMessage) ID() string { return m.id }
+
+// cumulativeSubscriber records the messages acknowledged cumulatively.
+type cumulativeSubscriber struct {
+	eventbus.Subscriber
+	cumulative []string
+}
+
+func (s *cumulativeSubscriber) Ack(eventbus.Message) error { return nil }
+func (s *cumulativeSubscriber) Nack(eventbus.Message)      {}
+func (s *cumulativeSubscriber) AckCumulative(msg eventbus.Message) error {
+	s.cumulative = append(s.cumulative, msg.ID())
+	return nil
+}
+
+func TestBatchAckerKeepsNackedMessageOfBatch(t *testing.T) {
+	sub := &cumulativeSubscriber{}
+	acker := newBatchAcker(sub, true, newConsumerMetrics().inFlight)
+
+	// Two messages of one Pulsar batch share their entry: the second is told apart by its batch index.
+	nacked, sibling := &stubMessage{id: "7:3:0"}, &stubMessage{id: "7:3:0:1"}
+	acker.nack(nacked, true)
+	require.NoError(t, acker.ack(sibling))
+	require.NoError(t, acker.ackBatch([]eventbus.Message{&stubMessage{id: "7:4:0"}}))
+	require.Empty(t, sub.cumulative)
+
+	require.NoError(t, acker.ack(&stubMessage{id: "7:3:0"}))
+	require.NoError(t, acker.ackBatch([]eventbus.Message{&stubMessage{id: "7:5:0"}}))
+	require.Equal(t, []string{"7:5:0"}, sub.cumulative)
+}
+
+func TestStartProcessedEventsPrune(t *testing.T) {
+	repo := ratingmemory.New()
+	cfg := &config.Rating{RatingConsumer: config.RatingConsumer{ProcessedEventsRetention: time.Hour, ProcessedEventsPruneInterval: time.Hour}}
+	svc := New(repo, nil, cfg, nil)
+	write := func() error {
+		return svc.apply(context.Background(), &model.RatingWrite{
+			EventID:    "e1",
+			RecordID:   "alien",
+			RecordType: model.RecordTypeMovie,
+			Rating:     &model.Rating{UserID: "alice", Value: 5, CreatedAt: time.Now()},
+		})
+	}
+	require.NoError(t, write())
+
+	// A cancelled context prunes once.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.StartProcessedEventsPrune(ctx)
+	require.ErrorIs(t, write(), ErrDuplicateEvent)
+
+	cfg.ProcessedEventsRetention = -time.Minute
+	svc.StartProcessedEventsPrune(ctx)
+	require.NoError(t, write())
+}
@@ -2,41 +2,71 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"main/audit"
+	"main/auth"
+	"main/config"
+	"main/eventbus"
 	"main/rating/model"
 	"main/rating/repository"
-	"main/util"
-
-	"github.com/apache/pulsar-client-go/pulsar"
+	"sync/atomic"
+	"time"
 )
 
-// ErrNotFound is returned when no ratings are found for a record.
-var ErrNotFound = errors.New("ratings not found for a record")
+var (
+	// ErrNotFound is returned when no ratings are found for a record.
+	ErrNotFound = errors.New("ratings not found for a record")
+	// ErrDuplicateEvent is returned when a rating event was already applied.
+	ErrDuplicateEvent = errors.New("rating event already applied")
+)
 
 type ratingRepository interface {
-	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
-	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType, since time.Time) ([]model.Rating, error)
+	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error
+	PutOnce(ctx context.Context, eventID string, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, record *audit.Record) error
+	PutOnceBatch(ctx context.Context, writes []model.RatingWrite) ([]bool, error)
+	PruneProcessedEvents(ctx context.Context, before time.Time) (int64, error)
+	Aggregates(ctx context.Context, now time.Time, halfLife time.Duration) ([]model.RecordAggregate, error)
+}
+
+type eventSubscriber interface {
+	Subscribe(opts eventbus.SubscriptionOptions) (eventbus.Subscriber, error)
 }
 
 // RatingService defines a rating service controller.
 type RatingService struct {
-	repo ratingRepository
-	cfg  *util.ConfigDatabase
+	repo      ratingRepository
+	bus       eventSubscriber
+	cfg       *config.Rating
+	board     leaderboard
+	metrics   *consumerMetrics
+	consuming atomic.Int32
+	auditor   *audit.Logger
 }
 
-// New creates a rating service controller.
-func New(repo ratingRepository, config *util.ConfigDatabase) *RatingService {
+// New creates a rating service controller. The ratings put through the API and consumed from the event bus are
+// audited with the auditor, if not nil, their records being stored with them. The consumed ratings failing to be
+// written are not audited, the dead letter topic keeping their events.
+func New(repo ratingRepository, bus eventSubscriber, cfg *config.Rating, auditor *audit.Logger) *RatingService {
 	return &RatingService{
-		repo: repo,
-		cfg:  config,
+		repo:    repo,
+		bus:     bus,
+		cfg:     cfg,
+		metrics: newConsumerMetrics(),
+		auditor: auditor,
 	}
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
-func (s *RatingService) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	ratings, err := s.repo.Get(ctx, recordID, recordType)
+// A positive window restricts the aggregation to ratings created within that duration from now.
+func (s *RatingService) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, window time.Duration) (float64, error) {
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	ratings, err := s.repo.Get(ctx, recordID, recordType, since)
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return 0, ErrNotFound
 	} else if err != nil {
@@ -49,57 +79,49 @@ func (s *RatingService) GetAggregatedRating(ctx context.Context, recordID model.
 	return sum / float64(len(ratings)), nil
 }
 
-// PutRating writes a rating for a given record
+// PutRating writes a rating for a given record from the principal of ctx. Ratings without a user id are from the
+// principal, and ratings from another user are refused with auth.ErrPermissionDenied. Ratings without a principal
+// are refused with auth.ErrUnauthenticated, unless requests are not authenticated and the rating has a user id.
+// Ratings without a creation time are stamped with the current time.
 func (s *RatingService) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	return s.repo.Put(ctx, recordID, recordType, rating)
-}
-
-// StartConsume starts consuming the rating events.
-func (s *RatingService) StartConsume(ctx context.Context) error {
-	client, err := pulsar.NewClient(pulsar.ClientOptions{
-		URL:               s.cfg.PulsarURL,
-		ConnectionTimeout: s.cfg.ConnectionTimeout,
-		OperationTimeout:  s.cfg.OperationTimeout,
-	})
-	if err != nil {
+	if err := s.authorizeRating(ctx, rating); err != nil {
 		return err
 	}
-	defer client.Close()
-
-	channel := make(chan pulsar.ConsumerMessage, 100)
-
-	options := pulsar.ConsumerOptions{
-		Topic:            s.cfg.TopicName,
-		SubscriptionName: s.cfg.SubscriberName,
-		Type:             pulsar.Exclusive,
-		MessageChannel:   channel,
+	if rating.CreatedAt.IsZero() {
+		rating.CreatedAt = time.Now()
 	}
-
-	consumer, err := client.Subscribe(options)
-	if err != nil {
+	if rating.UpdatedAt.IsZero() {
+		rating.UpdatedAt = rating.CreatedAt
+	}
+	record := s.auditor.NewRecord(ctx, "PutRating", auditResourceType, auditResourceID(recordID, recordType, rating), nil, rating)
+	if err := s.repo.Put(ctx, recordID, recordType, rating, record); err != nil {
+		s.auditor.Failed(ctx, record, err)
 		return err
 	}
-	defer consumer.Close()
-
-	for cm := range channel {
-		consumer := cm.Consumer
-		msg := cm.Message
-		fmt.Printf("Consumer %s received a message, msgId: %v, content: %s\n", consumer.Name(), msg.ID(), string(msg.Payload()))
-
-		var event model.RatingEvent
-		if err := json.Unmarshal(msg.Payload(), &event); err != nil {
-			return err
-		}
+	s.auditor.Committed(record)
+	return nil
+}
 
-		if err := s.PutRating(ctx, event.RecordID, event.RecordType, &model.Rating{
-			UserID: event.UserID,
-			Value:  event.Value,
-		}); err != nil {
-			return err
+// authorizeRating sets the user id of a rating to the principal of ctx, failing if the rating is from another user.
+func (s *RatingService) authorizeRating(ctx context.Context, rating *model.Rating) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		if s.cfg.AuthEnabled || rating.UserID == "" {
+			return fmt.Errorf("%w: cannot rate without a user", auth.ErrUnauthenticated)
 		}
-
-		consumer.Ack(msg)
+		return nil
 	}
-
+	if rating.UserID != "" && rating.UserID != model.UserID(p.Subject) {
+		return fmt.Errorf("%w: cannot rate as another user", auth.ErrPermissionDenied)
+	}
+	rating.UserID = model.UserID(p.Subject)
 	return nil
 }
+
+// auditResourceType is the resource type of the audit records of the ratings.
+const auditResourceType = "rating"
+
+// auditResourceID returns the resource id of the audit record of a rating.
+func auditResourceID(recordID model.RecordID, recordType model.RecordType, rating *model.Rating) string {
+	return fmt.Sprintf("%s/%s/%s", recordType, recordID, rating.UserID)
+}
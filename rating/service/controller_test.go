@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"main/auth"
+	"main/config"
+	"main/rating/model"
+	"main/rating/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutRatingAsPrincipal(t *testing.T) {
+	repo := memory.New()
+	cfg := &config.Rating{}
+	cfg.AuthEnabled = true
+	svc := New(repo, nil, cfg, nil)
+	alice := auth.NewContext(context.Background(), &auth.Principal{Subject: "alice"})
+
+	require.ErrorIs(t, svc.PutRating(context.Background(), "alien", model.RecordTypeMovie, &model.Rating{UserID: "alice", Value: 5}), auth.ErrUnauthenticated)
+	require.ErrorIs(t, svc.PutRating(alice, "alien", model.RecordTypeMovie, &model.Rating{UserID: "bob", Value: 1}), auth.ErrPermissionDenied)
+	require.NoError(t, svc.PutRating(alice, "alien", model.RecordTypeMovie, &model.Rating{Value: 4}))
+
+	ratings, err := repo.Get(context.Background(), "alien", model.RecordTypeMovie, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, ratings, 1)
+	require.Equal(t, model.UserID("alice"), ratings[0].UserID)
+	require.Equal(t, model.RatingValue(4), ratings[0].Value)
+}
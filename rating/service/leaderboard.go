@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"main/rating/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// leaderboard holds ranked snapshots of record aggregates, grouped by record type.
+type leaderboard struct {
+	sync.RWMutex
+	// refresh serializes the refreshes of the snapshots.
+	refresh     sync.Mutex
+	topRated    map[model.RecordType][]model.RecordAggregate
+	trending    map[model.RecordType][]model.RecordAggregate
+	refreshedAt time.Time
+}
+
+// ListTopRated returns a page of records of the given type ordered by average rating, skipping records with fewer
+// than minCount ratings. It also returns the offset of the next page, or zero when there are no more records.
+func (s *RatingService) ListTopRated(ctx context.Context, recordType model.RecordType, minCount int64, offset, limit int) ([]model.RecordAggregate, int, error) {
+	if err := s.ensureLeaderboard(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	s.board.RLock()
+	defer s.board.RUnlock()
+
+	var ranked []model.RecordAggregate
+	for _, agg := range s.board.topRated[recordType] {
+		if agg.RatingCount >= minCount {
+			ranked = append(ranked, agg)
+		}
+	}
+	page, next := paginate(ranked, offset, limit)
+	return page, next, nil
+}
+
+// ListTrending returns a page of records of the given type ordered by their time-decayed rating velocity.
+// It also returns the offset of the next page, or zero when there are no more records.
+func (s *RatingService) ListTrending(ctx context.Context, recordType model.RecordType, offset, limit int) ([]model.RecordAggregate, int, error) {
+	if err := s.ensureLeaderboard(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	s.board.RLock()
+	defer s.board.RUnlock()
+
+	page, next := paginate(s.board.trending[recordType], offset, limit)
+	return page, next, nil
+}
+
+// RefreshLeaderboards recomputes the top-rated and trending rankings from the repository.
+func (s *RatingService) RefreshLeaderboards(ctx context.Context) error {
+	s.board.refresh.Lock()
+	defer s.board.refresh.Unlock()
+	return s.refreshLeaderboards(ctx)
+}
+
+func (s *RatingService) refreshLeaderboards(ctx context.Context) error {
+	now := time.Now()
+	aggregates, err := s.repo.Aggregates(ctx, now, s.cfg.TrendingHalfLife)
+	if err != nil {
+		return err
+	}
+
+	topRated := map[model.RecordType][]model.RecordAggregate{}
+	trending := map[model.RecordType][]model.RecordAggregate{}
+	for _, agg := range aggregates {
+		topRated[agg.RecordType] = append(topRated[agg.RecordType], agg)
+		trending[agg.RecordType] = append(trending[agg.RecordType], agg)
+	}
+	for recordType := range topRated {
+		sortAggregates(topRated[recordType], func(a, b model.RecordAggregate) bool {
+			if a.AverageValue != b.AverageValue {
+				return a.AverageValue > b.AverageValue
+			}
+			return a.RatingCount > b.RatingCount
+		})
+		sortAggregates(trending[recordType], func(a, b model.RecordAggregate) bool {
+			return a.TrendingScore > b.TrendingScore
+		})
+	}
+
+	s.board.Lock()
+	defer s.board.Unlock()
+
+	s.board.topRated = topRated
+	s.board.trending = trending
+	s.board.refreshedAt = now
+	return nil
+}
+
+// StartLeaderboardRefresh periodically refreshes the leaderboards until the context is cancelled.
+func (s *RatingService) StartLeaderboardRefresh(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.LeaderboardRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RefreshLeaderboards(ctx); err != nil {
+			slog.Error("failed to refresh leaderboards:", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureLeaderboard computes the leaderboards if they were never computed. Once computed, they are served as they
+// are, even if stale, while StartLeaderboardRefresh refreshes them in the background.
+func (s *RatingService) ensureLeaderboard(ctx context.Context) error {
+	if s.leaderboardComputed() {
+		return nil
+	}
+
+	s.board.refresh.Lock()
+	defer s.board.refresh.Unlock()
+
+	// Concurrent requests wait for the first one to compute the leaderboards.
+	if s.leaderboardComputed() {
+		return nil
+	}
+	return s.refreshLeaderboards(ctx)
+}
+
+func (s *RatingService) leaderboardComputed() bool {
+	s.board.RLock()
+	defer s.board.RUnlock()
+	return !s.board.refreshedAt.IsZero()
+}
+
+func sortAggregates(aggs []model.RecordAggregate, less func(a, b model.RecordAggregate) bool) {
+	sort.SliceStable(aggs, func(i, j int) bool {
+		if less(aggs[i], aggs[j]) {
+			return true
+		}
+		if less(aggs[j], aggs[i]) {
+			return false
+		}
+		return aggs[i].RecordID < aggs[j].RecordID
+	})
+}
+
+func paginate(aggs []model.RecordAggregate, offset, limit int) ([]model.RecordAggregate, int) {
+	if offset >= len(aggs) {
+		return nil, 0
+	}
+	end := offset + limit
+	if end >= len(aggs) {
+		return aggs[offset:], 0
+	}
+	return aggs[offset:end], end
+}
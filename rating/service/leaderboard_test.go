@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"main/config"
+	"main/rating/model"
+	"main/rating/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*RatingService, *memory.Repository) {
+	t.Helper()
+	repo := memory.New()
+	cfg := &config.Rating{LeaderboardRefreshInterval: time.Minute, TrendingHalfLife: 72 * time.Hour}
+	return New(repo, nil, cfg, nil), repo
+}
+
+func putRatings(t *testing.T, svc *RatingService, recordID model.RecordID, values ...model.RatingValue) {
+	t.Helper()
+	for i, v := range values {
+		require.NoError(t, svc.PutRating(context.Background(), recordID, model.RecordTypeMovie, &model.Rating{
+			RecordID:   string(recordID),
+			RecordType: string(model.RecordTypeMovie),
+			UserID:     model.UserID(fmt.Sprintf("%s-user-%d", recordID, i)),
+			Value:      v,
+		}))
+	}
+}
+
+func recordIDs(aggs []model.RecordAggregate) []model.RecordID {
+	var res []model.RecordID
+	for _, agg := range aggs {
+		res = append(res, agg.RecordID)
+	}
+	return res
+}
+
+func TestListTopRated(t *testing.T) {
+	svc, _ := newTestService(t)
+	putRatings(t, svc, "alien", 5, 5)
+	putRatings(t, svc, "heat", 4, 5)
+	putRatings(t, svc, "jaws", 3, 4, 5)
+	putRatings(t, svc, "solo", 5)
+
+	page, next, err := svc.ListTopRated(context.Background(), model.RecordTypeMovie, 2, 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, []model.RecordID{"alien", "heat"}, recordIDs(page))
+	require.Equal(t, 2, next)
+
+	page, next, err = svc.ListTopRated(context.Background(), model.RecordTypeMovie, 2, next, 2)
+	require.NoError(t, err)
+	require.Equal(t, []model.RecordID{"jaws"}, recordIDs(page))
+	require.Zero(t, next)
+}
+
+func TestListServesStaleLeaderboard(t *testing.T) {
+	svc, _ := newTestService(t)
+	putRatings(t, svc, "alien", 4)
+
+	page, _, err := svc.ListTrending(context.Background(), model.RecordTypeMovie, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []model.RecordID{"alien"}, recordIDs(page))
+
+	// New ratings show up once the background refresh ran, not on the request path.
+	putRatings(t, svc, "heat", 5, 5)
+	page, _, err = svc.ListTrending(context.Background(), model.RecordTypeMovie, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []model.RecordID{"alien"}, recordIDs(page))
+
+	require.NoError(t, svc.RefreshLeaderboards(context.Background()))
+	page, _, err = svc.ListTrending(context.Background(), model.RecordTypeMovie, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []model.RecordID{"heat", "alien"}, recordIDs(page))
+}
+
+func TestPaginate(t *testing.T) {
+	aggs := []model.RecordAggregate{{RecordID: "a"}, {RecordID: "b"}, {RecordID: "c"}}
+
+	for _, tt := range []struct {
+		offset, limit int
+		want          []model.RecordID
+		next          int
+	}{
+		{0, 2, []model.RecordID{"a", "b"}, 2},
+		{2, 2, []model.RecordID{"c"}, 0},
+		{0, 3, []model.RecordID{"a", "b", "c"}, 0},
+		{3, 2, nil, 0},
+	} {
+		page, next := paginate(aggs, tt.offset, tt.limit)
+		require.Equal(t, tt.want, recordIDs(page))
+		require.Equal(t, tt.next, next)
+	}
+}
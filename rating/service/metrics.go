@@ -0,0 +1,127 @@
+package service
+
+import (
+	"main/eventbus"
+	"main/rating/model"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// eventTypeUnknown labels the metrics of messages that could not be decoded.
+const eventTypeUnknown = "unknown"
+
+// consumerMetrics describes the rating event pipeline: the outcome of every consumed event, how long it took to
+// process, how old it was once applied and how many events are held by the consumer.
+// Backlog of the subscription itself is reported by the broker.
+type consumerMetrics struct {
+	received      *prometheus.CounterVec
+	processed     *prometheus.CounterVec
+	duplicates    *prometheus.CounterVec
+	failed        *prometheus.CounterVec
+	deadLettered  *prometheus.CounterVec
+	redelivered   *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	eventAge      *prometheus.HistogramVec
+	batchSize     prometheus.Histogram
+	batchRetries  prometheus.Counter
+	inFlight      prometheus.Gauge
+	lastProcessed prometheus.Gauge
+}
+
+func newConsumerMetrics() *consumerMetrics {
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      name,
+			Help:      help,
+		}, []string{"event_type"})
+	}
+
+	return &consumerMetrics{
+		received:     counter("messages_received_total", "Number of rating events received."),
+		processed:    counter("messages_processed_total", "Number of rating events successfully processed."),
+		duplicates:   counter("messages_duplicate_total", "Number of rating events skipped because they were already processed."),
+		failed:       counter("messages_failed_total", "Number of rating event deliveries that failed and were negatively acknowledged."),
+		deadLettered: counter("messages_dead_lettered_total", "Number of rating events moved to the dead letter topic after exhausting their deliveries."),
+		redelivered:  counter("messages_redelivered_total", "Number of rating event deliveries that were redeliveries of a failed event."),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "processing_duration_seconds",
+			Help:      "Time from handing a rating event to a worker until it was written, including the wait for its batch.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"event_type"}),
+		eventAge: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "event_age_seconds",
+			Help:      "Time from publishing a rating event until its rating was committed to the database.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 18),
+		}, []string{"event_type"}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "batch_size",
+			Help:      "Number of rating events written per batch.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+		}),
+		batchRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "batches_retried_total",
+			Help:      "Number of rating event batches that failed to be written and were retried event by event.",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "messages_in_flight",
+			Help:      "Number of rating events received and not acknowledged yet.",
+		}),
+		lastProcessed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "rating",
+			Subsystem: "consumer",
+			Name:      "last_processed_timestamp_seconds",
+			Help:      "Unix time the last rating event was successfully processed.",
+		}),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the rating event consumer.
+func (s *RatingService) Collectors() []prometheus.Collector {
+	m := s.metrics
+	return []prometheus.Collector{
+		m.received, m.processed, m.duplicates, m.failed, m.deadLettered, m.redelivered,
+		m.duration, m.eventAge, m.batchSize, m.batchRetries, m.inFlight, m.lastProcessed,
+	}
+}
+
+// eventTypeLabel returns the event type metric label of a decoded rating event.
+func eventTypeLabel(event *model.RatingEvent) string {
+	if event == nil || event.EventType == "" {
+		return eventTypeUnknown
+	}
+	return string(event.EventType)
+}
+
+// receivedMessage records a message received by a worker.
+func (m *consumerMetrics) receivedMessage(msg eventbus.Message, eventType string) {
+	m.received.WithLabelValues(eventType).Inc()
+	if msg.RedeliveryCount() > 0 {
+		m.redelivered.WithLabelValues(eventType).Inc()
+	}
+}
+
+// processedMessage records a message applied, or skipped as a duplicate, at now after its worker got it at start.
+func (m *consumerMetrics) processedMessage(msg eventbus.Message, eventType string, duplicate bool, start, now time.Time) {
+	m.processed.WithLabelValues(eventType).Inc()
+	if duplicate {
+		m.duplicates.WithLabelValues(eventType).Inc()
+	}
+	m.duration.WithLabelValues(eventType).Observe(now.Sub(start).Seconds())
+	if !duplicate {
+		m.eventAge.WithLabelValues(eventType).Observe(now.Sub(msg.PublishTime()).Seconds())
+	}
+	m.lastProcessed.Set(float64(now.UnixNano()) / 1e9)
+}
@@ -1,16 +1,38 @@
 package testutil
 
 import (
+	"context"
+	"log/slog"
+	"main/config"
+	"main/eventbus"
+	"main/outbox"
 	grpchandler "main/rating/handler/grpc"
+	"main/rating/repository"
 	"main/rating/repository/memory"
 	"main/rating/service"
 	"main/rpc"
-	"main/util"
 )
 
 // NewTestRatingGRPCServer creates a new rating gRPC server to be used in tests.
-func NewTestRatingGRPCServer(cfg *util.ConfigDatabase) rpc.RatingServiceServer {
+// It consumes rating events from the given bus and publishes rating change events to it until the context is cancelled.
+func NewTestRatingGRPCServer(ctx context.Context, cfg *config.Rating, bus eventbus.Bus) rpc.RatingServiceServer {
 	r := memory.New()
-	svc := service.New(r, cfg)
+	svc := service.New(r, bus, cfg, nil)
+	go func() {
+		if err := svc.StartConsume(ctx); err != nil {
+			slog.Error("failed to consume events:", slog.String("error", err.Error()))
+		}
+	}()
+	go svc.StartLeaderboardRefresh(ctx)
+	publisher, err := bus.Publisher(cfg.RatingEventsTopic)
+	if err != nil {
+		slog.Error("failed to create rating events publisher:", slog.String("error", err.Error()))
+	} else {
+		relay := outbox.NewRelay(r.Outbox(), publisher, cfg.OutboxRelayConfig(repository.EventSource, cfg.RatingEventsTopic))
+		go func() {
+			defer publisher.Close()
+			relay.Run(ctx)
+		}()
+	}
 	return grpchandler.New(svc)
 }
@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: audit.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	AuditAdmin_QueryAuditLog_FullMethodName = "/rpc.AuditAdmin/QueryAuditLog"
+)
+
+// AuditAdminClient is the client API for AuditAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuditAdminClient interface {
+	QueryAuditLog(ctx context.Context, in *QueryAuditLogRequest, opts ...grpc.CallOption) (*QueryAuditLogResponse, error)
+}
+
+type auditAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuditAdminClient(cc grpc.ClientConnInterface) AuditAdminClient {
+	return &auditAdminClient{cc}
+}
+
+func (c *auditAdminClient) QueryAuditLog(ctx context.Context, in *QueryAuditLogRequest, opts ...grpc.CallOption) (*QueryAuditLogResponse, error) {
+	out := new(QueryAuditLogResponse)
+	err := c.cc.Invoke(ctx, AuditAdmin_QueryAuditLog_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuditAdminServer is the server API for AuditAdmin service.
+// All implementations must embed UnimplementedAuditAdminServer
+// for forward compatibility
+type AuditAdminServer interface {
+	QueryAuditLog(context.Context, *QueryAuditLogRequest) (*QueryAuditLogResponse, error)
+	mustEmbedUnimplementedAuditAdminServer()
+}
+
+// UnimplementedAuditAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAuditAdminServer struct {
+}
+
+func (UnimplementedAuditAdminServer) QueryAuditLog(context.Context, *QueryAuditLogRequest) (*QueryAuditLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAuditLog not implemented")
+}
+func (UnimplementedAuditAdminServer) mustEmbedUnimplementedAuditAdminServer() {}
+
+// UnsafeAuditAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuditAdminServer will
+// result in compilation errors.
+type UnsafeAuditAdminServer interface {
+	mustEmbedUnimplementedAuditAdminServer()
+}
+
+func RegisterAuditAdminServer(s grpc.ServiceRegistrar, srv AuditAdminServer) {
+	s.RegisterService(&AuditAdmin_ServiceDesc, srv)
+}
+
+func _AuditAdmin_QueryAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryAuditLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuditAdminServer).QueryAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuditAdmin_QueryAuditLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuditAdminServer).QueryAuditLog(ctx, req.(*QueryAuditLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuditAdmin_ServiceDesc is the grpc.ServiceDesc for AuditAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuditAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.AuditAdmin",
+	HandlerType: (*AuditAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryAuditLog",
+			Handler:    _AuditAdmin_QueryAuditLog_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "audit.proto",
+}
@@ -19,8 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MetadataService_GetMetadata_FullMethodName = "/rpc.MetadataService/GetMetadata"
-	MetadataService_PutMetadata_FullMethodName = "/rpc.MetadataService/PutMetadata"
+	MetadataService_GetMetadata_FullMethodName      = "/rpc.MetadataService/GetMetadata"
+	MetadataService_PutMetadata_FullMethodName      = "/rpc.MetadataService/PutMetadata"
+	MetadataService_GetMetadataBatch_FullMethodName = "/rpc.MetadataService/GetMetadataBatch"
 )
 
 // MetadataServiceClient is the client API for MetadataService service.
@@ -29,6 +30,7 @@ const (
 type MetadataServiceClient interface {
 	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
 	PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error)
+	GetMetadataBatch(ctx context.Context, in *GetMetadataBatchRequest, opts ...grpc.CallOption) (*GetMetadataBatchResponse, error)
 }
 
 type metadataServiceClient struct {
@@ -57,12 +59,22 @@ func (c *metadataServiceClient) PutMetadata(ctx context.Context, in *PutMetadata
 	return out, nil
 }
 
+func (c *metadataServiceClient) GetMetadataBatch(ctx context.Context, in *GetMetadataBatchRequest, opts ...grpc.CallOption) (*GetMetadataBatchResponse, error) {
+	out := new(GetMetadataBatchResponse)
+	err := c.cc.Invoke(ctx, MetadataService_GetMetadataBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetadataServiceServer is the server API for MetadataService service.
 // All implementations must embed UnimplementedMetadataServiceServer
 // for forward compatibility
 type MetadataServiceServer interface {
 	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
 	PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error)
+	GetMetadataBatch(context.Context, *GetMetadataBatchRequest) (*GetMetadataBatchResponse, error)
 	mustEmbedUnimplementedMetadataServiceServer()
 }
 
@@ -76,6 +88,9 @@ func (UnimplementedMetadataServiceServer) GetMetadata(context.Context, *GetMetad
 func (UnimplementedMetadataServiceServer) PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutMetadata not implemented")
 }
+func (UnimplementedMetadataServiceServer) GetMetadataBatch(context.Context, *GetMetadataBatchRequest) (*GetMetadataBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadataBatch not implemented")
+}
 func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
 
 // UnsafeMetadataServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +140,24 @@ func _MetadataService_PutMetadata_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataService_GetMetadataBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetMetadataBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetMetadataBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetMetadataBatch(ctx, req.(*GetMetadataBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,6 +173,10 @@ var MetadataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutMetadata",
 			Handler:    _MetadataService_PutMetadata_Handler,
 		},
+		{
+			MethodName: "GetMetadataBatch",
+			Handler:    _MetadataService_GetMetadataBatch_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "metadata.proto",
@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MovieService_GetMovieDetails_FullMethodName = "/rpc.MovieService/GetMovieDetails"
+	MovieService_GetMovieDetails_FullMethodName    = "/rpc.MovieService/GetMovieDetails"
+	MovieService_ListTopRatedMovies_FullMethodName = "/rpc.MovieService/ListTopRatedMovies"
+	MovieService_ListTrendingMovies_FullMethodName = "/rpc.MovieService/ListTrendingMovies"
 )
 
 // MovieServiceClient is the client API for MovieService service.
@@ -27,6 +29,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MovieServiceClient interface {
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
+	ListTopRatedMovies(ctx context.Context, in *ListTopRatedMoviesRequest, opts ...grpc.CallOption) (*ListTopRatedMoviesResponse, error)
+	ListTrendingMovies(ctx context.Context, in *ListTrendingMoviesRequest, opts ...grpc.CallOption) (*ListTrendingMoviesResponse, error)
 }
 
 type movieServiceClient struct {
@@ -46,11 +50,31 @@ func (c *movieServiceClient) GetMovieDetails(ctx context.Context, in *GetMovieDe
 	return out, nil
 }
 
+func (c *movieServiceClient) ListTopRatedMovies(ctx context.Context, in *ListTopRatedMoviesRequest, opts ...grpc.CallOption) (*ListTopRatedMoviesResponse, error) {
+	out := new(ListTopRatedMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_ListTopRatedMovies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) ListTrendingMovies(ctx context.Context, in *ListTrendingMoviesRequest, opts ...grpc.CallOption) (*ListTrendingMoviesResponse, error) {
+	out := new(ListTrendingMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_ListTrendingMovies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MovieServiceServer is the server API for MovieService service.
 // All implementations must embed UnimplementedMovieServiceServer
 // for forward compatibility
 type MovieServiceServer interface {
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
+	ListTopRatedMovies(context.Context, *ListTopRatedMoviesRequest) (*ListTopRatedMoviesResponse, error)
+	ListTrendingMovies(context.Context, *ListTrendingMoviesRequest) (*ListTrendingMoviesResponse, error)
 	mustEmbedUnimplementedMovieServiceServer()
 }
 
@@ -61,6 +85,12 @@ type UnimplementedMovieServiceServer struct {
 func (UnimplementedMovieServiceServer) GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMovieDetails not implemented")
 }
+func (UnimplementedMovieServiceServer) ListTopRatedMovies(context.Context, *ListTopRatedMoviesRequest) (*ListTopRatedMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTopRatedMovies not implemented")
+}
+func (UnimplementedMovieServiceServer) ListTrendingMovies(context.Context, *ListTrendingMoviesRequest) (*ListTrendingMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrendingMovies not implemented")
+}
 func (UnimplementedMovieServiceServer) mustEmbedUnimplementedMovieServiceServer() {}
 
 // UnsafeMovieServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +122,42 @@ func _MovieService_GetMovieDetails_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieService_ListTopRatedMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopRatedMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).ListTopRatedMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_ListTopRatedMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).ListTopRatedMovies(ctx, req.(*ListTopRatedMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_ListTrendingMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrendingMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).ListTrendingMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_ListTrendingMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).ListTrendingMovies(ctx, req.(*ListTrendingMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MovieService_ServiceDesc is the grpc.ServiceDesc for MovieService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +169,14 @@ var MovieService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMovieDetails",
 			Handler:    _MovieService_GetMovieDetails_Handler,
 		},
+		{
+			MethodName: "ListTopRatedMovies",
+			Handler:    _MovieService_ListTopRatedMovies_Handler,
+		},
+		{
+			MethodName: "ListTrendingMovies",
+			Handler:    _MovieService_ListTrendingMovies_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
@@ -21,6 +21,8 @@ const _ = grpc.SupportPackageIsVersion7
 const (
 	RatingService_GetAggregatedRating_FullMethodName = "/rpc.RatingService/GetAggregatedRating"
 	RatingService_PutRating_FullMethodName           = "/rpc.RatingService/PutRating"
+	RatingService_ListTopRated_FullMethodName        = "/rpc.RatingService/ListTopRated"
+	RatingService_ListTrending_FullMethodName        = "/rpc.RatingService/ListTrending"
 )
 
 // RatingServiceClient is the client API for RatingService service.
@@ -29,6 +31,8 @@ const (
 type RatingServiceClient interface {
 	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
 	PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error)
+	ListTopRated(ctx context.Context, in *ListTopRatedRequest, opts ...grpc.CallOption) (*ListTopRatedResponse, error)
+	ListTrending(ctx context.Context, in *ListTrendingRequest, opts ...grpc.CallOption) (*ListTrendingResponse, error)
 }
 
 type ratingServiceClient struct {
@@ -57,12 +61,32 @@ func (c *ratingServiceClient) PutRating(ctx context.Context, in *PutRatingReques
 	return out, nil
 }
 
+func (c *ratingServiceClient) ListTopRated(ctx context.Context, in *ListTopRatedRequest, opts ...grpc.CallOption) (*ListTopRatedResponse, error) {
+	out := new(ListTopRatedResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListTopRated_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListTrending(ctx context.Context, in *ListTrendingRequest, opts ...grpc.CallOption) (*ListTrendingResponse, error) {
+	out := new(ListTrendingResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListTrending_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RatingServiceServer is the server API for RatingService service.
 // All implementations must embed UnimplementedRatingServiceServer
 // for forward compatibility
 type RatingServiceServer interface {
 	GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error)
 	PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error)
+	ListTopRated(context.Context, *ListTopRatedRequest) (*ListTopRatedResponse, error)
+	ListTrending(context.Context, *ListTrendingRequest) (*ListTrendingResponse, error)
 	mustEmbedUnimplementedRatingServiceServer()
 }
 
@@ -76,6 +100,12 @@ func (UnimplementedRatingServiceServer) GetAggregatedRating(context.Context, *Ge
 func (UnimplementedRatingServiceServer) PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutRating not implemented")
 }
+func (UnimplementedRatingServiceServer) ListTopRated(context.Context, *ListTopRatedRequest) (*ListTopRatedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTopRated not implemented")
+}
+func (UnimplementedRatingServiceServer) ListTrending(context.Context, *ListTrendingRequest) (*ListTrendingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrending not implemented")
+}
 func (UnimplementedRatingServiceServer) mustEmbedUnimplementedRatingServiceServer() {}
 
 // UnsafeRatingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +155,42 @@ func _RatingService_PutRating_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatingService_ListTopRated_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopRatedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListTopRated(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListTopRated_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListTopRated(ctx, req.(*ListTopRatedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListTrending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListTrending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListTrending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListTrending(ctx, req.(*ListTrendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RatingService_ServiceDesc is the grpc.ServiceDesc for RatingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,6 +206,14 @@ var RatingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutRating",
 			Handler:    _RatingService_PutRating_Handler,
 		},
+		{
+			MethodName: "ListTopRated",
+			Handler:    _RatingService_ListTopRated_Handler,
+		},
+		{
+			MethodName: "ListTrending",
+			Handler:    _RatingService_ListTrending_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "rating.proto",
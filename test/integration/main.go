@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
-	"flag"
+	"encoding/json"
 	"log/slog"
+	"main/config"
 	"main/discovery"
 	"main/discovery/memory"
+	"main/eventbus"
+	eventbusmemory "main/eventbus/memory"
+	"main/eventcodec"
+	"main/outbox"
+	ratingmodel "main/rating/model"
 	"main/rpc"
-	"main/util"
 	"net"
 	"os"
+	"time"
 
 	metadatatest "main/metadata/testutil"
 	movietest "main/movie/testutil"
@@ -31,13 +37,23 @@ const (
 	movieServiceAddr    = "localhost:8083"
 )
 
+// integrationConfig is the configuration of the services started by the integration test.
+type integrationConfig struct {
+	Metadata config.Metadata
+	Rating   config.Rating
+}
+
+// Validate implements config.Validator.
+func (c *integrationConfig) Validate(p *config.Problems) {
+	c.Metadata.Validate(p)
+	c.Rating.Validate(p)
+}
+
 func main() {
-	var config string
-	flag.StringVar(&config, "config", ".env", "Configuration path")
-	flag.Parse()
-	cfg := util.LoadConfig(config)
+	var cfg integrationConfig
+	config.MustLoad(&cfg)
 
-	if cfg.Environment == "dev" {
+	if cfg.Rating.Environment == "dev" {
 		var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 		slog.SetDefault(logger)
 	} else {
@@ -47,14 +63,17 @@ func main() {
 
 	slog.Info("Starting the integration test")
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	registry := memory.NewRegistry()
+	bus := eventbusmemory.New()
+	defer bus.Close()
 
 	slog.Info("Setting up service handlers and clients")
 
-	metadataSrv := startMetadataService(ctx, registry)
+	metadataSrv := startMetadataService(ctx, registry, &cfg.Metadata, bus)
 	defer metadataSrv.GracefulStop()
-	ratingSrv := startRatingService(ctx, registry, cfg)
+	ratingSrv := startRatingService(ctx, registry, &cfg.Rating, bus)
 	defer ratingSrv.GracefulStop()
 	movieSrv := startMovieService(ctx, registry)
 	defer movieSrv.GracefulStop()
@@ -201,12 +220,148 @@ func main() {
 		return
 	}
 
+	slog.Info("Listing top rated movies via movie service")
+
+	listTopRatedResp, err := movieClient.ListTopRatedMovies(ctx, &rpc.ListTopRatedMoviesRequest{MinRatingCount: 2})
+	if err != nil {
+		slog.Error("list top rated movies:", slog.String("error", err.Error()))
+		return
+	}
+
+	if got, want := len(listTopRatedResp.Movies), 1; got != want {
+		slog.Error("top rated movies count mismatch:", slog.Int("got", got), slog.Int("want", want))
+		return
+	}
+	if diff := cmp.Diff(listTopRatedResp.Movies[0].MovieDetails, wantMovieDetails, cmpopts.IgnoreUnexported(rpc.MovieDetails{}, rpc.Metadata{})); diff != "" {
+		slog.Error("top rated movie mismatch:", slog.String("diff", diff))
+		return
+	}
+
+	slog.Info("Publishing rating event via event bus")
+
+	publisher, err := bus.Publisher(cfg.Rating.TopicName)
+	if err != nil {
+		slog.Error("create publisher:", slog.String("error", err.Error()))
+		return
+	}
+	defer publisher.Close()
+
+	ratingEvent := ratingmodel.RatingEvent{
+		ID:         "integration-rating-event",
+		OccurredAt: time.Now(),
+		UserID:     "user1",
+		RecordID:   ratingmodel.RecordID(m.MovieId),
+		RecordType: ratingmodel.RecordTypeMovie,
+		Value:      6,
+		EventType:  ratingmodel.RatingEventTypePut,
+	}
+	legacyPayload, err := json.Marshal(ratingEvent)
+	if err != nil {
+		slog.Error("encode legacy rating event:", slog.String("error", err.Error()))
+		return
+	}
+	cloudEventPayload, err := eventcodec.Encode(eventcodec.ContentTypeProtobuf, &eventcodec.Envelope{
+		ID:     ratingEvent.ID,
+		Source: "/integration-test",
+		Time:   ratingEvent.OccurredAt,
+	}, ratingmodel.RatingEventToProto(&ratingEvent))
+	if err != nil {
+		slog.Error("encode rating event:", slog.String("error", err.Error()))
+		return
+	}
+
+	// The event is published twice, as legacy JSON and as a CloudEvent, to simulate a replay; it must only be applied once.
+	for _, msg := range []*eventbus.OutgoingMessage{
+		{Key: m.MovieId, Payload: legacyPayload, EventTime: time.Now()},
+		{Key: m.MovieId, Payload: cloudEventPayload, Properties: map[string]string{eventcodec.PropertyContentType: eventcodec.ContentTypeProtobuf}},
+	} {
+		if _, err := publisher.Publish(ctx, msg); err != nil {
+			slog.Error("publish rating event:", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	slog.Info("Waiting for the rating event to be consumed by rating service")
+
+	wantRating = float64(firstRating+secondRating+6) / 3
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		getAggregatedRatingResp, err = ratingClient.GetAggregatedRating(ctx, &rpc.GetAggregatedRatingRequest{
+			RecordId:   m.MovieId,
+			RecordType: recordTypeMovie,
+		})
+		if err != nil {
+			slog.Error("get aggregated rating:", slog.String("error", err.Error()))
+			return
+		}
+		if getAggregatedRatingResp.RatingValue == wantRating {
+			break
+		}
+		if time.Now().After(deadline) {
+			slog.Error("rating mismatch after event:", slog.Float64("got", getAggregatedRatingResp.RatingValue), slog.Float64("want", wantRating))
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	getAggregatedRatingResp, err = ratingClient.GetAggregatedRating(ctx, &rpc.GetAggregatedRatingRequest{
+		RecordId:   m.MovieId,
+		RecordType: recordTypeMovie,
+	})
+	if err != nil {
+		slog.Error("get aggregated rating:", slog.String("error", err.Error()))
+		return
+	}
+	if getAggregatedRatingResp.RatingValue != wantRating {
+		slog.Error("replayed rating event was applied twice:", slog.Float64("got", getAggregatedRatingResp.RatingValue), slog.Float64("want", wantRating))
+		return
+	}
+
+	slog.Info("Waiting for change events to be relayed from the outboxes")
+
+	if err := waitForEvent(ctx, bus, cfg.Metadata.MetadataEventsTopic, eventcodec.TypeMetadataChanged, m.MovieId); err != nil {
+		slog.Error("metadata change event:", slog.String("error", err.Error()))
+		return
+	}
+	if err := waitForEvent(ctx, bus, cfg.Rating.RatingEventsTopic, eventcodec.TypeAggregateChanged, m.MovieId); err != nil {
+		slog.Error("rating aggregate change event:", slog.String("error", err.Error()))
+		return
+	}
+
 	slog.Info("Integration test execution successfull")
 }
 
-func startMetadataService(ctx context.Context, registry discovery.Registry) *grpc.Server {
+// waitForEvent waits until a change event of the given type and key is published to the topic.
+func waitForEvent(ctx context.Context, bus eventbus.Bus, topic, eventType, key string) error {
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{
+		Topic:        topic,
+		Subscription: "integration-test",
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if err := sub.Ack(msg); err != nil {
+			return err
+		}
+		if msg.Key() == key && msg.Properties()[outbox.PropertyEventType] == eventType {
+			return nil
+		}
+	}
+}
+
+func startMetadataService(ctx context.Context, registry discovery.Registry, cfg *config.Metadata, bus eventbus.Bus) *grpc.Server {
 	slog.Info("Starting metadata service on ", slog.String("address", metadataServiceAddr))
-	h := metadatatest.NewTestMetadataGRPCServer()
+	h := metadatatest.NewTestMetadataGRPCServer(ctx, cfg, bus)
 	l, err := net.Listen("tcp", metadataServiceAddr)
 	if err != nil {
 		slog.Error("failed to listen:", slog.String("error", err.Error()))
@@ -231,9 +386,9 @@ func startMetadataService(ctx context.Context, registry discovery.Registry) *grp
 	return srv
 }
 
-func startRatingService(ctx context.Context, registry discovery.Registry, cfg *util.ConfigDatabase) *grpc.Server {
+func startRatingService(ctx context.Context, registry discovery.Registry, cfg *config.Rating, bus eventbus.Bus) *grpc.Server {
 	slog.Info("Starting rating service on ", slog.String("address", ratingServiceAddr))
-	h := ratingtest.NewTestRatingGRPCServer(cfg)
+	h := ratingtest.NewTestRatingGRPCServer(ctx, cfg, bus)
 	l, err := net.Listen("tcp", ratingServiceAddr)
 	if err != nil {
 		slog.Error("failed to listen:", slog.String("error", err.Error()))
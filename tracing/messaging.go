@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"main/eventbus"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// messagingSystem is the messaging.system attribute of the message spans.
+const messagingSystem = "pulsar"
+
+// messagingRedeliveryCountKey is the attribute key of the number of times a consumed message was delivered before.
+const messagingRedeliveryCountKey = attribute.Key("messaging.pulsar.redelivery_count")
+
+// StartProducerSpan starts a producer span for a message sent to the topic and injects its trace context into the
+// message properties, so the consumers of the message continue the trace.
+func StartProducerSpan(ctx context.Context, tracerName, topic string, msg *eventbus.OutgoingMessage) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingMessagePayloadSizeBytes(len(msg.Payload)),
+		),
+	)
+
+	if msg.Properties == nil {
+		msg.Properties = map[string]string{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Properties))
+	return ctx, span
+}
+
+// StartConsumerSpan extracts the trace context of a received message and starts a consumer span continuing it.
+func StartConsumerSpan(ctx context.Context, tracerName, topic string, msg eventbus.Message) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Properties()))
+	return otel.Tracer(tracerName).Start(ctx, topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingMessageID(msg.ID()),
+			semconv.MessagingMessagePayloadSizeBytes(len(msg.Payload())),
+			messagingRedeliveryCountKey.Int64(int64(msg.RedeliveryCount())),
+		),
+	)
+}
+
+// StartBatchSpan starts a consumer span processing a batch of messages, linked to the span of every message.
+// The messages come from different traces, so the batch starts a trace of its own.
+func StartBatchSpan(ctx context.Context, tracerName, topic string, msgSpans []trace.Span) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(msgSpans))
+	for _, span := range msgSpans {
+		links = append(links, trace.Link{SpanContext: span.SpanContext()})
+	}
+	return otel.Tracer(tracerName).Start(ctx, topic+" process batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(len(msgSpans)),
+		),
+	)
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"main/eventbus"
+	"main/eventbus/memory"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMessageTracePropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	bus := memory.New()
+	defer bus.Close()
+	publisher, err := bus.Publisher("ratings")
+	require.NoError(t, err)
+	sub, err := bus.Subscribe(eventbus.SubscriptionOptions{Topic: "ratings", Subscription: "test"})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	msg := &eventbus.OutgoingMessage{Key: "1", Payload: []byte("rating")}
+	ctx, producerSpan := StartProducerSpan(context.Background(), "test", "ratings", msg)
+	_, err = publisher.Publish(ctx, msg)
+	require.NoError(t, err)
+	producerSpan.End()
+
+	received, err := sub.Receive(context.Background())
+	require.NoError(t, err)
+	_, consumerSpan := StartConsumerSpan(context.Background(), "test", "ratings", received)
+	consumerSpan.End()
+	_, batchSpan := StartBatchSpan(context.Background(), "test", "ratings", []trace.Span{consumerSpan})
+	batchSpan.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	producer, consumer, batch := spans[0], spans[1], spans[2]
+	require.Equal(t, trace.SpanKindProducer, producer.SpanKind())
+	require.Equal(t, trace.SpanKindConsumer, consumer.SpanKind())
+	require.Equal(t, producer.SpanContext().TraceID(), consumer.SpanContext().TraceID())
+	require.Equal(t, producer.SpanContext().SpanID(), consumer.Parent().SpanID())
+
+	require.NotEqual(t, producer.SpanContext().TraceID(), batch.SpanContext().TraceID())
+	require.Len(t, batch.Links(), 1)
+	require.Equal(t, consumer.SpanContext(), batch.Links()[0].SpanContext)
+}
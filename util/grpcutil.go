@@ -11,7 +11,9 @@ import (
 )
 
 // ServiceConnection attemps to select a random service instance and returns gRPC connection to it.
-func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
+// The dial options are added after the tracing ones; transport credentials among them replace the default
+// connection without TLS.
+func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	addrs, err := registry.ServiceAddresses(ctx, serviceName)
 	if err != nil {
 		return nil, err
@@ -19,9 +21,8 @@ func ServiceConnection(ctx context.Context, serviceName string, registry discove
 
 	targetAddress := addrs[rand.Intn(len(addrs))]
 
-	return grpc.Dial(
-		targetAddress,
+	return grpc.Dial(targetAddress, append([]grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
-	)
+	}, opts...)...)
 }